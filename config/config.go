@@ -3,14 +3,27 @@ package config
 import "fmt"
 
 var (
-	AggregationFile string
-	Port            string
-	StorageDir      string
-	InventoryFile   string
-	MenuFile        string
-	OrdersFile      string
-	LogFile         string
-	RestrictedDirs  = []string{"flags", "handlers", "models", "servers", "storage", "utils", "../"}
+	AggregationFile   string
+	Port              string
+	StorageDir        string
+	InventoryFile     string
+	MenuFile          string
+	OrdersFile        string
+	LogFile           string
+	PricingFile       string
+	MovementsFile     string
+	ReceiptsFile      string
+	WasteFile         string
+	CountsFile        string
+	SuppliersFile     string
+	PurchaseFile      string
+	DayCloseFile      string
+	TimeZone          = "Asia/Almaty"
+	MarginThreshold   float64
+	DailyItemStrategy string
+	AlertFile         string
+	AlertWebhook      string
+	RestrictedDirs    = []string{"flags", "handlers", "models", "servers", "storage", "utils", "../"}
 )
 
 // Default content for inventory.json
@@ -122,15 +135,65 @@ func DefaultOrders() []map[string]interface{} {
 	}
 }
 
+// Default content for pricing_rules.json
+func DefaultPricingRules() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for inventory_movements.json
+func DefaultMovements() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for goods_receipts.json
+func DefaultReceipts() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for waste.json
+func DefaultWaste() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for stock_counts.json
+func DefaultCounts() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for suppliers.json
+func DefaultSuppliers() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for purchase_orders.json
+func DefaultPurchaseOrders() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for day_closes.json
+func DefaultDayCloses() []map[string]interface{} {
+	return []map[string]interface{}{}
+}
+
+// Default content for aggregation.json. Empty aggregates are rebuilt from
+// the orders on first use.
+func DefaultAggregation() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
 func PrintUsage() {
 	fmt.Println("Coffee Shop Management System")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("    hot-coffee [--port <N>] [--dir <S>] ")
+	fmt.Println("    hot-coffee [--port <N>] [--dir <S>] [--margin-threshold <P>] [--daily-item-strategy <S>] [--alert-file <F>] [--alert-webhook <URL>]")
 	fmt.Println("    hot-coffee --help")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --help     Show this screen.")
 	fmt.Println("  --port N   Port number")
 	fmt.Println("  --dir S    Path to the directory")
+	fmt.Println("  --margin-threshold P    Gross margin percentage below which menu items are flagged")
+	fmt.Println("  --daily-item-strategy S How the daily item is chosen: random, rotation or slow_moving")
+	fmt.Println("  --alert-file F          File that low-stock alerts are appended to")
+	fmt.Println("  --alert-webhook URL     URL that low-stock alerts are POSTed to")
 }
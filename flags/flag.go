@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"hot-coffee/config"
 	"hot-coffee/logging"
+	"hot-coffee/models"
 	"hot-coffee/utils"
 	"log"
 	"net"
@@ -24,6 +25,15 @@ func isRestrictedDir(dir string) bool {
 	return false
 }
 
+func isDailyItemStrategy(strategy string) bool {
+	for _, known := range models.DailyItemStrategies {
+		if strategy == known {
+			return true
+		}
+	}
+	return false
+}
+
 func isPortInRange(port string) bool {
 	portNum, err := strconv.Atoi(port)
 	if err != nil {
@@ -55,6 +65,10 @@ func Setup() {
 
 	flag.StringVar(&config.Port, "port", defaultPort, "Port to run the server on")
 	flag.StringVar(&config.StorageDir, "directory", defaultStorageDir, "Directory for file storage")
+	flag.Float64Var(&config.MarginThreshold, "margin-threshold", 60, "Gross margin percentage below which menu items are flagged")
+	flag.StringVar(&config.DailyItemStrategy, "daily-item-strategy", models.DailyItemRandom, "How the daily item is chosen: random, rotation or slow_moving")
+	flag.StringVar(&config.AlertFile, "alert-file", "", "File that low-stock alerts are appended to")
+	flag.StringVar(&config.AlertWebhook, "alert-webhook", "", "URL that low-stock alerts are POSTed to")
 	flag.Parse()
 	if !isPortAvailable(config.Port) {
 		logging.Error("The specified port is already in use", nil, "port", config.Port)
@@ -67,6 +81,11 @@ func Setup() {
 		os.Exit(0)
 	}
 
+	if !isDailyItemStrategy(config.DailyItemStrategy) {
+		logging.Error("Invalid daily item strategy", nil, "strategy", config.DailyItemStrategy)
+		log.Fatalf("Invalid daily item strategy '%s'. Use random, rotation or slow_moving.", config.DailyItemStrategy)
+	}
+
 	if isRestrictedDir(config.StorageDir) {
 		logging.Error("The specified directory is restricted", fmt.Errorf("Error"), config.StorageDir)
 		log.Fatalf("The specified directory '%s' is restricted. Please choose a different name.", config.StorageDir)
@@ -102,8 +121,25 @@ func Setup() {
 	createJSONFileIfNotExists(filepath.Join(dataDir, "menu_item.json"), config.DefaultMenuItems())
 	config.OrdersFile = filepath.Join(config.StorageDir, "order.json")
 	createJSONFileIfNotExists(filepath.Join(dataDir, "order.json"), config.DefaultOrders())
+	config.PricingFile = filepath.Join(config.StorageDir, "pricing_rules.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "pricing_rules.json"), config.DefaultPricingRules())
+	config.MovementsFile = filepath.Join(config.StorageDir, "inventory_movements.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "inventory_movements.json"), config.DefaultMovements())
+	config.ReceiptsFile = filepath.Join(config.StorageDir, "goods_receipts.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "goods_receipts.json"), config.DefaultReceipts())
+	config.WasteFile = filepath.Join(config.StorageDir, "waste.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "waste.json"), config.DefaultWaste())
+	config.CountsFile = filepath.Join(config.StorageDir, "stock_counts.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "stock_counts.json"), config.DefaultCounts())
+	config.SuppliersFile = filepath.Join(config.StorageDir, "suppliers.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "suppliers.json"), config.DefaultSuppliers())
+	config.PurchaseFile = filepath.Join(config.StorageDir, "purchase_orders.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "purchase_orders.json"), config.DefaultPurchaseOrders())
+	config.DayCloseFile = filepath.Join(config.StorageDir, "day_closes.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "day_closes.json"), config.DefaultDayCloses())
 	config.LogFile = filepath.Join(config.StorageDir, "app.log")
 	config.AggregationFile = filepath.Join(config.StorageDir, "aggregation.json")
+	createJSONFileIfNotExists(filepath.Join(dataDir, "aggregation.json"), config.DefaultAggregation())
 
 	logging.Info("Using data directory", "data_directory", dataDir)
 }
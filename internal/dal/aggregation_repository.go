@@ -2,43 +2,34 @@
 package dal
 
 import (
-	"encoding/json"
 	"hot-coffee/config"
-	"hot-coffee/logging"
 	"hot-coffee/models"
-	"os"
 )
 
 type AggregationRepository interface {
+	ReadAggregationData() (models.AggregationData, error)
 	SaveAggregationData(aggregationData models.AggregationData) error
+	UpdateAggregationData(update func(*models.AggregationData) error) error
 }
 
 type AggregationService struct{}
 
-// SaveAggregationData saves the aggregated results (e.g., total sales, popular items, daily item) to a file.
-func (a *AggregationService) SaveAggregationData(aggregationData models.AggregationData) error {
-	// Marshal the aggregation data into JSON
-	data, err := json.MarshalIndent(aggregationData, "", "  ")
-	if err != nil {
-		logging.Error("Failed to marshal aggregation data", err)
-		return err
-	}
+var aggregationStore = newJSONStore[models.AggregationData](&config.AggregationFile, "aggregation data")
 
-	// Open the aggregation file (create it if it doesn't exist, overwrite if it does)
-	file, err := os.OpenFile(config.AggregationFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
-	if err != nil {
-		logging.Error("Failed to open aggregation file for writing", err)
-		return err
-	}
-	defer file.Close()
+// ReadAggregationData reads the aggregated results from the file. A missing
+// file reads as empty aggregates.
+func (a *AggregationService) ReadAggregationData() (models.AggregationData, error) {
+	return aggregationStore.Read()
+}
 
-	// Write the data to the file
-	_, err = file.Write(data)
-	if err != nil {
-		logging.Error("Failed to write aggregation data to file", err)
-		return err
-	}
+// SaveAggregationData saves the aggregated results (e.g., total sales, popular items, daily item) to a file.
+func (a *AggregationService) SaveAggregationData(aggregationData models.AggregationData) error {
+	return aggregationStore.Save(aggregationData)
+}
 
-	logging.Info("Successfully saved aggregation data", "file", config.AggregationFile)
-	return nil
+func (a *AggregationService) UpdateAggregationData(update func(*models.AggregationData) error) error {
+	return aggregationStore.Update(func(aggregationData models.AggregationData) (models.AggregationData, error) {
+		err := update(&aggregationData)
+		return aggregationData, err
+	})
 }
@@ -0,0 +1,40 @@
+package dal
+
+import (
+	"errors"
+	"hot-coffee/config"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+)
+
+type DayCloseRepository interface {
+	ReadItems() ([]models.DayClose, error)
+	Append(dayClose models.DayClose) error
+}
+
+type DayCloseService struct{}
+
+var dayCloseStore = newJSONStore[[]models.DayClose](&config.DayCloseFile, "day closes")
+
+func (d *DayCloseService) ReadItems() ([]models.DayClose, error) {
+	return dayCloseStore.Read()
+}
+
+// Append stores the Z report of a day that has not been closed yet. Stored
+// reports are never modified.
+func (d *DayCloseService) Append(dayClose models.DayClose) error {
+	err := dayCloseStore.Update(func(closes []models.DayClose) ([]models.DayClose, error) {
+		for _, existing := range closes {
+			if existing.Date == dayClose.Date {
+				return nil, errors.New("business day is already closed")
+			}
+		}
+		return append(closes, dayClose), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Stored day close", "date", dayClose.Date)
+	return nil
+}
@@ -0,0 +1,114 @@
+package dal
+
+import (
+	"encoding/json"
+	"hot-coffee/logging"
+	"hot-coffee/utils"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// jsonStore keeps a value, usually a list of records, in a JSON file. Every
+// access holds the store's lock. The path points at the config variable,
+// which is only set once the flags are parsed.
+type jsonStore[T any] struct {
+	mu   sync.Mutex
+	path *string
+	name string
+	// saved, when set, is called with every value written, under the lock.
+	saved func(T)
+}
+
+func newJSONStore[T any](path *string, name string) *jsonStore[T] {
+	return &jsonStore[T]{path: path, name: name}
+}
+
+// Read reads the stored value. A missing file reads as the zero value, and
+// a missing list as an empty one.
+func (s *jsonStore[T]) Read() (T, error) {
+	defer utils.CatchCriticalPoint()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+// Save replaces the stored value.
+func (s *jsonStore[T]) Save(value T) error {
+	defer utils.CatchCriticalPoint()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(value)
+}
+
+// Update reads the stored value, applies update and saves the result while
+// holding the lock, so concurrent writers never lose each other's changes.
+// Nothing is saved when update fails. Repositories expose it as their
+// UpdateItems.
+func (s *jsonStore[T]) Update(update func(T) (T, error)) error {
+	defer utils.CatchCriticalPoint()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, err := s.read()
+	if err != nil {
+		return err
+	}
+	value, err = update(value)
+	if err != nil {
+		return err
+	}
+	return s.save(value)
+}
+
+func (s *jsonStore[T]) read() (T, error) {
+	var value T
+	data, err := os.ReadFile(*s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logging.Info("File not found, returning empty "+s.name, "file", *s.path)
+			return emptyList(value), nil
+		}
+		logging.Error("Failed to read "+s.name+" file", err, "file", *s.path)
+		return value, err
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		logging.Error("Failed to unmarshal "+s.name, err, "file", *s.path)
+		return value, err
+	}
+	return emptyList(value), nil
+}
+
+func (s *jsonStore[T]) save(value T) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		logging.Error("Failed to marshal "+s.name, err)
+		return err
+	}
+	if err := os.WriteFile(*s.path, data, 0o666); err != nil {
+		logging.Error("Failed to write "+s.name+" file", err, "file", *s.path)
+		return err
+	}
+	if s.saved != nil {
+		s.saved(value)
+	}
+
+	logging.Info("Saved "+s.name, "file", *s.path)
+	return nil
+}
+
+// emptyList turns a nil list into an empty one, so that it encodes as [].
+// Other values are returned as they are.
+func emptyList[T any](value T) T {
+	v := reflect.ValueOf(&value).Elem()
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	return value
+}
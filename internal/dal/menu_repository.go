@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"hot-coffee/config"
+	"hot-coffee/internal/search"
 	"hot-coffee/logging"
 	"hot-coffee/models"
 	"hot-coffee/utils"
@@ -74,6 +75,8 @@ func (m *MenuItemService) SaveItems(items []models.MenuItem) error {
 		return err
 	}
 
+	search.IndexMenuItems(items)
+
 	logging.Info("Successfully saved menu items", "file", config.MenuFile, "count", len(items))
 	return nil
 }
@@ -0,0 +1,68 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+)
+
+type MovementRepository interface {
+	ReadItems() ([]models.InventoryMovement, error)
+	Append(movements ...models.InventoryMovement) ([]models.InventoryMovement, error)
+}
+
+type MovementService struct{}
+
+var movementStore = newJSONStore[[]models.InventoryMovement](&config.MovementsFile, "inventory movements")
+
+func (m *MovementService) ReadItems() ([]models.InventoryMovement, error) {
+	return movementStore.Read()
+}
+
+// Append adds movements to the end of the ledger, numbering them, and
+// returns them as stored. The first movement of an ingredient is preceded
+// by an opening balance, so that its ledger adds up to the stored quantity.
+// Existing entries are never modified.
+func (m *MovementService) Append(movements ...models.InventoryMovement) ([]models.InventoryMovement, error) {
+	var stored []models.InventoryMovement
+	err := movementStore.Update(func(ledger []models.InventoryMovement) ([]models.InventoryMovement, error) {
+		ids := make([]string, 0, len(ledger))
+		tracked := make(map[string]bool)
+		for _, movement := range ledger {
+			ids = append(ids, movement.ID)
+			tracked[movement.IngredientID] = true
+		}
+
+		stored = nil
+		for _, movement := range movements {
+			if !tracked[movement.IngredientID] {
+				opening := math.Round((movement.BalanceAfter-movement.Quantity)*10000) / 10000
+				if opening != 0 {
+					stored = append(stored, models.InventoryMovement{
+						IngredientID: movement.IngredientID,
+						Type:         models.MovementOpening,
+						Quantity:     opening,
+						BalanceAfter: opening,
+						Reason:       "opening balance",
+						CreatedAt:    movement.CreatedAt,
+					})
+				}
+				tracked[movement.IngredientID] = true
+			}
+			stored = append(stored, movement)
+		}
+		for i := range stored {
+			stored[i].ID = utils.NextID("mov", ids)
+			ids = append(ids, stored[i].ID)
+		}
+		return append(ledger, stored...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Info("Appended inventory movements", "count", len(stored))
+	return stored, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"hot-coffee/config"
+	"hot-coffee/internal/search"
 	"hot-coffee/logging"
 	"hot-coffee/models"
 	"hot-coffee/utils"
@@ -80,6 +81,8 @@ func (o *OrderService) SaveItems(orders []models.Order) error {
 		return err
 	}
 
+	search.IndexOrders(orders)
+
 	logging.Info("Successfully saved orders to file")
 	return nil
 }
@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type PricingRuleRepository interface {
+	ReadItems() ([]models.PricingRule, error)
+	SaveItems([]models.PricingRule) error
+	UpdateItems(update func([]models.PricingRule) ([]models.PricingRule, error)) error
+}
+
+type PricingRuleService struct{}
+
+var pricingRuleStore = newJSONStore[[]models.PricingRule](&config.PricingFile, "pricing rules")
+
+func (p *PricingRuleService) ReadItems() ([]models.PricingRule, error) {
+	return pricingRuleStore.Read()
+}
+
+func (p *PricingRuleService) SaveItems(rules []models.PricingRule) error {
+	return pricingRuleStore.Save(rules)
+}
+
+func (p *PricingRuleService) UpdateItems(update func([]models.PricingRule) ([]models.PricingRule, error)) error {
+	return pricingRuleStore.Update(update)
+}
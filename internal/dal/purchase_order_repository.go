@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type PurchaseOrderRepository interface {
+	ReadItems() ([]models.PurchaseOrder, error)
+	SaveItems([]models.PurchaseOrder) error
+	UpdateItems(update func([]models.PurchaseOrder) ([]models.PurchaseOrder, error)) error
+}
+
+type PurchaseOrderService struct{}
+
+var purchaseOrderStore = newJSONStore[[]models.PurchaseOrder](&config.PurchaseFile, "purchase orders")
+
+func (p *PurchaseOrderService) ReadItems() ([]models.PurchaseOrder, error) {
+	return purchaseOrderStore.Read()
+}
+
+func (p *PurchaseOrderService) SaveItems(orders []models.PurchaseOrder) error {
+	return purchaseOrderStore.Save(orders)
+}
+
+func (p *PurchaseOrderService) UpdateItems(update func([]models.PurchaseOrder) ([]models.PurchaseOrder, error)) error {
+	return purchaseOrderStore.Update(update)
+}
@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type ReceiptRepository interface {
+	ReadItems() ([]models.GoodsReceipt, error)
+	SaveItems([]models.GoodsReceipt) error
+	UpdateItems(update func([]models.GoodsReceipt) ([]models.GoodsReceipt, error)) error
+}
+
+type ReceiptService struct{}
+
+var receiptStore = newJSONStore[[]models.GoodsReceipt](&config.ReceiptsFile, "goods receipts")
+
+func (g *ReceiptService) ReadItems() ([]models.GoodsReceipt, error) {
+	return receiptStore.Read()
+}
+
+func (g *ReceiptService) SaveItems(receipts []models.GoodsReceipt) error {
+	return receiptStore.Save(receipts)
+}
+
+func (g *ReceiptService) UpdateItems(update func([]models.GoodsReceipt) ([]models.GoodsReceipt, error)) error {
+	return receiptStore.Update(update)
+}
@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type StockCountRepository interface {
+	ReadItems() ([]models.StockCount, error)
+	SaveItems([]models.StockCount) error
+	UpdateItems(update func([]models.StockCount) ([]models.StockCount, error)) error
+}
+
+type StockCountService struct{}
+
+var stockCountStore = newJSONStore[[]models.StockCount](&config.CountsFile, "stock counts")
+
+func (c *StockCountService) ReadItems() ([]models.StockCount, error) {
+	return stockCountStore.Read()
+}
+
+func (c *StockCountService) SaveItems(counts []models.StockCount) error {
+	return stockCountStore.Save(counts)
+}
+
+func (c *StockCountService) UpdateItems(update func([]models.StockCount) ([]models.StockCount, error)) error {
+	return stockCountStore.Update(update)
+}
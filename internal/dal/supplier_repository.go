@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type SupplierRepository interface {
+	ReadItems() ([]models.Supplier, error)
+	SaveItems([]models.Supplier) error
+	UpdateItems(update func([]models.Supplier) ([]models.Supplier, error)) error
+}
+
+type SupplierService struct{}
+
+var supplierStore = newJSONStore[[]models.Supplier](&config.SuppliersFile, "suppliers")
+
+func (s *SupplierService) ReadItems() ([]models.Supplier, error) {
+	return supplierStore.Read()
+}
+
+func (s *SupplierService) SaveItems(suppliers []models.Supplier) error {
+	return supplierStore.Save(suppliers)
+}
+
+func (s *SupplierService) UpdateItems(update func([]models.Supplier) ([]models.Supplier, error)) error {
+	return supplierStore.Update(update)
+}
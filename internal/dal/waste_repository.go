@@ -0,0 +1,28 @@
+package dal
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/models"
+)
+
+type WasteRepository interface {
+	ReadItems() ([]models.WasteEntry, error)
+	SaveItems([]models.WasteEntry) error
+	UpdateItems(update func([]models.WasteEntry) ([]models.WasteEntry, error)) error
+}
+
+type WasteService struct{}
+
+var wasteStore = newJSONStore[[]models.WasteEntry](&config.WasteFile, "waste entries")
+
+func (w *WasteService) ReadItems() ([]models.WasteEntry, error) {
+	return wasteStore.Read()
+}
+
+func (w *WasteService) SaveItems(entries []models.WasteEntry) error {
+	return wasteStore.Save(entries)
+}
+
+func (w *WasteService) UpdateItems(update func([]models.WasteEntry) ([]models.WasteEntry, error)) error {
+	return wasteStore.Update(update)
+}
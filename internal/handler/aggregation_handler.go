@@ -3,19 +3,33 @@ package handler
 
 import (
 	"encoding/json"
+	"hot-coffee/config"
 	"hot-coffee/internal/dal"
 	"hot-coffee/internal/service"
 	"hot-coffee/logging"
+	"hot-coffee/models"
 	"hot-coffee/utils"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var reportService service.ReportService
 
 func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	orderRepo := &dal.OrderService{}
+	menuitemRepo := &dal.MenuItemService{}
+	aggRepo := &dal.AggregationService{}
+	inventoryRepo := &dal.InventoryItemService{}
+	reportService = service.NewReportService(menuitemRepo, aggRepo, orderRepo, inventoryRepo)
+
 	switch r.Method {
 	case http.MethodGet:
 		handleGetReports(w, r)
+	case http.MethodPost:
+		handlePostReports(w, r)
 	default:
 		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
 	}
@@ -26,25 +40,103 @@ func handleGetReports(w http.ResponseWriter, r *http.Request) {
 
 	// Log the GET request
 	logging.Info("Handling GET request for reports", "url", r.URL.Path)
-	orderRepo := &dal.OrderService{}
-	menuitemRepo := &dal.MenuItemService{}
-	aggRepo := &dal.AggregationService{}
-	reportService = service.NewReportService(menuitemRepo, aggRepo, orderRepo)
 
 	// Check the URL for specific report
 	switch r.URL.Path {
 	case "/reports/total-sales":
-		handleTotalSales(w)
-	case "reports/popular-items":
-		handlePopularItems(w)
+		handleTotalSales(w, r)
+	case "/reports/popular-items":
+		handlePopularItems(w, r)
+	case "/reports/daily-item":
+		handleDailyItem(w, r)
+	case "/reports/sales":
+		handleSalesReport(w, r)
+	case "/reports/margins":
+		handleMarginReport(w, r)
+	case "/reports/menu-engineering":
+		handleMenuEngineering(w, r)
+	case "/reports/waste":
+		handleWasteReport(w, r)
+	case "/reports/consumption":
+		handleConsumptionReport(w, r)
+	case "/reports/customers":
+		handleCustomerReport(w, r)
+	case "/reports/aggregates":
+		handleGetAggregates(w, r)
+	default:
+		if date := strings.TrimPrefix(r.URL.Path, "/reports/day-close/"); date != r.URL.Path && date != "" {
+			handleGetDayClose(w, r, date)
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, "Report not found")
+	}
+}
+
+func handlePostReports(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling POST request for reports", "url", r.URL.Path)
+
+	switch r.URL.Path {
 	case "/reports/daily-item":
-		handleDailyItem(w)
+		handleSetDailyItem(w, r)
+	case "/reports/aggregates/rebuild":
+		handleRebuildAggregates(w)
+	case "/reports/day-close":
+		handleCloseDay(w, r)
 	default:
 		writeJSONError(w, http.StatusNotFound, "Report not found")
 	}
 }
 
-func handleTotalSales(w http.ResponseWriter) {
+func handleGetAggregates(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	data, err := reportService.GetAggregates()
+	if err != nil {
+		logging.Error("Failed to fetch sales aggregates", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch sales aggregates")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, data, exportTable{name: "days", rows: aggregateDays(data)})
+}
+
+// aggregateDay is one business day of the sales aggregates, as exported.
+type aggregateDay struct {
+	Date string `json:"date"`
+	models.DaySales
+}
+
+// aggregateDays lists the aggregated business days in date order.
+func aggregateDays(data models.AggregationData) []aggregateDay {
+	days := make([]aggregateDay, 0, len(data.Days))
+	for date, daySales := range data.Days {
+		days = append(days, aggregateDay{Date: date, DaySales: daySales})
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date < days[j].Date
+	})
+	return days
+}
+
+func handleRebuildAggregates(w http.ResponseWriter) {
+	defer utils.CatchCriticalPoint()
+
+	data, err := reportService.RebuildAggregates()
+	if err != nil {
+		logging.Error("Failed to rebuild sales aggregates", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to rebuild sales aggregates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+	logging.Info("Successfully rebuilt sales aggregates", "orders", data.TotalOrders)
+}
+
+func handleTotalSales(w http.ResponseWriter, r *http.Request) {
 	defer utils.CatchCriticalPoint()
 
 	totalSales, err := reportService.TotalSalesAmount()
@@ -55,39 +147,205 @@ func handleTotalSales(w http.ResponseWriter) {
 	}
 
 	// Return total sales as a JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]float64{"total_sales": totalSales})
+	writeResponse(w, r, http.StatusOK, map[string]float64{"total_sales": totalSales})
 }
 
-func handlePopularItems(w http.ResponseWriter) {
+func handlePopularItems(w http.ResponseWriter, r *http.Request) {
 	defer utils.CatchCriticalPoint()
 
-	popularItems, err := reportService.GetMostPopularItem()
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit", 10)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	popularItems, err := reportService.GetPopularItems(from, to, limit, r.URL.Query().Get("by"), r.URL.Query().Get("compare"))
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ranking") || strings.HasPrefix(err.Error(), "invalid comparison") ||
+			err.Error() == "comparison needs a from date" {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		logging.Error("Failed to fetch popular items", err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch popular items")
 		return
 	}
 
 	// Return popular items as a JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(popularItems)
+	tables := []exportTable{
+		{name: "items", rows: popularItems.Items},
+		{name: "categories", rows: popularItems.Categories},
+	}
+	if popularItems.Comparison != nil {
+		tables = append(tables,
+			exportTable{name: "item_comparison", rows: popularItems.Comparison.Items},
+			exportTable{name: "category_comparison", rows: popularItems.Comparison.Categories})
+	}
+	writeResponse(w, r, http.StatusOK, popularItems, tables...)
 }
 
-func handleDailyItem(w http.ResponseWriter) {
+func handleCustomerReport(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit", 10)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := reportService.GetCustomerReport(from, to, limit)
+	if err != nil {
+		logging.Error("Failed to fetch customer report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch customer report")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, report, exportTable{name: "top_customers", rows: report.TopCustomers})
+}
+
+func handleSalesReport(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	// Plain dates and buckets follow the requested time zone, the business one by default
+	loc, err := utils.BusinessLocation()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid time zone: "+tz)
+			return
+		}
+	}
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load time zone")
+		return
+	}
+
+	from, to, err := utils.ParseDateRangeIn(r.URL.Query().Get("from"), r.URL.Query().Get("to"), loc)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := reportService.GetSalesReport(from, to, r.URL.Query().Get("interval"), r.URL.Query().Get("compare"), loc)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid interval") || strings.HasPrefix(err.Error(), "too many buckets") ||
+			strings.HasPrefix(err.Error(), "invalid comparison") {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logging.Error("Failed to fetch sales report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch sales report")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, report, exportTable{name: "buckets", rows: report.Buckets})
+}
+
+func handleDailyItem(w http.ResponseWriter, r *http.Request) {
 	defer utils.CatchCriticalPoint()
 
 	dailyItem, err := reportService.GetDailyItem()
 	if err != nil {
+		if err.Error() == "no menu items available" {
+			writeJSONError(w, http.StatusNotFound, "No menu items available")
+			return
+		}
 		logging.Error("Failed to fetch daily item", err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch daily item")
 		return
 	}
 
 	// Return the daily item as a JSON response
+	writeResponse(w, r, http.StatusOK, dailyItem)
+}
+
+func handleSetDailyItem(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	var request models.DailyItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	dailyItem, err := reportService.SetDailyItem(request)
+	if err != nil {
+		switch {
+		case err.Error() == "menu item not found or sold out", err.Error() == "no menu items available":
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid daily item strategy"), err.Error() == "product ID or strategy is required", err.Error() == "give either a product ID or a strategy":
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			logging.Error("Failed to set daily item", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to set daily item")
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(dailyItem)
+	logging.Info("Successfully set daily item", "itemID", dailyItem.ID)
+}
+
+func handleMarginReport(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	// The threshold defaults to the configured one and can be overridden per request
+	threshold := config.MarginThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid threshold")
+			return
+		}
+		threshold = parsed
+	}
+
+	report, err := reportService.GetMarginReport(threshold)
+	if err != nil {
+		logging.Error("Failed to fetch margin report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch margin report")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, report, exportTable{name: "items", rows: report.Items})
+}
+
+func handleMenuEngineering(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var popularity float64
+	if value := r.URL.Query().Get("popularity"); value != "" {
+		if popularity, err = strconv.ParseFloat(value, 64); err != nil || popularity <= 0 || popularity > 100 {
+			writeJSONError(w, http.StatusBadRequest, "Invalid popularity")
+			return
+		}
+	}
+
+	report, err := reportService.GetMenuEngineering(from, to, popularity)
+	if err != nil {
+		logging.Error("Failed to fetch menu engineering report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch menu engineering report")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, report, exportTable{name: "items", rows: report.Items})
 }
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+)
+
+var consumptionService service.ConsumptionService
+
+// consumptionDay and consumptionMenuItem are the per-ingredient breakdowns
+// of the consumption report, as exported.
+type consumptionDay struct {
+	IngredientID string `json:"ingredient_id"`
+	models.ConsumptionDay
+}
+
+type consumptionMenuItem struct {
+	IngredientID string `json:"ingredient_id"`
+	models.MenuItemConsumption
+}
+
+func handleConsumptionReport(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	consumptionService = service.NewConsumptionService(&dal.OrderService{}, &dal.MenuItemService{}, &dal.InventoryItemService{}, &dal.MovementService{})
+	report, err := consumptionService.GetConsumptionReport(from, to)
+	if err != nil {
+		logging.Error("Failed to fetch consumption report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch consumption report")
+		return
+	}
+
+	days := []consumptionDay{}
+	menuItems := []consumptionMenuItem{}
+	for _, ingredient := range report.Ingredients {
+		for _, day := range ingredient.ByDay {
+			days = append(days, consumptionDay{IngredientID: ingredient.IngredientID, ConsumptionDay: day})
+		}
+		for _, menuItem := range ingredient.ByMenuItem {
+			menuItems = append(menuItems, consumptionMenuItem{IngredientID: ingredient.IngredientID, MenuItemConsumption: menuItem})
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, report,
+		exportTable{name: "ingredients", rows: report.Ingredients},
+		exportTable{name: "by_day", rows: days},
+		exportTable{name: "by_menu_item", rows: menuItems})
+}
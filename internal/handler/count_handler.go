@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+	"strings"
+)
+
+var countService service.CountService
+
+// CountHandler serves stock count sessions under /inventory/counts.
+func CountHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	countService = service.NewCountService(&dal.StockCountService{}, &dal.InventoryItemService{}, &dal.MovementService{})
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/inventory/counts"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		handleGetCounts(w)
+	case r.Method == http.MethodPost && path == "":
+		handleOpenCount(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		handleGetCount(w, parts[0])
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "variance":
+		handleCountVariance(w, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "submit":
+		handleSubmitCount(w, r, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "approve":
+		handleApproveCount(w, r, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "cancel":
+		writeCountResult(w)(countService.CancelCount(parts[0]))
+	default:
+		writeJSONError(w, http.StatusNotFound, "Stock count endpoint not found")
+	}
+}
+
+func handleGetCounts(w http.ResponseWriter) {
+	defer utils.CatchCriticalPoint()
+
+	counts, err := countService.GetCounts()
+	if err != nil {
+		logging.Error("Failed to fetch stock counts", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch stock counts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(counts)
+}
+
+func handleGetCount(w http.ResponseWriter, countId string) {
+	defer utils.CatchCriticalPoint()
+
+	count, err := countService.GetCountByID(countId)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Stock count not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(count)
+}
+
+func handleOpenCount(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling open stock count request")
+
+	var body struct {
+		OpenedBy      string   `json:"opened_by"`
+		Note          string   `json:"note"`
+		IngredientIDs []string `json:"ingredient_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	count, err := countService.OpenCount(body.OpenedBy, body.Note, body.IngredientIDs)
+	if err != nil {
+		logging.Error("Failed to open stock count", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(count)
+}
+
+func handleSubmitCount(w http.ResponseWriter, r *http.Request, countId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling submit stock count request", "countId", countId)
+
+	var body struct {
+		CountedBy string                   `json:"counted_by"`
+		Counts    []models.CountedQuantity `json:"counts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	writeCountResult(w)(countService.SubmitCount(countId, body.CountedBy, body.Counts))
+}
+
+func handleApproveCount(w http.ResponseWriter, r *http.Request, countId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling approve stock count request", "countId", countId)
+
+	var body struct {
+		ApprovedBy string `json:"approved_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	writeCountResult(w)(countService.ApproveCount(countId, body.ApprovedBy))
+}
+
+func handleCountVariance(w http.ResponseWriter, countId string) {
+	defer utils.CatchCriticalPoint()
+
+	report, err := countService.GetVarianceReport(countId)
+	if err != nil {
+		if err.Error() == "stock count not found" {
+			writeJSONError(w, http.StatusNotFound, "Stock count not found")
+		} else {
+			logging.Error("Failed to build variance report", err, "countId", countId)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to build variance report")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeCountResult writes the stock count returned by a session change, or
+// its error.
+func writeCountResult(w http.ResponseWriter) func(models.StockCount, error) {
+	return func(count models.StockCount, err error) {
+		if err != nil {
+			if err.Error() == "stock count not found" {
+				writeJSONError(w, http.StatusNotFound, "Stock count not found")
+			} else {
+				logging.Error("Failed to update stock count", err)
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(count)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var dayCloseService service.DayCloseService
+
+func newDayCloseService() service.DayCloseService {
+	return service.NewDayCloseService(&dal.DayCloseService{}, &dal.OrderService{}, &dal.MenuItemService{}, &dal.InventoryItemService{}, &dal.MovementService{})
+}
+
+// handleCloseDay handles POST /reports/day-close, closing today or the
+// business day given in the body.
+func handleCloseDay(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	var request models.DayCloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	dayCloseService = newDayCloseService()
+	dayClose, err := dayCloseService.CloseDay(request)
+	if err != nil {
+		switch {
+		case err.Error() == "business day is already closed":
+			writeJSONError(w, http.StatusConflict, "Business day is already closed")
+		case err.Error() == "business day has not started":
+			writeJSONError(w, http.StatusBadRequest, "Business day has not started")
+		case strings.HasPrefix(err.Error(), "invalid date"):
+			writeJSONError(w, http.StatusBadRequest, "Invalid date: "+request.Date)
+		default:
+			logging.Error("Failed to close business day", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to close business day")
+		}
+		return
+	}
+
+	writeDayClose(w, r, http.StatusCreated, dayClose)
+}
+
+// handleGetDayClose handles GET /reports/day-close/{date}.
+func handleGetDayClose(w http.ResponseWriter, r *http.Request, date string) {
+	defer utils.CatchCriticalPoint()
+
+	dayCloseService = newDayCloseService()
+	dayClose, err := dayCloseService.GetDayClose(date)
+	if err != nil {
+		if err.Error() == "day close not found" {
+			writeJSONError(w, http.StatusNotFound, "Day close not found")
+			return
+		}
+		logging.Error("Failed to fetch day close", err, "date", date)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch day close")
+		return
+	}
+
+	writeDayClose(w, r, http.StatusOK, dayClose)
+}
+
+func writeDayClose(w http.ResponseWriter, r *http.Request, statusCode int, dayClose models.DayClose) {
+	writeResponse(w, r, statusCode, dayClose,
+		exportTable{name: "products", rows: dayClose.Products},
+		exportTable{name: "categories", rows: dayClose.Categories},
+		exportTable{name: "payment_methods", rows: dayClose.PaymentMethods},
+		exportTable{name: "cancelled_orders", rows: dayClose.Cancelled},
+		exportTable{name: "voided_orders", rows: dayClose.Voided},
+		exportTable{name: "open_orders", rows: dayClose.OpenOrders},
+		exportTable{name: "inventory", rows: dayClose.Inventory})
+}
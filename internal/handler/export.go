@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hot-coffee/logging"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Response formats. JSON is the default; CSV and NDJSON export a list of rows.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// exportFlushRows is how many rows are written between flushes to the client.
+const exportFlushRows = 100
+
+// exportTable is a list of rows that a response can be exported as. Reports
+// with several lists name them, and ?rows= picks one; the first is the default.
+type exportTable struct {
+	name string
+	rows interface{}
+}
+
+// responseFormat reads the format from ?format=, or else from the Accept header.
+func responseFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "":
+	case formatJSON, formatCSV, formatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format: %s", format)
+	}
+
+	return acceptedFormat(r.Header.Get("Accept")), nil
+}
+
+// acceptFormats are the media types of each format, JSON first so that it
+// wins a tie.
+var acceptFormats = []struct {
+	format     string
+	mediaTypes []string
+}{
+	{formatJSON, []string{"application/json"}},
+	{formatCSV, []string{"text/csv"}},
+	{formatNDJSON, []string{"application/x-ndjson", "application/ndjson"}},
+}
+
+// acceptedFormat picks the format an Accept header prefers. Each format
+// takes the quality of the most specific media range matching it; the
+// highest quality wins, then the more specific match. Without any
+// acceptable format the response is JSON.
+func acceptedFormat(accept string) string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				quality = q
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	best, bestQuality, bestSpecificity := formatJSON, 0.0, -1
+	for _, candidate := range acceptFormats {
+		quality, specificity := 0.0, -1
+		for _, mediaType := range candidate.mediaTypes {
+			for _, accepted := range ranges {
+				matched := -1
+				switch {
+				case accepted.mediaType == mediaType:
+					matched = 2
+				case accepted.mediaType == strings.Split(mediaType, "/")[0]+"/*":
+					matched = 1
+				case accepted.mediaType == "*/*":
+					matched = 0
+				}
+				if matched > specificity {
+					quality, specificity = accepted.quality, matched
+				}
+			}
+		}
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = candidate.format, quality, specificity
+		}
+	}
+	return best
+}
+
+// writeResponse writes value as JSON, or exports it as CSV or NDJSON when the
+// request asks for it. Without tables the value itself is exported as a
+// single row. Rows are streamed to the client as they are encoded.
+func writeResponse(w http.ResponseWriter, r *http.Request, statusCode int, value interface{}, tables ...exportTable) {
+	format, err := responseFormat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid format: "+r.URL.Query().Get("format"))
+		return
+	}
+
+	if format == formatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(value)
+		return
+	}
+
+	table := exportTable{name: "rows", rows: []interface{}{value}}
+	if len(tables) > 0 {
+		table = tables[0]
+	}
+	if name := r.URL.Query().Get("rows"); name != "" {
+		found := false
+		for _, candidate := range tables {
+			if candidate.name == name {
+				table, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			writeJSONError(w, http.StatusBadRequest, "Unknown rows: "+name)
+			return
+		}
+	}
+
+	rows := reflect.ValueOf(table.rows)
+	if rows.Kind() != reflect.Slice {
+		writeJSONError(w, http.StatusInternalServerError, "Response cannot be exported")
+		return
+	}
+
+	filename := strings.Trim(strings.ReplaceAll(r.URL.Path, "/", "-"), "-")
+	if len(tables) > 1 {
+		filename += "-" + table.name
+	}
+
+	if format == formatNDJSON {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.ndjson"`)
+		w.WriteHeader(statusCode)
+		encoder := json.NewEncoder(w)
+		for i := 0; i < rows.Len(); i++ {
+			if err := encoder.Encode(rows.Index(i).Interface()); err != nil {
+				logging.Error("Failed to write export row", err, "url", r.URL.Path)
+				return
+			}
+			if (i+1)%exportFlushRows == 0 {
+				flushExport(w)
+			}
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+	w.WriteHeader(statusCode)
+
+	writer := csv.NewWriter(w)
+	columns := exportColumns(rows)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	writer.Write(header)
+
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		record := make([]string, len(columns))
+		for j, column := range columns {
+			record[j] = exportCell(column.value(row))
+		}
+		writer.Write(record)
+
+		if (i+1)%exportFlushRows == 0 {
+			writer.Flush()
+			flushExport(w)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logging.Error("Failed to write export rows", err, "url", r.URL.Path)
+	}
+}
+
+// flushExport pushes what was written so far to the client.
+func flushExport(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// exportColumn is one CSV column and how to get its value out of a row.
+type exportColumn struct {
+	name  string
+	value func(row reflect.Value) reflect.Value
+}
+
+// exportColumns lists the columns of a slice of rows. Struct fields are named
+// after their JSON tags, nested structs are flattened into "outer.inner"
+// columns and embedded ones into the outer struct. Map rows get a column per
+// key of the first row.
+func exportColumns(rows reflect.Value) []exportColumn {
+	elemType := rows.Type().Elem()
+	if elemType.Kind() == reflect.Interface && rows.Len() > 0 {
+		elemType = indirect(rows.Index(0)).Type()
+	}
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	switch elemType.Kind() {
+	case reflect.Struct:
+		return structColumns(elemType, "", func(row reflect.Value) reflect.Value { return indirect(row) })
+	case reflect.Map:
+		if rows.Len() == 0 {
+			return nil
+		}
+		var keys []string
+		for _, key := range indirect(rows.Index(0)).MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(keys)
+
+		columns := make([]exportColumn, len(keys))
+		for i, key := range keys {
+			key := key
+			columns[i] = exportColumn{name: key, value: func(row reflect.Value) reflect.Value {
+				row = indirect(row)
+				if !row.IsValid() {
+					return reflect.Value{}
+				}
+				return row.MapIndex(reflect.ValueOf(key).Convert(row.Type().Key()))
+			}}
+		}
+		return columns
+	}
+	return []exportColumn{{name: "value", value: func(row reflect.Value) reflect.Value { return row }}}
+}
+
+func structColumns(structType reflect.Type, prefix string, get func(reflect.Value) reflect.Value) []exportColumn {
+	var columns []exportColumn
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		index := i
+		fieldValue := func(row reflect.Value) reflect.Value {
+			row = get(row)
+			if !row.IsValid() {
+				return reflect.Value{}
+			}
+			return indirect(row.Field(index))
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				columns = append(columns, structColumns(fieldType, prefix, fieldValue)...)
+			} else {
+				columns = append(columns, structColumns(fieldType, prefix+name+".", fieldValue)...)
+			}
+			continue
+		}
+		columns = append(columns, exportColumn{name: prefix + name, value: fieldValue})
+	}
+	return columns
+}
+
+// indirect follows pointers and interfaces, returning the zero Value for nil.
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// exportCell formats a value for a CSV cell. Lists and maps are written as JSON.
+func exportCell(value reflect.Value) string {
+	value = indirect(value)
+	if !value.IsValid() {
+		return ""
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return escapeFormula(value.String())
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return ""
+		}
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		logging.Warn("Failed to encode export cell", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// escapeFormula keeps a spreadsheet from running text as a formula, by
+// prefixing text that starts like one with a quote. Numbers are formatted
+// by the export itself and are left alone.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
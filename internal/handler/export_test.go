@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptedFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: formatJSON},
+		{accept: "text/csv", want: formatCSV},
+		{accept: "application/x-ndjson", want: formatNDJSON},
+		{accept: "*/*", want: formatJSON},
+		{accept: "text/*", want: formatCSV},
+		{accept: "application/json;q=0.5, text/csv", want: formatCSV},
+		{accept: "text/csv;q=0.9, */*;q=1", want: formatJSON},
+		{accept: "text/html", want: formatJSON},
+		{accept: "TEXT/CSV; Q=1", want: formatCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := acceptedFormat(tt.accept); got != tt.want {
+				t.Errorf("acceptedFormat(%q) = %s, want %s", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	type price struct {
+		Amount   float64 `json:"amount"`
+		Currency string  `json:"currency"`
+	}
+	type Named struct {
+		Name string `json:"name"`
+	}
+	type row struct {
+		ID string `json:"id"`
+		Named
+		Price  price    `json:"price"`
+		Tags   []string `json:"tags"`
+		Secret string   `json:"-"`
+	}
+	type report struct {
+		Total int   `json:"total"`
+		Rows  []row `json:"rows"`
+	}
+	value := report{
+		Total: 2,
+		Rows: []row{
+			{ID: "latte", Named: Named{Name: "Latte, large"}, Price: price{Amount: 4.5, Currency: "EUR"}, Tags: []string{"hot", "milk"}, Secret: "x"},
+			{ID: "formula", Named: Named{Name: "=SUM(A1:A2)"}, Price: price{Amount: -1}},
+		},
+	}
+	tables := []exportTable{{name: "rows", rows: value.Rows}, {name: "totals", rows: []report{value}}}
+
+	tests := []struct {
+		name            string
+		target          string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json by default",
+			target:          "/reports/test",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"total":2,"rows":[{"id":"latte","name":"Latte, large","price":{"amount":4.5,"currency":"EUR"},"tags":["hot","milk"]},{"id":"formula","name":"=SUM(A1:A2)","price":{"amount":-1,"currency":""},"tags":null}]}` + "\n",
+		},
+		{
+			name:            "csv by accept header",
+			target:          "/reports/test",
+			accept:          "text/csv",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv",
+			wantBody: "id,name,price.amount,price.currency,tags\n" +
+				"latte,\"Latte, large\",4.5,EUR,\"[\"\"hot\"\",\"\"milk\"\"]\"\n" +
+				"formula,'=SUM(A1:A2),-1,,\n",
+		},
+		{
+			name:            "ndjson by query",
+			target:          "/reports/test?format=ndjson&rows=totals",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody:        `{"total":2,"rows":[{"id":"latte","name":"Latte, large","price":{"amount":4.5,"currency":"EUR"},"tags":["hot","milk"]},{"id":"formula","name":"=SUM(A1:A2)","price":{"amount":-1,"currency":""},"tags":null}]}` + "\n",
+		},
+		{
+			name:       "unknown rows",
+			target:     "/reports/test?format=csv&rows=items",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid format",
+			target:     "/reports/test?format=xml",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			writeResponse(w, r, http.StatusOK, value, tables...)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.wantContentType {
+				t.Errorf("content type = %s, want %s", contentType, tt.wantContentType)
+			}
+			if body := w.Body.String(); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -15,6 +15,8 @@ import (
 
 var Inventory service.InventoryService
 
+var forecastService service.ForecastService
+
 // InventoryHandler handles different HTTP methods for the inventory endpoint.
 func InventoryHandler(w http.ResponseWriter, r *http.Request) {
 	defer utils.CatchCriticalPoint()
@@ -23,16 +25,28 @@ func InventoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	inventoryRepo := &dal.InventoryItemService{}
-	Inventory = service.NewInventoryService(inventoryRepo)
+	movementRepo := &dal.MovementService{}
+	Inventory = service.NewInventoryService(inventoryRepo, movementRepo, &dal.ReceiptService{}, &dal.MenuItemService{})
+	ledger = service.NewLedgerService(movementRepo, inventoryRepo)
 	item, itemId, _ := splitPath(r.URL.Path)
 
 	switch r.Method {
 	case http.MethodGet:
-		handleGetInventory(w, item, itemId)
+		if strings.HasSuffix(r.URL.Path, "/movements") {
+			handleGetItemMovements(w, r, itemId)
+		} else {
+			handleGetInventory(w, r, item, itemId)
+		}
 	case http.MethodPost:
-		handlePostInventory(w, r)
+		if strings.HasSuffix(r.URL.Path, "/receive") {
+			handleReceiveInventory(w, r, itemId)
+		} else {
+			handlePostInventory(w, r)
+		}
 	case http.MethodPut:
 		handlePutInventory(w, r, itemId)
+	case http.MethodPatch:
+		handlePatchInventory(w, r, itemId)
 	case http.MethodDelete:
 		handleDeleteInventory(w, itemId)
 	default:
@@ -41,8 +55,111 @@ func InventoryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// InventoryAlertsHandler handles GET /inventory/alerts, listing the
+// ingredients at or below their reorder point.
+func InventoryAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+		return
+	}
+
+	Inventory = service.NewInventoryService(&dal.InventoryItemService{}, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	alerts, err := Inventory.GetLowStockAlerts()
+	if err != nil {
+		logging.Error("Failed to fetch low stock alerts", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch low stock alerts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// InventoryExpiryHandler handles GET /inventory/expiry?days=N, listing the
+// expired lots and those expiring within N days (7 by default).
+func InventoryExpiryHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+		return
+	}
+
+	days, err := queryInt(r, "days", 7)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	Inventory = service.NewInventoryService(&dal.InventoryItemService{}, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	report, err := Inventory.GetExpiryReport(days)
+	if err != nil {
+		logging.Error("Failed to build expiry report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to build expiry report")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// InventoryForecastHandler handles GET /inventory/forecast. The history
+// (28 days), moving average window (7) and horizon (7) can be set with
+// ?history=&window=&horizon=.
+func InventoryForecastHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+		return
+	}
+
+	history, err := queryInt(r, "history", 28)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	window, err := queryInt(r, "window", 7)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	horizon, err := queryInt(r, "horizon", 7)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	forecastService = service.NewForecastService(&dal.OrderService{}, &dal.MenuItemService{}, &dal.InventoryItemService{})
+	report, err := forecastService.GetForecast(history, window, horizon)
+	if err != nil {
+		logging.Error("Failed to build inventory forecast", err)
+		switch err.Error() {
+		case "history, window and horizon must be greater than zero", "window cannot be longer than the history":
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, "Failed to build inventory forecast")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
 // handleGetInventory handles the GET request for fetching inventory items.
-func handleGetInventory(w http.ResponseWriter, item string, itemId string) {
+func handleGetInventory(w http.ResponseWriter, r *http.Request, item string, itemId string) {
 	defer utils.CatchCriticalPoint()
 
 	logging.Info("Handling GET request", "item", item, "itemId", itemId)
@@ -54,8 +171,7 @@ func handleGetInventory(w http.ResponseWriter, item string, itemId string) {
 			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch all inventory items")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(inventoryItems)
+		writeResponse(w, r, http.StatusOK, inventoryItems, exportTable{name: "inventory", rows: inventoryItems})
 	} else {
 		inventoryItem, err := Inventory.GetInventoryItemByID(itemId)
 		if err != nil {
@@ -63,8 +179,7 @@ func handleGetInventory(w http.ResponseWriter, item string, itemId string) {
 			writeJSONError(w, http.StatusNotFound, "Inventory item not found")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(inventoryItem)
+		writeResponse(w, r, http.StatusOK, inventoryItem)
 	}
 }
 
@@ -100,6 +215,14 @@ func handlePostInventory(w http.ResponseWriter, r *http.Request) {
 
 	if err := Inventory.AddInventoryItem(newItem); err != nil {
 		logging.Error("Failed to add inventory item", err)
+		if strings.HasPrefix(err.Error(), "invalid inventory item") {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "unit change breaks the recipe") {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to add inventory item")
 		return
 	}
@@ -139,6 +262,18 @@ func handlePutInventory(w http.ResponseWriter, r *http.Request, itemId string) {
 
 	if err := Inventory.UpdateInventoryItem(itemId, updatedItem); err != nil {
 		logging.Error("Failed to update inventory item", err, "itemId", itemId)
+		if err.Error() == "inventory item not found" {
+			writeJSONError(w, http.StatusNotFound, "Inventory item not found")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid inventory item") {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err.Error() == "unit cannot be changed" || strings.HasPrefix(err.Error(), "unit change breaks the recipe") {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to update inventory item")
 		return
 	}
@@ -148,6 +283,36 @@ func handlePutInventory(w http.ResponseWriter, r *http.Request, itemId string) {
 	logging.Info("Successfully updated inventory item", "itemId", itemId, "updatedItem", updatedItem)
 }
 
+// handlePatchInventory handles the PATCH request for adjusting or partially
+// updating an inventory item.
+func handlePatchInventory(w http.ResponseWriter, r *http.Request, itemId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling PATCH request", "itemId", itemId)
+
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		logging.Warn("Invalid patch body", "itemId", itemId)
+		writeJSONError(w, http.StatusBadRequest, "Request body must be a JSON object")
+		return
+	}
+
+	patchedItem, err := Inventory.PatchInventoryItem(itemId, patch)
+	if err != nil {
+		if err.Error() == "inventory item not found" {
+			writeJSONError(w, http.StatusNotFound, "Inventory item not found")
+		} else {
+			logging.Error("Failed to patch inventory item", err, "itemId", itemId)
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(patchedItem)
+	logging.Info("Successfully patched inventory item", "itemId", itemId)
+}
+
 // handleDeleteInventory handles the DELETE request for removing an inventory item.
 func handleDeleteInventory(w http.ResponseWriter, itemId string) {
 	defer utils.CatchCriticalPoint()
@@ -159,6 +324,9 @@ func handleDeleteInventory(w http.ResponseWriter, itemId string) {
 		if err.Error() == "inventory item not found" {
 			logging.Error("Inventory item not found", err, "itemId", itemId)
 			writeJSONError(w, http.StatusNotFound, "Inventory item not found")
+		} else if strings.HasPrefix(err.Error(), "inventory item is used by menu item") {
+			logging.Warn("Inventory item is used by a recipe", "itemId", itemId)
+			writeJSONError(w, http.StatusConflict, err.Error())
 		} else {
 			logging.Error("Failed to delete inventory item", err, "itemId", itemId)
 			writeJSONError(w, http.StatusInternalServerError, "Failed to delete inventory item")
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/utils"
+	"net/http"
+	"strings"
+)
+
+var ledger service.LedgerService
+
+// LedgerHandler serves the inventory movement ledger under /inventory/movements.
+func LedgerHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	ledger = service.NewLedgerService(&dal.MovementService{}, &dal.InventoryItemService{})
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/inventory/movements"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		handleGetMovements(w, r, "")
+	case r.Method == http.MethodGet && path == "drift":
+		handleLedgerDrift(w)
+	case r.Method == http.MethodPost && path == "rebuild":
+		handleLedgerRebuild(w)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "reverse":
+		handleReverseMovement(w, r, parts[0])
+	default:
+		writeJSONError(w, http.StatusNotFound, "Ledger endpoint not found")
+	}
+}
+
+// handleGetItemMovements handles GET /inventory/{id}/movements.
+func handleGetItemMovements(w http.ResponseWriter, r *http.Request, itemId string) {
+	defer utils.CatchCriticalPoint()
+
+	if _, err := Inventory.GetInventoryItemByID(itemId); err != nil {
+		writeJSONError(w, http.StatusNotFound, "Inventory item not found")
+		return
+	}
+	handleGetMovements(w, r, itemId)
+}
+
+func handleGetMovements(w http.ResponseWriter, r *http.Request, itemId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling GET movements request", "itemId", itemId)
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	movements, err := ledger.GetMovements(itemId, from, to)
+	if err != nil {
+		logging.Error("Failed to fetch inventory movements", err, "itemId", itemId)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch inventory movements")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(movements)
+}
+
+func handleReverseMovement(w http.ResponseWriter, r *http.Request, movementId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling reverse movement request", "movementId", movementId)
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+			return
+		}
+	}
+
+	reversal, err := ledger.ReverseMovement(movementId, body.Reason)
+	if err != nil {
+		switch err.Error() {
+		case "inventory movement not found":
+			writeJSONError(w, http.StatusNotFound, "Inventory movement not found")
+		case "inventory movement already reversed", "a reversal cannot be reversed":
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			logging.Error("Failed to reverse inventory movement", err, "movementId", movementId)
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
+
+func handleLedgerDrift(w http.ResponseWriter) {
+	defer utils.CatchCriticalPoint()
+
+	drifts, err := ledger.CheckDrift()
+	if err != nil {
+		logging.Error("Failed to check ledger drift", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to check ledger drift")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(drifts)
+}
+
+func handleLedgerRebuild(w http.ResponseWriter) {
+	defer utils.CatchCriticalPoint()
+
+	corrected, err := ledger.RebuildQuantities()
+	if err != nil {
+		logging.Error("Failed to rebuild quantities from ledger", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to rebuild quantities from ledger")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"corrected": corrected})
+}
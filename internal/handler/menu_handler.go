@@ -9,6 +9,7 @@ import (
 	"hot-coffee/models"
 	"hot-coffee/utils"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -22,12 +23,17 @@ func MenuHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	menuitemRepo := &dal.MenuItemService{}
-	menuitem = service.NewMenuService(menuitemRepo)
+	inventoryRepo := &dal.InventoryItemService{}
+	menuitem = service.NewMenuService(menuitemRepo, inventoryRepo)
 	item, itemId, _ := splitPath(r.URL.Path)
 
 	switch r.Method {
 	case http.MethodGet:
-		handleGetMenu(w, item, itemId)
+		if strings.HasSuffix(r.URL.Path, "/cost") {
+			handleGetMenuCost(w, itemId)
+		} else {
+			handleGetMenu(w, r, item, itemId)
+		}
 	case http.MethodPost:
 		handlePostMenu(w, r)
 	case http.MethodPut:
@@ -56,21 +62,26 @@ func splitPath(path string) (string, string, error) {
 	return item, itemId, nil
 }
 
-func handleGetMenu(w http.ResponseWriter, item string, itemId string) {
+func handleGetMenu(w http.ResponseWriter, r *http.Request, item string, itemId string) {
 	defer utils.CatchCriticalPoint()
 
 	// Log the GET request
 	logging.Info("Handling GET request", "item", item, "itemId", itemId)
 
 	if itemId == "" {
-		menuItems, err := menuitem.FetchAllMenuItems()
+		var menuItems []models.MenuItem
+		var err error
+		if excluded := queryList(r, "exclude_allergen"); len(excluded) > 0 {
+			menuItems, err = menuitem.FetchMenuItemsWithoutAllergens(excluded)
+		} else {
+			menuItems, err = menuitem.FetchAllMenuItems()
+		}
 		if err != nil {
 			logging.Error("Failed to fetch all menu items", err)
 			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch all menu items")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(menuItems)
+		writeResponse(w, r, http.StatusOK, menuItems, exportTable{name: "menu", rows: menuItems})
 	} else {
 		menuItem, err := menuitem.FindMenuItemByID(itemId)
 		if err != nil {
@@ -78,9 +89,29 @@ func handleGetMenu(w http.ResponseWriter, item string, itemId string) {
 			writeJSONError(w, http.StatusNotFound, "Menu item not found")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(menuItem)
+		writeResponse(w, r, http.StatusOK, menuItem)
+	}
+}
+
+// handleGetMenuCost returns the recipe cost and margin of a menu item.
+func handleGetMenuCost(w http.ResponseWriter, itemId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling GET cost request", "itemId", itemId)
+
+	itemCost, err := menuitem.GetMenuItemCost(itemId)
+	if err != nil {
+		if err.Error() == "menu item not found" {
+			writeJSONError(w, http.StatusNotFound, "Menu item not found")
+		} else {
+			logging.Error("Failed to calculate menu item cost", err, "itemId", itemId)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to calculate menu item cost")
+		}
+		return
 	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(itemCost)
 }
 
 func handlePostMenu(w http.ResponseWriter, r *http.Request) {
@@ -168,6 +199,33 @@ func handleDeleteMenu(w http.ResponseWriter, itemId string) {
 	logging.Info("Successfully deleted menu item", "itemId", itemId)
 }
 
+// queryList collects a query parameter that may be repeated or comma separated.
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// queryInt reads a non-negative integer query parameter, returning def when
+// it is missing.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s value", name)
+	}
+	return parsed, nil
+}
+
 // writeJSONError writes a structured JSON error response.
 func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -8,6 +8,7 @@ import (
 	"hot-coffee/logging"
 	"hot-coffee/models"
 	"hot-coffee/utils"
+	"io"
 	"net/http"
 	"strings"
 )
@@ -25,17 +26,29 @@ func OrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	if orderService == nil {
 		orderRepo := &dal.OrderService{}
-		orderService = service.NewOrderService(orderRepo)
+		menuitemRepo := &dal.MenuItemService{}
+		inventoryRepo := &dal.InventoryItemService{}
+		pricingRepo := &dal.PricingRuleService{}
+		movementRepo := &dal.MovementService{}
+		aggRepo := &dal.AggregationService{}
+		dayCloseRepo := &dal.DayCloseService{}
+		orderService = service.NewOrderService(orderRepo, menuitemRepo, inventoryRepo, pricingRepo, movementRepo, aggRepo, dayCloseRepo)
 	}
 
 	item, itemId, _ := splitPath(r.URL.Path)
 
 	switch r.Method {
 	case http.MethodGet:
-		handleGetOrder(w, item, itemId)
+		handleGetOrder(w, r, item, itemId)
 	case http.MethodPost:
 		if strings.HasSuffix(r.URL.Path, "/close") {
 			CloseOrderHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/cancel") {
+			order, err := orderService.CancelOrder(itemId)
+			writeOrderActionResult(w, "cancel", order, err)
+		} else if strings.HasSuffix(r.URL.Path, "/void") {
+			order, err := orderService.VoidOrder(itemId)
+			writeOrderActionResult(w, "void", order, err)
 		} else {
 			handlePostOrder(w, r)
 		}
@@ -49,7 +62,7 @@ func OrderHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleGetOrder(w http.ResponseWriter, item string, itemId string) {
+func handleGetOrder(w http.ResponseWriter, r *http.Request, item string, itemId string) {
 	defer utils.CatchCriticalPoint()
 
 	logging.Info("Handling GET request", "item", item, "itemId", itemId)
@@ -61,8 +74,7 @@ func handleGetOrder(w http.ResponseWriter, item string, itemId string) {
 			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch all orders")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(orders)
+		writeResponse(w, r, http.StatusOK, orders, exportTable{name: "orders", rows: orders})
 	} else {
 		order, err := orderService.FindOrderByID(itemId)
 		if err != nil {
@@ -70,8 +82,7 @@ func handleGetOrder(w http.ResponseWriter, item string, itemId string) {
 			writeJSONError(w, http.StatusNotFound, "Order not found")
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(order)
+		writeResponse(w, r, http.StatusOK, order)
 	}
 }
 
@@ -89,9 +100,18 @@ func handlePostOrder(w http.ResponseWriter, r *http.Request) {
 
 	logging.Info("Parsed order", "order", newOrder)
 
-	if err := orderService.CreateOrder(newOrder); err != nil {
+	newOrder, err := orderService.CreateOrder(newOrder)
+	if err != nil {
 		logging.Error("Failed to create order", err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to create order")
+		if err.Error() == "business day is closed" {
+			writeJSONError(w, http.StatusConflict, "Business day is closed")
+		} else if strings.HasPrefix(err.Error(), "invalid payment method") {
+			writeJSONError(w, http.StatusBadRequest, "Invalid payment method: "+strings.TrimPrefix(err.Error(), "invalid payment method: "))
+		} else if strings.HasPrefix(err.Error(), "invalid order item") {
+			writeJSONError(w, http.StatusBadRequest, "Invalid order item: "+strings.TrimPrefix(err.Error(), "invalid order item: "))
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to create order")
+		}
 		return
 	}
 
@@ -116,7 +136,17 @@ func handlePutOrder(w http.ResponseWriter, r *http.Request, itemId string) {
 
 	if err := orderService.UpdateOrderByID(itemId, updatedOrder); err != nil {
 		logging.Error("Failed to update order", err, "itemId", itemId)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to update order")
+		if err.Error() == "business day is closed" {
+			writeJSONError(w, http.StatusConflict, "Business day is closed")
+		} else if err.Error() == "order status cannot be changed" {
+			writeJSONError(w, http.StatusConflict, "Order status cannot be changed, use the close, cancel or void actions")
+		} else if strings.HasPrefix(err.Error(), "invalid payment method") {
+			writeJSONError(w, http.StatusBadRequest, "Invalid payment method: "+strings.TrimPrefix(err.Error(), "invalid payment method: "))
+		} else if strings.HasPrefix(err.Error(), "invalid order item") {
+			writeJSONError(w, http.StatusBadRequest, "Invalid order item: "+strings.TrimPrefix(err.Error(), "invalid order item: "))
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to update order")
+		}
 		return
 	}
 
@@ -132,7 +162,11 @@ func handleDeleteOrder(w http.ResponseWriter, itemId string) {
 
 	if err := orderService.DeleteOrderByID(itemId); err != nil {
 		logging.Error("Failed to delete order", err, "itemId", itemId)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to delete order")
+		if err.Error() == "business day is closed" {
+			writeJSONError(w, http.StatusConflict, "Business day is closed")
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to delete order")
+		}
 		return
 	}
 
@@ -155,7 +189,17 @@ func CloseOrderHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	orderID := pathParts[2]
 
-	err := orderService.CloseOrder(orderID)
+	// The payment method is optional; an empty body keeps the order's own
+	var request struct {
+		PaymentMethod string `json:"payment_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	err := orderService.CloseOrder(orderID, request.PaymentMethod)
 	if err != nil {
 		if err.Error() == "order not found" {
 			logging.Error("Order not found", err, "orderID", orderID)
@@ -163,6 +207,13 @@ func CloseOrderHandler(w http.ResponseWriter, r *http.Request) {
 		} else if err.Error() == "order is already closed" {
 			logging.Error("Order is already closed", err, "orderID", orderID)
 			writeJSONError(w, http.StatusBadRequest, "Order is already closed")
+		} else if err.Error() == "order is cancelled" || err.Error() == "order is voided" {
+			logging.Error("Order can no longer be closed", err, "orderID", orderID)
+			writeJSONError(w, http.StatusBadRequest, "Order is "+strings.TrimPrefix(err.Error(), "order is "))
+		} else if strings.HasPrefix(err.Error(), "invalid payment method") {
+			writeJSONError(w, http.StatusBadRequest, "Invalid payment method: "+strings.TrimPrefix(err.Error(), "invalid payment method: "))
+		} else if err.Error() == "business day is closed" {
+			writeJSONError(w, http.StatusConflict, "Business day is closed")
 		} else {
 			logging.Error("Failed to close order", err, "orderID", orderID)
 			writeJSONError(w, http.StatusInternalServerError, "Failed to close order")
@@ -174,3 +225,24 @@ func CloseOrderHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Order closed successfully"})
 	logging.Info("Successfully closed order", "orderID", orderID)
 }
+
+// writeOrderActionResult writes the result of cancelling or voiding an order.
+func writeOrderActionResult(w http.ResponseWriter, action string, order models.Order, err error) {
+	if err != nil {
+		logging.Error("Failed to "+action+" order", err)
+		switch {
+		case err.Error() == "order not found":
+			writeJSONError(w, http.StatusNotFound, "Order not found")
+		case err.Error() == "business day is closed":
+			writeJSONError(w, http.StatusConflict, "Business day is closed")
+		case strings.HasPrefix(err.Error(), "order is "):
+			writeJSONError(w, http.StatusBadRequest, "Order is "+strings.TrimPrefix(err.Error(), "order is "))
+		default:
+			writeJSONError(w, http.StatusInternalServerError, "Failed to "+action+" order")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+)
+
+var pricingService service.PricingService
+
+func PricingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	pricingRepo := &dal.PricingRuleService{}
+	pricingService = service.NewPricingService(pricingRepo)
+	_, ruleId, _ := splitPath(r.URL.Path)
+
+	switch r.Method {
+	case http.MethodGet:
+		handleGetPricingRules(w, ruleId)
+	case http.MethodPost:
+		handlePostPricingRule(w, r)
+	case http.MethodPut:
+		handlePutPricingRule(w, r, ruleId)
+	case http.MethodDelete:
+		handleDeletePricingRule(w, ruleId)
+	default:
+		logging.Warn("Invalid HTTP method", "method", r.Method)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+	}
+}
+
+func handleGetPricingRules(w http.ResponseWriter, ruleId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling GET request", "ruleId", ruleId)
+
+	if ruleId == "" {
+		rules, err := pricingService.FetchAllRules()
+		if err != nil {
+			logging.Error("Failed to fetch all pricing rules", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch all pricing rules")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rules)
+	} else {
+		rule, err := pricingService.FindRuleByID(ruleId)
+		if err != nil {
+			logging.Error("Failed to fetch pricing rule by ID", err, "ruleId", ruleId)
+			writeJSONError(w, http.StatusNotFound, "Pricing rule not found")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+func handlePostPricingRule(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling POST request")
+
+	var newRule models.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&newRule); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	createdRule, err := pricingService.CreateRule(newRule)
+	if err != nil {
+		logging.Error("Failed to create pricing rule", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdRule)
+	logging.Info("Successfully created pricing rule", "rule", createdRule)
+}
+
+func handlePutPricingRule(w http.ResponseWriter, r *http.Request, ruleId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling PUT request", "ruleId", ruleId)
+
+	var updatedRule models.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&updatedRule); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	if err := pricingService.UpdateRuleByID(ruleId, updatedRule); err != nil {
+		if err.Error() == "pricing rule not found" {
+			writeJSONError(w, http.StatusNotFound, "Pricing rule not found")
+		} else {
+			logging.Error("Failed to update pricing rule", err, "ruleId", ruleId)
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	updatedRule.ID = ruleId
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedRule)
+	logging.Info("Successfully updated pricing rule", "ruleId", ruleId)
+}
+
+func handleDeletePricingRule(w http.ResponseWriter, ruleId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling DELETE request", "ruleId", ruleId)
+
+	if err := pricingService.DeleteRuleByID(ruleId); err != nil {
+		if err.Error() == "pricing rule not found" {
+			writeJSONError(w, http.StatusNotFound, "Pricing rule not found")
+		} else {
+			logging.Error("Failed to delete pricing rule", err, "ruleId", ruleId)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to delete pricing rule")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logging.Info("Successfully deleted pricing rule", "ruleId", ruleId)
+}
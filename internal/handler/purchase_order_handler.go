@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+	"strings"
+)
+
+// PurchaseOrderHandler serves purchase orders under /purchase-orders.
+func PurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	procurementService = newProcurementService()
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/purchase-orders"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		handleGetPurchaseOrders(w, r)
+	case r.Method == http.MethodPost && path == "":
+		handlePostPurchaseOrder(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		order, err := procurementService.FindPurchaseOrderByID(parts[0])
+		writePurchaseOrderResult(w, http.StatusOK, order, err)
+	case r.Method == http.MethodPut && len(parts) == 1:
+		handlePutPurchaseOrder(w, r, parts[0])
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		handleDeletePurchaseOrder(w, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "send":
+		order, err := procurementService.SendPurchaseOrder(parts[0])
+		writePurchaseOrderResult(w, http.StatusOK, order, err)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "cancel":
+		order, err := procurementService.CancelPurchaseOrder(parts[0])
+		writePurchaseOrderResult(w, http.StatusOK, order, err)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "receive":
+		handleReceivePurchaseOrder(w, r, parts[0])
+	default:
+		writeJSONError(w, http.StatusNotFound, "Purchase order endpoint not found")
+	}
+}
+
+func handleGetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	orders, err := procurementService.FetchPurchaseOrders(r.URL.Query().Get("status"))
+	if err != nil {
+		logging.Error("Failed to fetch purchase orders", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch purchase orders")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+func handlePostPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling POST purchase order request")
+
+	var order models.PurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	created, err := procurementService.CreatePurchaseOrder(order)
+	writePurchaseOrderResult(w, http.StatusCreated, created, err)
+}
+
+func handlePutPurchaseOrder(w http.ResponseWriter, r *http.Request, poId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling PUT purchase order request", "poId", poId)
+
+	var order models.PurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	updated, err := procurementService.UpdatePurchaseOrder(poId, order)
+	writePurchaseOrderResult(w, http.StatusOK, updated, err)
+}
+
+func handleDeletePurchaseOrder(w http.ResponseWriter, poId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling DELETE purchase order request", "poId", poId)
+
+	if err := procurementService.DeletePurchaseOrder(poId); err != nil {
+		writePurchaseOrderResult(w, 0, models.PurchaseOrder{}, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleReceivePurchaseOrder(w http.ResponseWriter, r *http.Request, poId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling receive purchase order request", "poId", poId)
+
+	var delivery models.PurchaseOrderReceipt
+	if err := json.NewDecoder(r.Body).Decode(&delivery); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	order, err := procurementService.ReceivePurchaseOrder(poId, delivery)
+	writePurchaseOrderResult(w, http.StatusOK, order, err)
+}
+
+// writePurchaseOrderResult writes a purchase order with the given status, or
+// the error that prevented the change.
+func writePurchaseOrderResult(w http.ResponseWriter, status int, order models.PurchaseOrder, err error) {
+	if err != nil {
+		switch {
+		case err.Error() == "purchase order not found":
+			writeJSONError(w, http.StatusNotFound, "Purchase order not found")
+		case strings.HasPrefix(err.Error(), "only a ") || strings.HasPrefix(err.Error(), "purchase order is "):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			logging.Error("Failed to change purchase order", err)
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(order)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+	"strings"
+)
+
+// ReceiptHandler serves goods receipts under /inventory/receipts.
+func ReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	Inventory = service.NewInventoryService(&dal.InventoryItemService{}, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	receiptId := strings.Trim(strings.TrimPrefix(r.URL.Path, "/inventory/receipts"), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		handleGetReceipts(w, r, receiptId)
+	case http.MethodPost:
+		if receiptId != "" {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+			return
+		}
+		var receipt models.GoodsReceipt
+		if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+			logging.Error("Failed to decode request body", err)
+			writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+			return
+		}
+		handlePostReceipt(w, receipt)
+	default:
+		logging.Warn("Invalid HTTP method", "method", r.Method)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+	}
+}
+
+// handleReceiveInventory handles POST /inventory/{id}/receive, a goods
+// receipt with a single line.
+func handleReceiveInventory(w http.ResponseWriter, r *http.Request, itemId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling receive request", "itemId", itemId)
+
+	var body struct {
+		Supplier   string  `json:"supplier"`
+		InvoiceRef string  `json:"invoice_ref"`
+		ReceivedBy string  `json:"received_by"`
+		Quantity   float64 `json:"quantity"`
+		Unit       string  `json:"unit"`
+		UnitCost   float64 `json:"unit_cost"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	if _, err := Inventory.GetInventoryItemByID(itemId); err != nil {
+		writeJSONError(w, http.StatusNotFound, "Inventory item not found")
+		return
+	}
+
+	handlePostReceipt(w, models.GoodsReceipt{
+		Supplier:   body.Supplier,
+		InvoiceRef: body.InvoiceRef,
+		ReceivedBy: body.ReceivedBy,
+		Lines: []models.GoodsReceiptLine{{
+			IngredientID: itemId,
+			Quantity:     body.Quantity,
+			Unit:         body.Unit,
+			UnitCost:     body.UnitCost,
+		}},
+	})
+}
+
+func handlePostReceipt(w http.ResponseWriter, receipt models.GoodsReceipt) {
+	defer utils.CatchCriticalPoint()
+
+	stored, err := Inventory.ReceiveStock(receipt)
+	if err != nil {
+		logging.Error("Failed to receive stock", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stored)
+	logging.Info("Successfully received stock", "receiptID", stored.ID)
+}
+
+func handleGetReceipts(w http.ResponseWriter, r *http.Request, receiptId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling GET receipts request", "receiptId", receiptId)
+
+	if receiptId != "" {
+		receipt, err := Inventory.GetReceiptByID(receiptId)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "Goods receipt not found")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(receipt)
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	receipts, err := Inventory.GetReceipts(from, to)
+	if err != nil {
+		logging.Error("Failed to fetch goods receipts", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch goods receipts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipts)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var searchService service.SearchService
+
+// InitSearchIndex fills the search index on startup.
+func InitSearchIndex() error {
+	searchService = service.NewSearchService(&dal.MenuItemService{}, &dal.InventoryItemService{}, &dal.OrderService{})
+	return searchService.BuildIndex()
+}
+
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+		return
+	}
+	if searchService == nil {
+		searchService = service.NewSearchService(&dal.MenuItemService{}, &dal.InventoryItemService{}, &dal.OrderService{})
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSONError(w, http.StatusBadRequest, "Query parameter 'q' is required")
+		return
+	}
+
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeJSONError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	results := searchService.Search(query, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   query,
+		"results": results,
+	})
+}
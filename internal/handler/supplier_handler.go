@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+	"strings"
+)
+
+var procurementService service.ProcurementService
+
+// SupplierHandler serves the suppliers and their catalogues under /suppliers.
+func SupplierHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	procurementService = newProcurementService()
+	_, supplierId, _ := splitPath(r.URL.Path)
+
+	switch r.Method {
+	case http.MethodGet:
+		handleGetSuppliers(w, supplierId)
+	case http.MethodPost:
+		handlePostSupplier(w, r)
+	case http.MethodPut:
+		handlePutSupplier(w, r, supplierId)
+	case http.MethodDelete:
+		handleDeleteSupplier(w, supplierId)
+	default:
+		logging.Warn("Invalid HTTP method", "method", r.Method)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+	}
+}
+
+func handleGetSuppliers(w http.ResponseWriter, supplierId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling GET request", "supplierId", supplierId)
+
+	if supplierId == "" {
+		suppliers, err := procurementService.FetchAllSuppliers()
+		if err != nil {
+			logging.Error("Failed to fetch all suppliers", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to fetch all suppliers")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(suppliers)
+	} else {
+		supplier, err := procurementService.FindSupplierByID(supplierId)
+		if err != nil {
+			logging.Error("Failed to fetch supplier by ID", err, "supplierId", supplierId)
+			writeJSONError(w, http.StatusNotFound, "Supplier not found")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(supplier)
+	}
+}
+
+func handlePostSupplier(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling POST request")
+
+	var newSupplier models.Supplier
+	if err := json.NewDecoder(r.Body).Decode(&newSupplier); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	createdSupplier, err := procurementService.CreateSupplier(newSupplier)
+	if err != nil {
+		logging.Error("Failed to create supplier", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdSupplier)
+	logging.Info("Successfully created supplier", "supplier", createdSupplier)
+}
+
+func handlePutSupplier(w http.ResponseWriter, r *http.Request, supplierId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling PUT request", "supplierId", supplierId)
+
+	var updatedSupplier models.Supplier
+	if err := json.NewDecoder(r.Body).Decode(&updatedSupplier); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	if err := procurementService.UpdateSupplierByID(supplierId, updatedSupplier); err != nil {
+		if err.Error() == "supplier not found" {
+			writeJSONError(w, http.StatusNotFound, "Supplier not found")
+		} else {
+			logging.Error("Failed to update supplier", err, "supplierId", supplierId)
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	updatedSupplier.ID = supplierId
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedSupplier)
+	logging.Info("Successfully updated supplier", "supplierId", supplierId)
+}
+
+func handleDeleteSupplier(w http.ResponseWriter, supplierId string) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling DELETE request", "supplierId", supplierId)
+
+	if err := procurementService.DeleteSupplierByID(supplierId); err != nil {
+		if err.Error() == "supplier not found" {
+			writeJSONError(w, http.StatusNotFound, "Supplier not found")
+		} else if strings.HasPrefix(err.Error(), "supplier has purchase orders in progress") {
+			writeJSONError(w, http.StatusConflict, err.Error())
+		} else {
+			logging.Error("Failed to delete supplier", err, "supplierId", supplierId)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to delete supplier")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logging.Info("Successfully deleted supplier", "supplierId", supplierId)
+}
+
+// newProcurementService wires the procurement service; receiving purchase
+// orders goes through the inventory service.
+func newProcurementService() service.ProcurementService {
+	inventoryRepo := &dal.InventoryItemService{}
+	inventory := service.NewInventoryService(inventoryRepo, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	return service.NewProcurementService(&dal.SupplierService{}, &dal.PurchaseOrderService{}, inventoryRepo, inventory)
+}
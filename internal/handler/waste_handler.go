@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"net/http"
+)
+
+var wasteService service.WasteService
+
+// WasteHandler serves /inventory/waste: POST records waste, GET lists it.
+func WasteHandler(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Received request", "method", r.Method, "url", r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	wasteService = service.NewWasteService(&dal.WasteService{}, &dal.InventoryItemService{}, &dal.MenuItemService{}, &dal.MovementService{})
+
+	switch r.Method {
+	case http.MethodGet:
+		handleGetWaste(w, r)
+	case http.MethodPost:
+		handlePostWaste(w, r)
+	default:
+		logging.Warn("Invalid HTTP method", "method", r.Method)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Invalid HTTP method")
+	}
+}
+
+func handlePostWaste(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Handling POST waste request")
+
+	var entry models.WasteEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		logging.Error("Failed to decode request body", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to decode request body")
+		return
+	}
+
+	recorded, err := wasteService.RecordWaste(entry)
+	if err != nil {
+		logging.Error("Failed to record waste", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(recorded)
+	logging.Info("Successfully recorded waste", "wasteID", recorded.ID)
+}
+
+func handleGetWaste(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := wasteService.GetWaste(from, to)
+	if err != nil {
+		logging.Error("Failed to fetch waste entries", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch waste entries")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// handleWasteReport handles GET /reports/waste?from=&to=.
+func handleWasteReport(w http.ResponseWriter, r *http.Request) {
+	defer utils.CatchCriticalPoint()
+
+	from, to, err := utils.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wasteService = service.NewWasteService(&dal.WasteService{}, &dal.InventoryItemService{}, &dal.MenuItemService{}, &dal.MovementService{})
+	report, err := wasteService.GetWasteReport(from, to)
+	if err != nil {
+		logging.Error("Failed to fetch waste report", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch waste report")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, report,
+		exportTable{name: "by_ingredient", rows: report.ByIngredient},
+		exportTable{name: "by_reason", rows: report.ByReason})
+}
@@ -0,0 +1,304 @@
+// Package search keeps a small in-process inverted index over menu items,
+// inventory ingredients and orders. The data access layer pushes every write
+// into the index, so searches never have to read the JSON files.
+package search
+
+import (
+	"hot-coffee/models"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	TypeMenu      = "menu"
+	TypeInventory = "inventory"
+	TypeOrders    = "orders"
+)
+
+// Match weights: a term that equals the query word counts the most, then a
+// term that starts with it, then a term within a small edit distance.
+const (
+	exactWeight  = 3.0
+	prefixWeight = 2.0
+	fuzzyWeight  = 1.0
+)
+
+// Document is one searchable entity. Title terms weigh twice as much as
+// terms from the rest of the text.
+type Document struct {
+	Type  string
+	ID    string
+	Title string
+	Text  string
+}
+
+type Result struct {
+	Type  string  `json:"type"`
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]Document
+	postings map[string]map[string]float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]Document),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+var defaultIndex = NewIndex()
+
+// IndexMenuItems syncs the menu documents with the saved menu.
+func IndexMenuItems(items []models.MenuItem) {
+	docs := make([]Document, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, Document{
+			Type:  TypeMenu,
+			ID:    item.ID,
+			Title: item.Name,
+			Text:  strings.Join([]string{item.ID, item.Description, item.Category}, " "),
+		})
+	}
+	defaultIndex.Replace(TypeMenu, docs)
+}
+
+// IndexInventoryItems syncs the ingredient documents with the saved inventory.
+func IndexInventoryItems(items []models.InventoryItem) {
+	docs := make([]Document, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, Document{
+			Type:  TypeInventory,
+			ID:    item.IngredientID,
+			Title: item.Name,
+			Text:  item.IngredientID,
+		})
+	}
+	defaultIndex.Replace(TypeInventory, docs)
+}
+
+// IndexOrders syncs the order documents with the saved orders.
+func IndexOrders(orders []models.Order) {
+	docs := make([]Document, 0, len(orders))
+	for _, order := range orders {
+		docs = append(docs, Document{
+			Type:  TypeOrders,
+			ID:    order.ID,
+			Title: order.CustomerName,
+			Text:  order.ID,
+		})
+	}
+	defaultIndex.Replace(TypeOrders, docs)
+}
+
+// Search queries the shared index.
+func Search(query string, limit int) map[string][]Result {
+	return defaultIndex.Search(query, limit)
+}
+
+// Replace makes the documents of a type match the given set. Only documents
+// that were added, changed or removed touch the postings.
+func (idx *Index) Replace(docType string, docs []Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	current := make(map[string]bool)
+	for _, doc := range docs {
+		key := docKey(doc.Type, doc.ID)
+		current[key] = true
+
+		if existing, ok := idx.docs[key]; ok {
+			if existing == doc {
+				continue
+			}
+			idx.remove(key)
+		}
+		idx.add(key, doc)
+	}
+
+	for key, doc := range idx.docs {
+		if doc.Type == docType && !current[key] {
+			idx.remove(key)
+		}
+	}
+}
+
+// Search returns the documents matching every word of the query, grouped by
+// type and ranked by score. Limit caps each group; zero means no limit.
+func (idx *Index) Search(query string, limit int) map[string][]Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results := make(map[string][]Result)
+	words := tokenize(query)
+	if len(words) == 0 {
+		return results
+	}
+
+	var scores map[string]float64
+	for _, word := range words {
+		wordScores := idx.scoreWord(word)
+
+		// Documents must match every word of the query
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for key := range scores {
+			if wordScore, ok := wordScores[key]; ok {
+				scores[key] += wordScore
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	for key, score := range scores {
+		doc := idx.docs[key]
+		results[doc.Type] = append(results[doc.Type], Result{Type: doc.Type, ID: doc.ID, Title: doc.Title, Score: score})
+	}
+
+	for docType, group := range results {
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Score != group[j].Score {
+				return group[i].Score > group[j].Score
+			}
+			return group[i].ID < group[j].ID
+		})
+		if limit > 0 && len(group) > limit {
+			group = group[:limit]
+		}
+		results[docType] = group
+	}
+	return results
+}
+
+// scoreWord scores the documents containing a term that matches the word.
+// Each document keeps the best way the word matched it.
+func (idx *Index) scoreWord(word string) map[string]float64 {
+	scores := make(map[string]float64)
+	for term, docs := range idx.postings {
+		weight := matchWeight(word, term)
+		if weight == 0 {
+			continue
+		}
+		for key, frequency := range docs {
+			if score := weight * frequency; score > scores[key] {
+				scores[key] = score
+			}
+		}
+	}
+	return scores
+}
+
+func (idx *Index) add(key string, doc Document) {
+	idx.docs[key] = doc
+	for term, frequency := range termFrequencies(doc) {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]float64)
+		}
+		idx.postings[term][key] = frequency
+	}
+}
+
+func (idx *Index) remove(key string) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for term := range termFrequencies(doc) {
+		delete(idx.postings[term], key)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, key)
+}
+
+func docKey(docType, id string) string {
+	return docType + "/" + id
+}
+
+func termFrequencies(doc Document) map[string]float64 {
+	frequencies := make(map[string]float64)
+	for _, term := range tokenize(doc.Title) {
+		frequencies[term] += 2
+	}
+	for _, term := range tokenize(doc.Text) {
+		frequencies[term]++
+	}
+	return frequencies
+}
+
+// tokenize lower-cases text and splits it into words on anything that is not
+// a letter or digit.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func matchWeight(word, term string) float64 {
+	switch {
+	case term == word:
+		return exactWeight
+	case strings.HasPrefix(term, word):
+		return prefixWeight
+	case withinEditDistance(word, term, maxEdits(word)):
+		return fuzzyWeight
+	}
+	return 0
+}
+
+// maxEdits allows one typo in medium words and two in long ones. Short words
+// must match exactly or by prefix.
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// withinEditDistance reports whether the edit distance between a and b is
+// at most max. Swapping two adjacent letters counts as a single edit.
+func withinEditDistance(a, b string, max int) bool {
+	if max == 0 {
+		return false
+	}
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return false
+	}
+
+	beforePrevious := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+		}
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+	return previous[len(rb)] <= max
+}
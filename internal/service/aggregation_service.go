@@ -2,148 +2,1123 @@ package service
 
 import (
 	"errors"
+	"hot-coffee/config"
 	"hot-coffee/internal/dal"
 	"hot-coffee/logging"
 	"hot-coffee/models"
 	"hot-coffee/utils"
+	"math"
+	"sort"
+	"strings"
+	"time"
 )
 
 type ReportService interface {
-	GetMostPopularItem() (models.MenuItem, error)
-	GetDailyItem() (models.MenuItem, error)
+	GetPopularItems(from, to time.Time, limit int, by string, compare string) (models.PopularItemsReport, error)
+	GetSalesReport(from, to time.Time, interval string, compare string, loc *time.Location) (models.SalesReport, error)
+	GetDailyItem() (models.DailyItem, error)
+	SetDailyItem(request models.DailyItemRequest) (models.DailyItem, error)
 	TotalSalesAmount() (float64, error)
+	GetMarginReport(threshold float64) (models.MarginReport, error)
+	GetMenuEngineering(from, to time.Time, popularityFactor float64) (models.MenuEngineeringReport, error)
+	GetCustomerReport(from, to time.Time, limit int) (models.CustomerReport, error)
+	GetAggregates() (models.AggregationData, error)
+	RebuildAggregates() (models.AggregationData, error)
 }
 
 type reportService struct {
 	menuRepo        dal.MenuRepository
 	aggregationRepo dal.AggregationRepository
 	orderRepo       dal.OrderRepository
+	inventoryRepo   dal.InventoryRepository
 }
 
-func NewReportService(menuRepo dal.MenuRepository, aggregationRepo dal.AggregationRepository, orderRepo dal.OrderRepository) ReportService {
+func NewReportService(menuRepo dal.MenuRepository, aggregationRepo dal.AggregationRepository, orderRepo dal.OrderRepository, inventoryRepo dal.InventoryRepository) ReportService {
 	return &reportService{
 		menuRepo:        menuRepo,
 		aggregationRepo: aggregationRepo,
 		orderRepo:       orderRepo,
+		inventoryRepo:   inventoryRepo,
 	}
 }
 
-// TotalSalesAmount calculates the total sales amount from all closed orders.
+// TotalSalesAmount returns the total sales amount of all closed orders.
 func (s *reportService) TotalSalesAmount() (float64, error) {
 	defer utils.CatchCriticalPoint()
 
-	orders, err := s.orderRepo.ReadClosedOrders()
+	data, err := s.aggregates()
 	if err != nil {
-		logging.Error("Failed to read closed orders", err)
 		return 0, err
 	}
 
-	// Get all menu items for price lookup
+	logging.Info("Total sales amount calculated", "totalSalesAmount", data.TotalSales)
+	return data.TotalSales, nil
+}
+
+// GetPopularItems ranks the products of the closed orders created within
+// the date range by quantity sold, revenue or number of orders, and breaks
+// the sales down by menu category. A limit of zero lists every product.
+// With a comparison the range needs a start; an open end is now.
+func (s *reportService) GetPopularItems(from, to time.Time, limit int, by string, compare string) (models.PopularItemsReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	if by == "" {
+		by = "quantity"
+	}
+	if by != "quantity" && by != "revenue" && by != "orders" {
+		return models.PopularItemsReport{}, errors.New("invalid ranking: " + by)
+	}
+	if err := validateComparison(compare); err != nil {
+		return models.PopularItemsReport{}, err
+	}
+	if compare != "" {
+		if from.IsZero() {
+			return models.PopularItemsReport{}, errors.New("comparison needs a from date")
+		}
+		if to.IsZero() {
+			to = time.Now()
+		}
+	}
+
+	report, err := s.popularItems(from, to, by)
+	if err != nil {
+		return models.PopularItemsReport{}, err
+	}
+
+	if compare != "" {
+		loc, err := utils.BusinessLocation()
+		if err != nil {
+			logging.Error("Failed to load business time zone", err)
+			return models.PopularItemsReport{}, err
+		}
+		previousFrom, previousTo := comparisonPeriod(from, to, compare, loc)
+		previous, err := s.popularItems(previousFrom, previousTo, by)
+		if err != nil {
+			return models.PopularItemsReport{}, err
+		}
+		report.Comparison = comparePopularItems(report, previous, compare, limit)
+	}
+	if limit > 0 && len(report.Items) > limit {
+		report.Items = report.Items[:limit]
+	}
+
+	logging.Info("Popular items calculated", "items", len(report.Items), "by", by, "compare", compare)
+	return report, nil
+}
+
+// popularItems ranks every product sold within the date range.
+func (s *reportService) popularItems(from, to time.Time, by string) (models.PopularItemsReport, error) {
+	// Fetch all menu items to resolve bundles into their components
 	menuItems, err := s.menuRepo.ReadItems()
 	if err != nil {
 		logging.Error("Failed to fetch menu items", err)
-		return 0, err
+		return models.PopularItemsReport{}, err
 	}
+	menuItemMap := mapMenuItems(menuItems)
 
-	// Create a map to quickly look up menu items by their ID
-	menuItemMap := make(map[string]models.MenuItem)
-	for _, item := range menuItems {
-		menuItemMap[item.ID] = item
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.PopularItemsReport{}, err
 	}
 
-	// Calculate the total sales amount
-	var totalSalesAmount float64
+	// Whole business days are served from the aggregates, anything else
+	// from the orders themselves
+	var report models.PopularItemsReport
+	if alignedToDays(from, loc) && alignedToDays(to, loc) {
+		data, err := s.aggregates()
+		if err != nil {
+			return models.PopularItemsReport{}, err
+		}
+		report = aggregatedPopularItems(data, menuItemMap, from, to, by, loc)
+	} else {
+		orders, err := s.orderRepo.ReadClosedOrders()
+		if err != nil {
+			logging.Error("Failed to read closed orders", err)
+			return models.PopularItemsReport{}, err
+		}
+		report = popularItems(orders, menuItemMap, from, to, by)
+	}
+	return report, nil
+}
+
+// maxSalesBuckets caps the size of a sales report.
+const maxSalesBuckets = 5000
+
+// GetSalesReport totals the closed orders created within the date range per
+// hour, day, week (starting Monday) or month in the given time zone. A
+// missing from starts at the first order, a missing to ends now. With a
+// comparison the report is set against the same buckets of another period.
+func (s *reportService) GetSalesReport(from, to time.Time, interval string, compare string, loc *time.Location) (models.SalesReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	if err := validateComparison(compare); err != nil {
+		return models.SalesReport{}, err
+	}
+
+	report, err := s.salesReport(from, to, interval, loc)
+	if err != nil || compare == "" {
+		return report, err
+	}
+
+	// Compare over the range the report ended up covering
+	from, _ = time.Parse(time.RFC3339, report.From)
+	to, _ = time.Parse(time.RFC3339, report.To)
+	from, to = comparisonPeriod(from.In(loc), to.In(loc), compare, loc)
+	previous, err := s.salesReport(from, to, interval, loc)
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+	compareSales(&report, previous, compare)
+
+	logging.Info("Sales report compared", "compare", compare, "previousOrders", previous.Totals.Orders)
+	return report, nil
+}
+
+func (s *reportService) salesReport(from, to time.Time, interval string, loc *time.Location) (models.SalesReport, error) {
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "hour" && interval != "day" && interval != "week" && interval != "month" {
+		return models.SalesReport{}, errors.New("invalid interval: " + interval)
+	}
+
+	businessLoc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.SalesReport{}, err
+	}
+
+	// Aggregated hours can be regrouped in any time zone whose hours line
+	// up with the business ones. Other ranges are served from the orders.
+	useAggregates := wholeHourZone(loc) && wholeHourZone(businessLoc) &&
+		alignedToHours(from, loc) && alignedToHours(to, loc)
+
+	var data models.AggregationData
+	var orders []models.Order
+	var menuItemMap map[string]models.MenuItem
+	if useAggregates {
+		if data, err = s.aggregates(); err != nil {
+			return models.SalesReport{}, err
+		}
+	} else {
+		if orders, err = s.orderRepo.ReadClosedOrders(); err != nil {
+			logging.Error("Failed to read closed orders", err)
+			return models.SalesReport{}, err
+		}
+		menuItems, err := s.menuRepo.ReadItems()
+		if err != nil {
+			logging.Error("Failed to fetch menu items", err)
+			return models.SalesReport{}, err
+		}
+		menuItemMap = mapMenuItems(menuItems)
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to
+		if firstSale, err := time.Parse(time.RFC3339, data.FirstSale); err == nil && firstSale.Before(from) {
+			from = firstSale
+		}
+		for _, order := range orders {
+			if createdAt, err := time.Parse(time.RFC3339, order.CreatedAt); err == nil && createdAt.Before(from) {
+				from = createdAt
+			}
+		}
+	}
+
+	// Buckets are aligned to the interval in the report's time zone
+	var starts []time.Time
+	for start := bucketStart(from, interval, loc); start.Before(to); start = nextBucket(start, interval) {
+		if len(starts) == maxSalesBuckets {
+			return models.SalesReport{}, errors.New("too many buckets, use a longer interval or a shorter range")
+		}
+		starts = append(starts, start)
+	}
+
+	report := models.SalesReport{
+		From:     from.In(loc).Format(time.RFC3339),
+		To:       to.In(loc).Format(time.RFC3339),
+		Interval: interval,
+		TimeZone: loc.String(),
+		Buckets:  []models.SalesBucket{},
+	}
+	bucketIndex := make(map[int64]int)
+	for i, start := range starts {
+		bucketIndex[start.Unix()] = i
+		report.Buckets = append(report.Buckets, models.SalesBucket{
+			Start: start.Format(time.RFC3339),
+			End:   nextBucket(start, interval).Format(time.RFC3339),
+		})
+	}
+
+	addSales := func(at time.Time, revenue float64, orders, itemsSold int) {
+		i, found := bucketIndex[bucketStart(at, interval, loc).Unix()]
+		if !found {
+			return
+		}
+		bucket := &report.Buckets[i].SalesTotals
+		bucket.Revenue += revenue
+		bucket.Orders += orders
+		bucket.ItemsSold += itemsSold
+	}
+
+	if useAggregates {
+		for day, daySales := range data.Days {
+			for hour, hourSales := range daySales.Hours {
+				// Bounds given by the caller are whole hours; the defaulted
+				// first sale and now may cut an hour short
+				start, err := time.ParseInLocation("2006-01-02 15", day+" "+hour, businessLoc)
+				if err != nil || !start.Add(time.Hour).After(from) || !start.Before(to) {
+					continue
+				}
+				addSales(start, hourSales.Revenue, hourSales.Orders, hourSales.ItemsSold)
+			}
+		}
+	}
 	for _, order := range orders {
-		for _, orderItem := range order.Items {
-			menuItem, exists := menuItemMap[orderItem.ProductID]
-			if !exists {
-				logging.Warn("Menu item not found for order item", "menuItemID", orderItem.ProductID)
-				continue
+		if !utils.InDateRange(order.CreatedAt, from, to) {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339, order.CreatedAt)
+
+		var itemsSold int
+		for _, item := range order.Items {
+			itemsSold += item.Quantity
+		}
+		addSales(createdAt, orderRevenue(order, menuItemMap), 1, itemsSold)
+	}
+
+	for i := range report.Buckets {
+		bucket := &report.Buckets[i].SalesTotals
+		report.Totals.Revenue += bucket.Revenue
+		report.Totals.Orders += bucket.Orders
+		report.Totals.ItemsSold += bucket.ItemsSold
+		finishSalesTotals(bucket)
+	}
+	finishSalesTotals(&report.Totals)
+
+	logging.Info("Sales report calculated", "interval", interval, "buckets", len(report.Buckets), "orders", report.Totals.Orders, "aggregated", useAggregates)
+	return report, nil
+}
+
+// GetDailyItem returns the item of the current business day. It is chosen
+// by the configured strategy the first time it is asked for, and chosen
+// again if it sells out or leaves the menu.
+func (s *reportService) GetDailyItem() (models.DailyItem, error) {
+	defer utils.CatchCriticalPoint()
+
+	return s.chooseDailyItem(func(current models.DailyItem, today string, available map[string]models.MenuItem) (string, string, error) {
+		if current.Date == today {
+			if _, found := available[current.ID]; found {
+				return current.ID, current.Strategy, nil
+			}
+			logging.Warn("Daily item no longer available, choosing again", "itemID", current.ID)
+		}
+		return "", config.DailyItemStrategy, nil
+	})
+}
+
+// SetDailyItem overrides the item of the current business day with a given
+// product, or chooses it again by a strategy.
+func (s *reportService) SetDailyItem(request models.DailyItemRequest) (models.DailyItem, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to set daily item", "productID", request.ProductID, "strategy", request.Strategy)
+
+	switch {
+	case request.ProductID != "" && request.Strategy != "":
+		return models.DailyItem{}, errors.New("give either a product ID or a strategy")
+	case request.ProductID == "" && request.Strategy == "":
+		return models.DailyItem{}, errors.New("product ID or strategy is required")
+	case request.Strategy != "":
+		known := false
+		for _, strategy := range models.DailyItemStrategies {
+			known = known || strategy == request.Strategy
+		}
+		if !known {
+			return models.DailyItem{}, errors.New("invalid daily item strategy: " + request.Strategy)
+		}
+	}
+
+	dailyItem, err := s.chooseDailyItem(func(current models.DailyItem, today string, available map[string]models.MenuItem) (string, string, error) {
+		if request.Strategy != "" {
+			return "", request.Strategy, nil
+		}
+		if _, found := available[request.ProductID]; !found {
+			return "", "", errors.New("menu item not found or sold out")
+		}
+		return request.ProductID, models.DailyItemManual, nil
+	})
+	if err != nil {
+		logging.Warn("Daily item not set", "productID", request.ProductID, "error", err)
+		return models.DailyItem{}, err
+	}
+
+	logging.Info("Successfully set daily item", "itemID", dailyItem.ID, "strategy", dailyItem.Strategy)
+	return dailyItem, nil
+}
+
+// chooseDailyItem settles the daily item under the aggregation lock. Decide
+// gets the stored item, today's date and the menu items that are not sold
+// out, and returns the product to feature, or an empty ID to choose one by
+// the returned strategy. A changed choice is saved.
+func (s *reportService) chooseDailyItem(decide func(current models.DailyItem, today string, available map[string]models.MenuItem) (string, string, error)) (models.DailyItem, error) {
+	// The slow-moving strategy reads the sales aggregates, so make sure they exist
+	if _, err := s.aggregates(); err != nil {
+		return models.DailyItem{}, err
+	}
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.DailyItem{}, err
+	}
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return models.DailyItem{}, err
+	}
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.DailyItem{}, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.In(loc).Year(), now.In(loc).Month(), now.In(loc).Day(), 0, 0, 0, 0, loc)
+	menuItemMap := mapMenuItems(menuItems)
+	inventoryMap := mapInventoryItems(inventoryItems)
+
+	available := make(map[string]models.MenuItem)
+	for _, menuItem := range menuItems {
+		if !soldOut(menuItem, menuItemMap, inventoryMap, now) {
+			available[menuItem.ID] = menuItem
+		}
+	}
+
+	var dailyItem models.DailyItem
+	err = s.aggregationRepo.UpdateAggregationData(func(data *models.AggregationData) error {
+		productID, strategy, err := decide(data.DailyItem, today.Format("2006-01-02"), available)
+		if err != nil {
+			return err
+		}
+		if productID != "" && productID == data.DailyItem.ID && strategy == data.DailyItem.Strategy && data.DailyItem.Date == today.Format("2006-01-02") {
+			// Unchanged; the menu item is refreshed in case its price or name changed
+			dailyItem = data.DailyItem
+			dailyItem.MenuItem = available[productID]
+			return nil
+		}
+
+		menuItem, found := available[productID]
+		if !found {
+			if menuItem, err = pickDailyItem(strategy, available, menuItemMap, inventoryMap, *data, today, now); err != nil {
+				return err
 			}
+		}
 
-			// Calculate the sales amount for this item (quantity * price)
-			totalSalesAmount += float64(orderItem.Quantity) * menuItem.Price
+		dailyItem = models.DailyItem{
+			Date:     today.Format("2006-01-02"),
+			Strategy: strategy,
+			ChosenAt: now.Format(time.RFC3339),
+			MenuItem: menuItem,
 		}
+		data.DailyItem = dailyItem
+		logging.Info("Daily item selected", "itemID", menuItem.ID, "strategy", strategy, "date", dailyItem.Date)
+		return nil
+	})
+	if err != nil {
+		return models.DailyItem{}, err
 	}
+	return dailyItem, nil
+}
+
+// GetMarginReport costs every menu item and flags those whose gross margin
+// percentage is below the threshold. Items whose recipe no longer converts
+// to the stock units are left out.
+func (s *reportService) GetMarginReport(threshold float64) (models.MarginReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.MarginReport{}, err
+	}
+
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return models.MarginReport{}, err
+	}
+
+	menuItemMap := mapMenuItems(menuItems)
+	inventoryMap := mapInventoryItems(inventoryItems)
 
-	logging.Info("Total sales amount calculated", "totalSalesAmount", totalSalesAmount)
-	return totalSalesAmount, nil
+	report := models.MarginReport{
+		Threshold:      threshold,
+		Items:          []models.MenuItemCost{},
+		BelowThreshold: []string{},
+	}
+	for _, menuItem := range menuItems {
+		itemCost, err := menuItemCost(menuItem, menuItemMap, inventoryMap)
+		if err != nil {
+			logging.Error("Failed to cost menu item, leaving it out of the report", err, "itemID", menuItem.ID)
+			continue
+		}
+		if itemCost.MarginPercent < threshold {
+			itemCost.BelowThreshold = true
+			report.BelowThreshold = append(report.BelowThreshold, menuItem.ID)
+		}
+		report.Items = append(report.Items, itemCost)
+	}
+
+	logging.Info("Margin report calculated", "items", len(report.Items), "belowThreshold", len(report.BelowThreshold))
+	return report, nil
 }
 
-// GetMostPopularItem calculates the most frequently ordered product by product ID
-func (s *reportService) GetMostPopularItem() (models.MenuItem, error) {
+// GetCustomerReport analyses the customers of the closed orders created
+// within the date range and ranks them by spend. The number of weeks runs
+// over the range, or between the first and last order when it is open, and
+// is at least one. A limit of zero lists every customer.
+func (s *reportService) GetCustomerReport(from, to time.Time, limit int) (models.CustomerReport, error) {
 	defer utils.CatchCriticalPoint()
 
-	// Fetch all closed orders
 	orders, err := s.orderRepo.ReadClosedOrders()
 	if err != nil {
 		logging.Error("Failed to read closed orders", err)
-		return models.MenuItem{}, err
+		return models.CustomerReport{}, err
+	}
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.CustomerReport{}, err
 	}
+	menuItemMap := mapMenuItems(menuItems)
 
-	// Map to count frequency of each ProductID
-	productFrequency := make(map[string]int)
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.CustomerReport{}, err
+	}
+
+	// A customer goes by the name on their earliest order
+	type customerTally struct {
+		summary    models.CustomerSummary
+		firstOrder time.Time
+		visits     map[string]bool
+		products   map[string]int
+	}
+	customers := make(map[string]*customerTally)
+	var keys []string
+	var first, last time.Time
 
-	// Loop through all orders and count the frequency of each product
 	for _, order := range orders {
-		for _, orderItem := range order.Items {
-			productFrequency[orderItem.ProductID]++ // Increment frequency of each ProductID
+		if !utils.InDateRange(order.CreatedAt, from, to) {
+			continue
+		}
+		key := customerKey(order.CustomerName)
+		if key == "" {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339, order.CreatedAt)
+		if first.IsZero() || createdAt.Before(first) {
+			first = createdAt
+		}
+		if createdAt.After(last) {
+			last = createdAt
+		}
+
+		customer, found := customers[key]
+		if !found {
+			customer = &customerTally{
+				visits:   make(map[string]bool),
+				products: make(map[string]int),
+			}
+			customers[key] = customer
+			keys = append(keys, key)
+		}
+		if customer.summary.Customer == "" || createdAt.Before(customer.firstOrder) {
+			customer.summary.Customer = strings.Join(strings.Fields(order.CustomerName), " ")
+			customer.firstOrder = createdAt
 		}
-	}
 
-	// Find the ProductID with the highest frequency
-	var mostPopularProductID string
-	var highestFrequency int
-	for productID, frequency := range productFrequency {
-		if frequency > highestFrequency {
-			mostPopularProductID = productID
-			highestFrequency = frequency
+		day := createdAt.In(loc).Format("2006-01-02")
+		if customer.summary.FirstVisit == "" || day < customer.summary.FirstVisit {
+			customer.summary.FirstVisit = day
+		}
+		if day > customer.summary.LastVisit {
+			customer.summary.LastVisit = day
+		}
+		customer.visits[day] = true
+		customer.summary.Orders++
+		customer.summary.Spend += orderRevenue(order, menuItemMap)
+		for _, item := range order.Items {
+			customer.products[item.ProductID] += item.Quantity
 		}
 	}
 
-	// If we have a most popular product, fetch its details from the menu
-	if mostPopularProductID != "" {
-		// Fetch all menu items
-		menuItems, err := s.menuRepo.ReadItems()
-		if err != nil {
-			logging.Error("Failed to fetch menu items", err)
-			return models.MenuItem{}, err
-		}
+	report := models.CustomerReport{TopCustomers: []models.CustomerSummary{}}
+	if !from.IsZero() {
+		report.From = from.Format(time.RFC3339)
+		first = from
+	}
+	if !to.IsZero() {
+		report.To = to.Format(time.RFC3339)
+		last = to
+	}
+	report.Weeks = math.Max(1, math.Round(last.Sub(first).Hours()/24/7*100)/100)
 
-		// Search for the MenuItem corresponding to the most popular ProductID
-		for _, menuItem := range menuItems {
-			if menuItem.ID == mostPopularProductID {
-				logging.Info("Most popular item found", "productID", mostPopularProductID, "frequency", highestFrequency)
-				return menuItem, nil
+	var totalVisits int
+	for _, key := range keys {
+		customer := customers[key]
+		summary := &customer.summary
+		summary.Visits = len(customer.visits)
+		summary.VisitsPerWeek = roundPrice(float64(summary.Visits) / report.Weeks)
+		summary.Spend = roundPrice(summary.Spend)
+		summary.AverageTicket = roundPrice(summary.Spend / float64(summary.Orders))
+		for productID, quantity := range customer.products {
+			if quantity > summary.FavouriteCount || (quantity == summary.FavouriteCount && productID < summary.FavouriteProduct) {
+				summary.FavouriteProduct = productID
+				summary.FavouriteCount = quantity
 			}
 		}
+		summary.FavouriteName = menuItemMap[summary.FavouriteProduct].Name
+
+		report.UniqueCustomers++
+		if summary.Visits > 1 {
+			report.RepeatCustomers++
+		}
+		totalVisits += summary.Visits
+		report.TotalSpend += summary.Spend
+		report.TopCustomers = append(report.TopCustomers, *summary)
+	}
+	report.TotalSpend = roundPrice(report.TotalSpend)
+	report.RepeatRate = share(float64(report.RepeatCustomers), float64(report.UniqueCustomers))
+	if report.UniqueCustomers > 0 {
+		report.AverageVisitsPerWeek = roundPrice(float64(totalVisits) / float64(report.UniqueCustomers) / report.Weeks)
+	}
+
+	sort.SliceStable(report.TopCustomers, func(i, j int) bool {
+		a, b := report.TopCustomers[i], report.TopCustomers[j]
+		if a.Spend != b.Spend {
+			return a.Spend > b.Spend
+		}
+		return customerKey(a.Customer) < customerKey(b.Customer)
+	})
+	if limit > 0 && len(report.TopCustomers) > limit {
+		report.TopCustomers = report.TopCustomers[:limit]
+	}
+	for i := range report.TopCustomers {
+		report.TopCustomers[i].Rank = i + 1
 	}
 
-	// If no popular product found, return error
-	logging.Info("No popular item found", "productID", mostPopularProductID)
-	return models.MenuItem{}, nil
+	logging.Info("Customer report calculated", "customers", report.UniqueCustomers, "repeatCustomers", report.RepeatCustomers)
+	return report, nil
+}
+
+// GetAggregates returns the running sales totals, building them from the
+// orders the first time.
+func (s *reportService) GetAggregates() (models.AggregationData, error) {
+	defer utils.CatchCriticalPoint()
+
+	return s.aggregates()
 }
 
-// GetDailyItem selects a random menu item from the available items.
-func (s *reportService) GetDailyItem() (models.MenuItem, error) {
+// RebuildAggregates recomputes the running sales totals from the closed
+// orders, discarding what was stored. The daily item is kept.
+func (s *reportService) RebuildAggregates() (models.AggregationData, error) {
 	defer utils.CatchCriticalPoint()
 
-	// Fetch all menu items
-	items, err := s.menuRepo.ReadItems()
+	logging.Info("Rebuilding sales aggregates from orders")
+
+	menuItems, err := s.menuRepo.ReadItems()
 	if err != nil {
 		logging.Error("Failed to fetch menu items", err)
-		return models.MenuItem{}, err
+		return models.AggregationData{}, err
 	}
+	menuItemMap := mapMenuItems(menuItems)
 
-	if len(items) == 0 {
-		logging.Warn("No menu items available")
-		return models.MenuItem{}, errors.New("no menu items available")
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.AggregationData{}, err
 	}
 
-	// Select a random menu item
-	dailyItem := items[utils.RandomInt(0, len(items)-1)]
+	// Orders are saved and added to the totals under dayCloseMu, so holding
+	// it counts every closed order exactly once: one saved before is read
+	// here, one saved after is added by its own recordSale
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
 
-	logging.Info("Daily item selected", "itemID", dailyItem.ID)
+	var rebuilt models.AggregationData
+	err = s.aggregationRepo.UpdateAggregationData(func(data *models.AggregationData) error {
+		orders, err := s.orderRepo.ReadClosedOrders()
+		if err != nil {
+			logging.Error("Failed to read closed orders", err)
+			return err
+		}
 
-	return dailyItem, nil
+		rebuilt = models.AggregationData{
+			Days:      map[string]models.DaySales{},
+			DailyItem: data.DailyItem,
+			RebuiltAt: time.Now().Format(time.RFC3339),
+		}
+		for _, order := range orders {
+			aggregateOrder(&rebuilt, order, menuItemMap, loc)
+		}
+		rebuilt.UpdatedAt = rebuilt.RebuiltAt
+
+		*data = rebuilt
+		return nil
+	})
+	if err != nil {
+		logging.Error("Failed to save rebuilt aggregates", err)
+		return models.AggregationData{}, err
+	}
+
+	logging.Info("Rebuilt sales aggregates", "orders", rebuilt.TotalOrders, "days", len(rebuilt.Days))
+	return rebuilt, nil
+}
+
+// aggregates reads the running sales totals. Aggregates that were never
+// built are rebuilt from the orders first.
+func (s *reportService) aggregates() (models.AggregationData, error) {
+	data, err := s.aggregationRepo.ReadAggregationData()
+	if err != nil {
+		logging.Error("Failed to read aggregation data", err)
+		return models.AggregationData{}, err
+	}
+	if data.RebuiltAt == "" {
+		return s.RebuildAggregates()
+	}
+	return data, nil
+}
+
+// recordSale adds a closed order to the running sales totals. A failure is
+// only logged: the order stays closed, and rebuilding the aggregates picks
+// it up. Aggregates that were never built are left for their first rebuild.
+func recordSale(aggregationRepo dal.AggregationRepository, order models.Order, menuItemMap map[string]models.MenuItem) {
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return
+	}
+
+	err = aggregationRepo.UpdateAggregationData(func(data *models.AggregationData) error {
+		if data.RebuiltAt == "" {
+			return nil
+		}
+		aggregateOrder(data, order, menuItemMap, loc)
+		data.UpdatedAt = time.Now().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		logging.Error("Failed to update sales aggregates", err, "orderID", order.ID)
+	}
+}
+
+// removeSale takes a voided order back out of the running sales totals. A
+// failure is only logged: rebuilding the aggregates drops the order.
+func removeSale(aggregationRepo dal.AggregationRepository, order models.Order, menuItemMap map[string]models.MenuItem) {
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return
+	}
+
+	err = aggregationRepo.UpdateAggregationData(func(data *models.AggregationData) error {
+		if data.RebuiltAt == "" {
+			return nil
+		}
+		deductOrder(data, order, menuItemMap, loc)
+		data.UpdatedAt = time.Now().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		logging.Error("Failed to update sales aggregates", err, "orderID", order.ID)
+	}
+}
+
+// aggregateOrder adds a closed order to the totals of the business day and
+// hour it was created in.
+func aggregateOrder(data *models.AggregationData, order models.Order, menuItemMap map[string]models.MenuItem, loc *time.Location) {
+	tallyOrder(data, order, menuItemMap, loc, 1)
+}
+
+// deductOrder takes an order added by aggregateOrder back out of the totals.
+// Its products come off the categories they were counted in, and emptied
+// days, hours, products and categories are dropped. The first sale is kept.
+func deductOrder(data *models.AggregationData, order models.Order, menuItemMap map[string]models.MenuItem, loc *time.Location) {
+	tallyOrder(data, order, menuItemMap, loc, -1)
+}
+
+// tallyOrder adds an order to the totals, or takes it out when sign is -1.
+func tallyOrder(data *models.AggregationData, order models.Order, menuItemMap map[string]models.MenuItem, loc *time.Location, sign int) {
+	createdAt, err := time.Parse(time.RFC3339, order.CreatedAt)
+	if err != nil {
+		logging.Warn("Skipping order with invalid timestamp", "orderID", order.ID, "createdAt", order.CreatedAt)
+		return
+	}
+	createdAt = createdAt.In(loc)
+
+	revenue := float64(sign) * orderRevenue(order, menuItemMap)
+	var itemsSold int
+	for _, item := range order.Items {
+		itemsSold += sign * item.Quantity
+	}
+
+	data.TotalSales = roundPrice(data.TotalSales + revenue)
+	data.TotalOrders += sign
+	data.ItemsSold += itemsSold
+	if firstSale, err := time.Parse(time.RFC3339, data.FirstSale); sign > 0 && (err != nil || createdAt.Before(firstSale)) {
+		data.FirstSale = order.CreatedAt
+	}
+
+	if data.Days == nil {
+		data.Days = map[string]models.DaySales{}
+	}
+	day := data.Days[createdAt.Format("2006-01-02")]
+	if day.Hours == nil {
+		day.Hours = map[string]models.HourSales{}
+		day.Products = map[string]models.ProductSales{}
+		day.Categories = map[string]models.SalesCount{}
+	}
+	day.Revenue = roundPrice(day.Revenue + revenue)
+	day.Orders += sign
+	day.ItemsSold += itemsSold
+
+	hour := day.Hours[createdAt.Format("15")]
+	hour.Revenue = roundPrice(hour.Revenue + revenue)
+	hour.Orders += sign
+	hour.ItemsSold += itemsSold
+	day.Hours[createdAt.Format("15")] = hour
+	if hour.Orders <= 0 {
+		delete(day.Hours, createdAt.Format("15"))
+	}
+
+	// An order counts once per product and once per category
+	inOrder := make(map[string]bool)
+	for _, orderItem := range order.Items {
+		products, err := expandOrderItem(orderItem, menuItemMap)
+		if err != nil {
+			logging.Warn("Menu item not found for order item", "menuItemID", orderItem.ProductID, "error", err)
+			continue
+		}
+
+		for _, product := range products {
+			productSales := day.Products[product.ProductID]
+			category := productSales.Category
+			if sign > 0 {
+				category = menuCategory(menuItemMap[product.ProductID])
+				productSales.Category = category
+			}
+			productSales.Quantity += sign * product.Quantity
+			productSales.Revenue = roundPrice(productSales.Revenue + float64(sign)*product.Revenue)
+			if !inOrder[product.ProductID] {
+				inOrder[product.ProductID] = true
+				productSales.Orders += sign
+			}
+			day.Products[product.ProductID] = productSales
+
+			categorySales := day.Categories[category]
+			categorySales.Quantity += sign * product.Quantity
+			categorySales.Revenue = roundPrice(categorySales.Revenue + float64(sign)*product.Revenue)
+			if !inOrder["category/"+category] {
+				inOrder["category/"+category] = true
+				categorySales.Orders += sign
+			}
+			day.Categories[category] = categorySales
+		}
+	}
+	for productID, productSales := range day.Products {
+		if productSales.Orders <= 0 {
+			delete(day.Products, productID)
+		}
+	}
+	for category, categorySales := range day.Categories {
+		if categorySales.Orders <= 0 {
+			delete(day.Categories, category)
+		}
+	}
+
+	data.Days[createdAt.Format("2006-01-02")] = day
+	if day.Orders <= 0 {
+		delete(data.Days, createdAt.Format("2006-01-02"))
+	}
+}
+
+// aggregatedPopularItems is popularItems over the aggregated business days
+// starting within the date range.
+func aggregatedPopularItems(data models.AggregationData, menuItemMap map[string]models.MenuItem, from, to time.Time, by string, loc *time.Location) models.PopularItemsReport {
+	tally := newPopularTally(from, to)
+	for day, daySales := range data.Days {
+		start, err := time.ParseInLocation("2006-01-02", day, loc)
+		if err != nil || !utils.InDateRange(start.Format(time.RFC3339), from, to) {
+			continue
+		}
+
+		tally.report.TotalOrders += daySales.Orders
+		for productID, productSales := range daySales.Products {
+			tally.addProduct(productID, menuItemMap[productID].Name, productSales.Category, productSales.SalesCount)
+		}
+		for category, categorySales := range daySales.Categories {
+			tally.category(category).Orders += categorySales.Orders
+		}
+	}
+	return tally.finish(by)
+}
+
+// popularItems totals the products of the orders in the date range and ranks
+// them by the given measure, ties broken by product ID.
+func popularItems(orders []models.Order, menuItemMap map[string]models.MenuItem, from, to time.Time, by string) models.PopularItemsReport {
+	tally := newPopularTally(from, to)
+	for _, order := range orders {
+		if !utils.InDateRange(order.CreatedAt, from, to) {
+			continue
+		}
+		tally.report.TotalOrders++
+
+		// An order counts once per product and once per category
+		inOrder := make(map[string]bool)
+		for _, orderItem := range order.Items {
+			products, err := expandOrderItem(orderItem, menuItemMap)
+			if err != nil {
+				logging.Warn("Menu item not found for order item", "menuItemID", orderItem.ProductID, "error", err)
+				continue
+			}
+
+			for _, product := range products {
+				menuItem := menuItemMap[product.ProductID]
+				category := menuCategory(menuItem)
+
+				sales := models.SalesCount{Quantity: product.Quantity, Revenue: product.Revenue}
+				if !inOrder[product.ProductID] {
+					inOrder[product.ProductID] = true
+					sales.Orders = 1
+				}
+				tally.addProduct(product.ProductID, menuItem.Name, category, sales)
+
+				if !inOrder["category/"+category] {
+					inOrder["category/"+category] = true
+					tally.category(category).Orders++
+				}
+			}
+		}
+	}
+	return tally.finish(by)
+}
+
+// popularTally collects the products and categories of a popular items report.
+type popularTally struct {
+	report        models.PopularItemsReport
+	itemIndex     map[string]int
+	categoryIndex map[string]int
+}
+
+func newPopularTally(from, to time.Time) *popularTally {
+	tally := &popularTally{
+		report: models.PopularItemsReport{
+			Items:      []models.PopularItem{},
+			Categories: []models.CategorySales{},
+		},
+		itemIndex:     make(map[string]int),
+		categoryIndex: make(map[string]int),
+	}
+	if !from.IsZero() {
+		tally.report.From = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		tally.report.To = to.Format(time.RFC3339)
+	}
+	return tally
+}
+
+// addProduct adds sales to a product, its category and the totals. The
+// category's order count is left to the caller.
+func (t *popularTally) addProduct(productID, name, category string, sales models.SalesCount) {
+	i, found := t.itemIndex[productID]
+	if !found {
+		i = len(t.report.Items)
+		t.itemIndex[productID] = i
+		t.report.Items = append(t.report.Items, models.PopularItem{
+			ProductID: productID,
+			Name:      name,
+			Category:  category,
+		})
+	}
+	item := &t.report.Items[i]
+	item.Quantity += sales.Quantity
+	item.Revenue += sales.Revenue
+	item.Orders += sales.Orders
+
+	categorySales := t.category(category)
+	categorySales.Quantity += sales.Quantity
+	categorySales.Revenue += sales.Revenue
+
+	t.report.TotalQuantity += sales.Quantity
+	t.report.TotalRevenue += sales.Revenue
+}
+
+func (t *popularTally) category(category string) *models.CategorySales {
+	j, found := t.categoryIndex[category]
+	if !found {
+		j = len(t.report.Categories)
+		t.categoryIndex[category] = j
+		t.report.Categories = append(t.report.Categories, models.CategorySales{Category: category})
+	}
+	return &t.report.Categories[j]
+}
+
+// finish rounds the totals, works out the shares and ranks the products
+// and categories by the given measure, ties broken by ID.
+func (t *popularTally) finish(by string) models.PopularItemsReport {
+	report := t.report
+	report.By = by
+
+	for i := range report.Items {
+		item := &report.Items[i]
+		item.Revenue = roundPrice(item.Revenue)
+		item.QuantityShare = share(float64(item.Quantity), float64(report.TotalQuantity))
+		item.RevenueShare = share(item.Revenue, report.TotalRevenue)
+		report.Categories[t.categoryIndex[item.Category]].Products++
+	}
+	for i := range report.Categories {
+		category := &report.Categories[i]
+		category.Revenue = roundPrice(category.Revenue)
+		category.QuantityShare = share(float64(category.Quantity), float64(report.TotalQuantity))
+		category.RevenueShare = share(category.Revenue, report.TotalRevenue)
+	}
+	report.TotalRevenue = roundPrice(report.TotalRevenue)
+
+	measure := func(quantity int, revenue float64, orders int) float64 {
+		switch by {
+		case "revenue":
+			return revenue
+		case "orders":
+			return float64(orders)
+		}
+		return float64(quantity)
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if ma, mb := measure(a.Quantity, a.Revenue, a.Orders), measure(b.Quantity, b.Revenue, b.Orders); ma != mb {
+			return ma > mb
+		}
+		return a.ProductID < b.ProductID
+	})
+	sort.SliceStable(report.Categories, func(i, j int) bool {
+		a, b := report.Categories[i], report.Categories[j]
+		if ma, mb := measure(a.Quantity, a.Revenue, a.Orders), measure(b.Quantity, b.Revenue, b.Orders); ma != mb {
+			return ma > mb
+		}
+		return a.Category < b.Category
+	})
+	for i := range report.Items {
+		report.Items[i].Rank = i + 1
+	}
+
+	return report
+}
+
+// customerKey identifies a customer by name, ignoring case and extra
+// whitespace, so "John  Doe" and "john doe" are the same customer.
+func customerKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// menuCategory is the category a menu item is reported under.
+func menuCategory(menuItem models.MenuItem) string {
+	if category := strings.ToLower(strings.TrimSpace(menuItem.Category)); category != "" {
+		return category
+	}
+	return "uncategorized"
+}
+
+// share is part as a percentage of total, rounded to two decimals.
+func share(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundPrice(part / total * 100)
+}
+
+// orderRevenue is what an order brought in after discounts.
+func orderRevenue(order models.Order, menuItemMap map[string]models.MenuItem) float64 {
+	var revenue float64
+	for _, item := range order.Items {
+		revenue += lineRevenue(item, menuItemMap[item.ProductID])
+	}
+	return revenue
+}
+
+// finishSalesTotals rounds the revenue and works out the average ticket.
+func finishSalesTotals(totals *models.SalesTotals) {
+	totals.Revenue = roundPrice(totals.Revenue)
+	totals.AverageTicket = 0
+	if totals.Orders > 0 {
+		totals.AverageTicket = roundPrice(totals.Revenue / float64(totals.Orders))
+	}
+}
+
+// bucketStart returns the start of the hour, day, week or month a moment
+// falls in, in the given time zone. Weeks start on Monday.
+func bucketStart(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		// Hours step by the wall clock, as bucketStart counts them, so the
+		// hour repeated when the clocks go back is a single bucket
+		next := bucketStart(start.Add(time.Hour), interval, start.Location())
+		if !next.After(start) {
+			next = bucketStart(start.Add(2*time.Hour), interval, start.Location())
+		}
+		return next
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// alignedToDays reports whether a range bound falls on midnight in the
+// given time zone. Open bounds are aligned.
+func alignedToDays(t time.Time, loc *time.Location) bool {
+	if t.IsZero() {
+		return true
+	}
+	t = t.In(loc)
+	return t.Equal(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc))
+}
+
+// alignedToHours reports whether a range bound falls on a whole hour in a
+// time zone offset from UTC by whole hours. Open bounds are aligned.
+func alignedToHours(t time.Time, loc *time.Location) bool {
+	if t.IsZero() {
+		return true
+	}
+	_, offset := t.In(loc).Zone()
+	return offset%3600 == 0 && t.Equal(t.Truncate(time.Hour))
+}
+
+// wholeHourZone reports whether a time zone is currently offset from UTC by
+// whole hours.
+func wholeHourZone(loc *time.Location) bool {
+	_, offset := time.Now().In(loc).Zone()
+	return offset%3600 == 0
 }
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeductOrderUndoesAggregateOrder(t *testing.T) {
+	menuItemMap := map[string]models.MenuItem{
+		"espresso": {ID: "espresso", Name: "Espresso", Price: 2.5, Category: "coffee"},
+		"muffin":   {ID: "muffin", Name: "Muffin", Price: 3, Category: "bakery"},
+	}
+	order := func(id string, day, hour int, items ...models.OrderItem) models.Order {
+		return models.Order{
+			ID:        id,
+			Status:    "closed",
+			CreatedAt: time.Date(2026, 1, day, hour, 30, 0, 0, time.UTC).Format(time.RFC3339),
+			Items:     items,
+		}
+	}
+	espresso := models.OrderItem{ProductID: "espresso", Quantity: 2}
+	muffin := models.OrderItem{ProductID: "muffin", Quantity: 1}
+
+	tests := []struct {
+		name     string
+		orders   []models.Order
+		voided   []models.Order
+		category string
+	}{
+		{
+			name:   "only order of its day",
+			orders: []models.Order{order("order1", 5, 10, espresso), order("order2", 6, 9, muffin)},
+			voided: []models.Order{order("order1", 5, 10, espresso)},
+		},
+		{
+			name:   "order sharing its hour and products",
+			orders: []models.Order{order("order1", 5, 10, espresso, muffin), order("order2", 5, 10, espresso)},
+			voided: []models.Order{order("order1", 5, 10, espresso, muffin)},
+		},
+		{
+			name:   "every order",
+			orders: []models.Order{order("order1", 5, 10, espresso), order("order2", 5, 11, muffin)},
+			voided: []models.Order{order("order2", 5, 11, muffin), order("order1", 5, 10, espresso)},
+		},
+		{
+			name:     "category changed since the sale",
+			orders:   []models.Order{order("order1", 5, 10, espresso), order("order2", 5, 11, espresso)},
+			voided:   []models.Order{order("order1", 5, 10, espresso)},
+			category: "drinks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data models.AggregationData
+			for _, order := range tt.orders {
+				aggregateOrder(&data, order, menuItemMap, time.UTC)
+			}
+
+			deductMenu := menuItemMap
+			if tt.category != "" {
+				deductMenu = map[string]models.MenuItem{}
+				for id, menuItem := range menuItemMap {
+					menuItem.Category = tt.category
+					deductMenu[id] = menuItem
+				}
+			}
+			for _, order := range tt.voided {
+				deductOrder(&data, order, deductMenu, time.UTC)
+			}
+
+			// The totals must be those of the orders that were never voided
+			want := models.AggregationData{Days: map[string]models.DaySales{}}
+			for _, order := range tt.orders {
+				voided := false
+				for _, voidedOrder := range tt.voided {
+					voided = voided || voidedOrder.ID == order.ID
+				}
+				if !voided {
+					aggregateOrder(&want, order, menuItemMap, time.UTC)
+				}
+			}
+
+			if data.TotalOrders != want.TotalOrders || data.TotalSales != want.TotalSales || data.ItemsSold != want.ItemsSold {
+				t.Errorf("totals = %d orders, %v sales, %d items, want %d orders, %v sales, %d items",
+					data.TotalOrders, data.TotalSales, data.ItemsSold, want.TotalOrders, want.TotalSales, want.ItemsSold)
+			}
+			if !reflect.DeepEqual(data.Days, want.Days) {
+				t.Errorf("days = %+v, want %+v", data.Days, want.Days)
+			}
+		})
+	}
+}
+
+func TestPopularItems(t *testing.T) {
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4, Category: "Coffee"},
+		{ID: "espresso", Name: "Espresso", Price: 2.5, Category: "coffee"},
+		{ID: "muffin", Name: "Muffin", Price: 3, Category: "Bakery"},
+	})
+	order := func(id string, day int, items ...models.OrderItem) models.Order {
+		return models.Order{
+			ID:        id,
+			Status:    "closed",
+			CreatedAt: time.Date(2026, 1, day, 10, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			Items:     items,
+		}
+	}
+	orders := []models.Order{
+		order("order1", 5, models.OrderItem{ProductID: "latte", Quantity: 1}, models.OrderItem{ProductID: "muffin", Quantity: 1}),
+		order("order2", 5, models.OrderItem{ProductID: "espresso", Quantity: 4}),
+		order("order3", 5, models.OrderItem{ProductID: "latte", Quantity: 1}, models.OrderItem{ProductID: "latte", Quantity: 1}),
+		order("order4", 7, models.OrderItem{ProductID: "muffin", Quantity: 10}),
+	}
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{by: "quantity", want: []string{"espresso", "latte", "muffin"}},
+		{by: "revenue", want: []string{"latte", "espresso", "muffin"}},
+		{by: "orders", want: []string{"latte", "espresso", "muffin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			report := popularItems(orders, menuItemMap, from, to, tt.by)
+
+			var ranked []string
+			for i, item := range report.Items {
+				ranked = append(ranked, item.ProductID)
+				if item.Rank != i+1 {
+					t.Errorf("%s rank = %d, want %d", item.ProductID, item.Rank, i+1)
+				}
+			}
+			if !reflect.DeepEqual(ranked, tt.want) {
+				t.Errorf("ranking = %v, want %v", ranked, tt.want)
+			}
+			if report.TotalOrders != 3 || report.TotalQuantity != 8 || report.TotalRevenue != 25 {
+				t.Errorf("totals = %d orders, %d sold, %v revenue, want 3, 8, 25", report.TotalOrders, report.TotalQuantity, report.TotalRevenue)
+			}
+
+			items := make(map[string]models.PopularItem)
+			for _, item := range report.Items {
+				items[item.ProductID] = item
+			}
+			latte := items["latte"]
+			if latte.Quantity != 3 || latte.Revenue != 12 || latte.Orders != 2 || latte.QuantityShare != 37.5 || latte.RevenueShare != 48 {
+				t.Errorf("latte = %+v, want 3 sold in 2 orders for 12, shares 37.5%% and 48%%", latte)
+			}
+
+			categories := make(map[string]models.CategorySales)
+			for _, category := range report.Categories {
+				categories[category.Category] = category
+			}
+			coffee, bakery := categories["coffee"], categories["bakery"]
+			if len(categories) != 2 || coffee.Products != 2 || coffee.Quantity != 7 || coffee.Orders != 3 || bakery.Quantity != 1 || bakery.Orders != 1 {
+				t.Errorf("categories = %+v, want coffee with 2 products, 7 sold in 3 orders and bakery with 1 sold", report.Categories)
+			}
+
+			// Whole days served from the aggregates give the same report
+			var data models.AggregationData
+			for _, order := range orders {
+				aggregateOrder(&data, order, menuItemMap, time.UTC)
+			}
+			if aggregated := aggregatedPopularItems(data, menuItemMap, from, to, tt.by, time.UTC); !reflect.DeepEqual(aggregated, report) {
+				t.Errorf("aggregated report = %+v, want %+v", aggregated, report)
+			}
+		})
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		interval  string
+		wantStart time.Time
+		wantNext  time.Time
+	}{
+		{
+			name:      "day in the report's time zone",
+			at:        time.Date(2026, 1, 5, 23, 30, 0, 0, time.UTC),
+			interval:  "day",
+			wantStart: time.Date(2026, 1, 6, 0, 0, 0, 0, loc),
+			wantNext:  time.Date(2026, 1, 7, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "week starting Monday",
+			at:        time.Date(2026, 1, 11, 12, 0, 0, 0, loc),
+			interval:  "week",
+			wantStart: time.Date(2026, 1, 5, 0, 0, 0, 0, loc),
+			wantNext:  time.Date(2026, 1, 12, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "month",
+			at:        time.Date(2026, 2, 28, 12, 0, 0, 0, loc),
+			interval:  "month",
+			wantStart: time.Date(2026, 2, 1, 0, 0, 0, 0, loc),
+			wantNext:  time.Date(2026, 3, 1, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "day the clocks go forward is 23 hours",
+			at:        time.Date(2026, 3, 29, 12, 0, 0, 0, loc),
+			interval:  "day",
+			wantStart: time.Date(2026, 3, 28, 23, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2026, 3, 29, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "hour before the clocks go back",
+			at:        time.Date(2026, 10, 24, 23, 30, 0, 0, time.UTC),
+			interval:  "hour",
+			wantStart: time.Date(2026, 10, 24, 23, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2026, 10, 25, 2, 0, 0, 0, loc),
+		},
+		{
+			name:      "hour repeated when the clocks go back is one bucket",
+			at:        time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC),
+			interval:  "hour",
+			wantStart: time.Date(2026, 10, 25, 2, 0, 0, 0, loc),
+			wantNext:  time.Date(2026, 10, 25, 3, 0, 0, 0, loc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := bucketStart(tt.at, tt.interval, loc)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("bucketStart(%v, %s) = %v, want %v", tt.at, tt.interval, start, tt.wantStart)
+			}
+			if next := nextBucket(start, tt.interval); !next.Equal(tt.wantNext) {
+				t.Errorf("nextBucket(%v, %s) = %v, want %v", start, tt.interval, next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestGetSalesReport(t *testing.T) {
+	useTempData(t)
+	previousZone := config.TimeZone
+	config.TimeZone = "UTC"
+	t.Cleanup(func() { config.TimeZone = previousZone })
+
+	menuRepo, orderRepo := &dal.MenuItemService{}, &dal.OrderService{}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4},
+		{ID: "muffin", Name: "Muffin", Price: 3},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	order := func(id, status string, day, hour int, items ...models.OrderItem) models.Order {
+		return models.Order{
+			ID:        id,
+			Status:    status,
+			CreatedAt: time.Date(2026, 1, day, hour, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			Items:     items,
+		}
+	}
+	if err := orderRepo.SaveItems([]models.Order{
+		order("order1", "closed", 5, 10, models.OrderItem{ProductID: "latte", Quantity: 2}),
+		order("order2", "closed", 5, 11, models.OrderItem{ProductID: "muffin", Quantity: 1}),
+		order("order3", "open", 6, 9, models.OrderItem{ProductID: "latte", Quantity: 5}),
+		order("order4", "closed", 7, 9, models.OrderItem{ProductID: "latte", Quantity: 1}),
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	service := NewReportService(menuRepo, &dal.AggregationService{}, orderRepo, &dal.InventoryItemService{})
+
+	wantBuckets := []models.SalesTotals{
+		{Revenue: 11, Orders: 2, ItemsSold: 3, AverageTicket: 5.5},
+		{},
+		{Revenue: 4, Orders: 1, ItemsSold: 1, AverageTicket: 4},
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+	}{
+		// Whole hours are served from the aggregates, the rest from the orders
+		{name: "aggregated", from: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), to: time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "from the orders", from: time.Date(2026, 1, 5, 0, 30, 0, 0, time.UTC), to: time.Date(2026, 1, 7, 23, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := service.GetSalesReport(tt.from, tt.to, "day", "", time.UTC)
+			if err != nil {
+				t.Fatalf("GetSalesReport failed: %v", err)
+			}
+
+			var buckets []models.SalesTotals
+			for _, bucket := range report.Buckets {
+				buckets = append(buckets, bucket.SalesTotals)
+			}
+			if !reflect.DeepEqual(buckets, wantBuckets) {
+				t.Errorf("buckets = %+v, want %+v", buckets, wantBuckets)
+			}
+			want := models.SalesTotals{Revenue: 15, Orders: 3, ItemsSold: 4, AverageTicket: 5}
+			if report.Totals != want {
+				t.Errorf("totals = %+v, want %+v", report.Totals, want)
+			}
+		})
+	}
+
+	if _, err := service.GetSalesReport(time.Time{}, time.Time{}, "year", "", time.UTC); err == nil {
+		t.Error("GetSalesReport succeeded with an invalid interval")
+	}
+	if _, err := service.GetSalesReport(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "hour", "", time.UTC); err == nil {
+		t.Error("GetSalesReport succeeded with more buckets than allowed")
+	}
+}
+
+func TestCustomerKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "John Doe", want: "john doe"},
+		{name: "  john   DOE ", want: "john doe"},
+		{name: "John\tDoe", want: "john doe"},
+		{name: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := customerKey(tt.name); got != tt.want {
+				t.Errorf("customerKey(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCustomerReport(t *testing.T) {
+	useTempData(t)
+	previousZone := config.TimeZone
+	config.TimeZone = "UTC"
+	t.Cleanup(func() { config.TimeZone = previousZone })
+
+	menuRepo, orderRepo := &dal.MenuItemService{}, &dal.OrderService{}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4},
+		{ID: "muffin", Name: "Muffin", Price: 3},
+		{ID: "tea", Name: "Tea", Price: 2},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	order := func(id, status, customer string, day, hour int, items ...models.OrderItem) models.Order {
+		return models.Order{
+			ID:           id,
+			Status:       status,
+			CustomerName: customer,
+			CreatedAt:    time.Date(2026, 1, day, hour, 0, 0, 0, time.UTC).Format(time.RFC3339),
+			Items:        items,
+		}
+	}
+	if err := orderRepo.SaveItems([]models.Order{
+		order("order1", "closed", "John  Doe", 5, 10, models.OrderItem{ProductID: "latte", Quantity: 2}),
+		order("order2", "closed", "john doe", 5, 15, models.OrderItem{ProductID: "muffin", Quantity: 1}),
+		order("order3", "closed", " JOHN DOE ", 12, 9, models.OrderItem{ProductID: "latte", Quantity: 1}),
+		order("order4", "closed", "Ann", 6, 11, models.OrderItem{ProductID: "tea", Quantity: 1}),
+		order("order5", "closed", "", 7, 11, models.OrderItem{ProductID: "latte", Quantity: 1}),
+		order("order6", "open", "Bob", 7, 12, models.OrderItem{ProductID: "latte", Quantity: 1}),
+		order("order7", "closed", "Ann", 20, 9, models.OrderItem{ProductID: "latte", Quantity: 3}),
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	service := NewReportService(menuRepo, &dal.AggregationService{}, orderRepo, &dal.InventoryItemService{})
+
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC)
+	report, err := service.GetCustomerReport(from, to, 1)
+	if err != nil {
+		t.Fatalf("GetCustomerReport failed: %v", err)
+	}
+
+	if report.Weeks != 2 || report.UniqueCustomers != 2 || report.RepeatCustomers != 1 || report.RepeatRate != 50 ||
+		report.AverageVisitsPerWeek != 0.75 || report.TotalSpend != 17 {
+		t.Errorf("report = %+v, want 2 weeks, 2 customers, 1 repeating (50%%), 0.75 visits a week and 17 spent", report)
+	}
+	want := []models.CustomerSummary{{
+		Rank:             1,
+		Customer:         "John Doe",
+		Orders:           3,
+		Visits:           2,
+		VisitsPerWeek:    1,
+		Spend:            15,
+		AverageTicket:    5,
+		FirstVisit:       "2026-01-05",
+		LastVisit:        "2026-01-12",
+		FavouriteProduct: "latte",
+		FavouriteName:    "Latte",
+		FavouriteCount:   3,
+	}}
+	if !reflect.DeepEqual(report.TopCustomers, want) {
+		t.Errorf("top customers = %+v, want %+v", report.TopCustomers, want)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"hot-coffee/config"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"net/http"
+	"os"
+	"time"
+)
+
+// isLowStock reports whether an ingredient is at or below its reorder point.
+// Ingredients without a reorder point never raise alerts.
+func isLowStock(item models.InventoryItem) bool {
+	return item.ReorderPoint > 0 && item.Quantity <= item.ReorderPoint
+}
+
+func newStockAlert(item models.InventoryItem) models.StockAlert {
+	return models.StockAlert{
+		IngredientID:    item.IngredientID,
+		Name:            item.Name,
+		Quantity:        item.Quantity,
+		Unit:            item.Unit,
+		ReorderPoint:    item.ReorderPoint,
+		ReorderQuantity: item.ReorderQuantity,
+		RaisedAt:        time.Now().Format(time.RFC3339),
+	}
+}
+
+// checkReorderPoints compares the inventory before and after a change and
+// emits an alert for each ingredient that has just dropped to its reorder
+// point. Ingredients that were already low stay quiet until restocked.
+func checkReorderPoints(before []models.InventoryItem, after []models.InventoryItem) {
+	wasLow := make(map[string]bool)
+	for _, item := range before {
+		wasLow[item.IngredientID] = isLowStock(item)
+	}
+
+	for _, item := range after {
+		if isLowStock(item) && !wasLow[item.IngredientID] {
+			emitStockAlert(newStockAlert(item))
+		}
+	}
+}
+
+// emitStockAlert logs the alert and hands it to the configured sinks: a file
+// that gets one JSON alert per line, and a webhook that receives it as a
+// JSON POST.
+func emitStockAlert(alert models.StockAlert) {
+	logging.Warn("Ingredient reached its reorder point", "ingredientID", alert.IngredientID, "quantity", alert.Quantity, "reorderPoint", alert.ReorderPoint)
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		logging.Error("Failed to marshal stock alert", err)
+		return
+	}
+
+	if config.AlertFile != "" {
+		file, err := os.OpenFile(config.AlertFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o666)
+		if err != nil {
+			logging.Error("Failed to open alert file", err, "file", config.AlertFile)
+		} else {
+			if _, err := file.Write(append(data, '\n')); err != nil {
+				logging.Error("Failed to write stock alert", err, "file", config.AlertFile)
+			}
+			file.Close()
+		}
+	}
+
+	if config.AlertWebhook != "" {
+		// Deliver in the background so a slow receiver never holds up an order
+		go func() {
+			client := http.Client{Timeout: 5 * time.Second}
+			resp, err := client.Post(config.AlertWebhook, "application/json", bytes.NewReader(data))
+			if err != nil {
+				logging.Error("Failed to deliver stock alert", err, "webhook", config.AlertWebhook)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				logging.Warn("Stock alert webhook rejected alert", "webhook", config.AlertWebhook, "status", resp.StatusCode)
+			}
+		}()
+	}
+}
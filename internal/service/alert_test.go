@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"hot-coffee/config"
+	"hot-coffee/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckReorderPoints(t *testing.T) {
+	milk := func(quantity float64) models.InventoryItem {
+		return models.InventoryItem{IngredientID: "milk", Name: "Milk", Quantity: quantity, Unit: "ml", ReorderPoint: 500, ReorderQuantity: 2000}
+	}
+	sugar := models.InventoryItem{IngredientID: "sugar", Quantity: 0, Unit: "g"}
+
+	tests := []struct {
+		name   string
+		before []models.InventoryItem
+		after  []models.InventoryItem
+		want   []string
+	}{
+		{name: "drops to the reorder point", before: []models.InventoryItem{milk(600)}, after: []models.InventoryItem{milk(500)}, want: []string{"milk"}},
+		{name: "stays above", before: []models.InventoryItem{milk(900)}, after: []models.InventoryItem{milk(600)}},
+		{name: "already low", before: []models.InventoryItem{milk(400)}, after: []models.InventoryItem{milk(300)}},
+		{name: "new item below its reorder point", after: []models.InventoryItem{milk(100)}, want: []string{"milk"}},
+		{name: "no reorder point", before: []models.InventoryItem{sugar}, after: []models.InventoryItem{sugar}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alertFile := filepath.Join(t.TempDir(), "alerts.jsonl")
+			previous := config.AlertFile
+			config.AlertFile = alertFile
+			defer func() { config.AlertFile = previous }()
+
+			checkReorderPoints(tt.before, tt.after)
+
+			var got []string
+			file, err := os.Open(alertFile)
+			if err == nil {
+				defer file.Close()
+				scanner := bufio.NewScanner(file)
+				for scanner.Scan() {
+					var alert models.StockAlert
+					if err := json.Unmarshal(scanner.Bytes(), &alert); err != nil {
+						t.Fatalf("invalid alert line %q: %v", scanner.Text(), err)
+					}
+					got = append(got, alert.IngredientID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alerts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmitStockAlertWebhook(t *testing.T) {
+	received := make(chan models.StockAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert models.StockAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	previous := config.AlertWebhook
+	config.AlertWebhook = server.URL
+	defer func() { config.AlertWebhook = previous }()
+
+	emitStockAlert(models.StockAlert{IngredientID: "milk", Quantity: 400, ReorderPoint: 500})
+
+	select {
+	case alert := <-received:
+		if alert.IngredientID != "milk" || alert.Quantity != 400 {
+			t.Errorf("webhook got %+v, want the milk alert", alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook never received the alert")
+	}
+}
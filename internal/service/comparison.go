@@ -0,0 +1,172 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/models"
+	"time"
+)
+
+// validateComparison accepts one of the comparison periods, or none.
+func validateComparison(compare string) error {
+	if compare != "" && compare != models.ComparePreviousPeriod && compare != models.CompareSamePeriodLastYear {
+		return errors.New("invalid comparison: " + compare)
+	}
+	return nil
+}
+
+// comparisonPeriod returns the range a report over [from, to) is compared
+// with. Ranges of whole business days step back by days, so that the
+// previous period of a week starts on the same weekday.
+func comparisonPeriod(from, to time.Time, compare string, loc *time.Location) (time.Time, time.Time) {
+	if compare == models.CompareSamePeriodLastYear {
+		return from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+	}
+	if alignedToDays(from, loc) && alignedToDays(to, loc) {
+		days := int(to.Sub(from).Round(24*time.Hour) / (24 * time.Hour))
+		return from.AddDate(0, 0, -days), from
+	}
+	return from.Add(-to.Sub(from)), from
+}
+
+// newDelta compares a metric with its previous value. The percentage is
+// left out when the previous value is zero.
+func newDelta(current, previous float64) models.Delta {
+	delta := models.Delta{
+		Current:  current,
+		Previous: previous,
+		Change:   roundPrice(current - previous),
+	}
+	if previous != 0 {
+		percent := roundPrice((current - previous) / previous * 100)
+		delta.ChangePercent = &percent
+	}
+	return delta
+}
+
+func salesDeltas(current, previous models.SalesTotals) models.SalesDeltas {
+	return models.SalesDeltas{
+		Revenue:       newDelta(current.Revenue, previous.Revenue),
+		Orders:        newDelta(float64(current.Orders), float64(previous.Orders)),
+		ItemsSold:     newDelta(float64(current.ItemsSold), float64(previous.ItemsSold)),
+		AverageTicket: newDelta(current.AverageTicket, previous.AverageTicket),
+	}
+}
+
+func popularDeltas(quantity int, revenue float64, orders int, previousQuantity int, previousRevenue float64, previousOrders int) models.PopularDeltas {
+	return models.PopularDeltas{
+		Quantity: newDelta(float64(quantity), float64(previousQuantity)),
+		Revenue:  newDelta(revenue, previousRevenue),
+		Orders:   newDelta(float64(orders), float64(previousOrders)),
+	}
+}
+
+// compareSales sets the comparison of a sales report with the report over
+// the comparison period. Buckets are paired by position.
+func compareSales(report *models.SalesReport, previous models.SalesReport, compare string) {
+	report.Comparison = &models.SalesComparison{
+		Compare: compare,
+		From:    previous.From,
+		To:      previous.To,
+		Totals:  previous.Totals,
+		Deltas:  salesDeltas(report.Totals, previous.Totals),
+	}
+	for i := range report.Buckets {
+		var totals models.SalesTotals
+		if i < len(previous.Buckets) {
+			totals = previous.Buckets[i].SalesTotals
+		}
+		deltas := salesDeltas(report.Buckets[i].SalesTotals, totals)
+		report.Buckets[i].Previous = &totals
+		report.Buckets[i].Deltas = &deltas
+	}
+}
+
+// comparePopularItems compares the full rankings of two periods. The first
+// limit products of each ranking are listed; a product listed from the
+// previous ranking alone did not sell in the report's period.
+func comparePopularItems(report, previous models.PopularItemsReport, compare string, limit int) *models.PopularItemsComparison {
+	comparison := &models.PopularItemsComparison{
+		Compare: compare,
+		From:    previous.From,
+		To:      previous.To,
+		Deltas: popularDeltas(report.TotalQuantity, report.TotalRevenue, report.TotalOrders,
+			previous.TotalQuantity, previous.TotalRevenue, previous.TotalOrders),
+		Items:      []models.ProductComparison{},
+		Categories: []models.CategoryComparison{},
+	}
+
+	previousItems := make(map[string]models.PopularItem)
+	for _, item := range previous.Items {
+		previousItems[item.ProductID] = item
+	}
+	currentItems := make(map[string]bool)
+	for i, item := range report.Items {
+		currentItems[item.ProductID] = true
+		if limit > 0 && i >= limit {
+			continue
+		}
+
+		before, found := previousItems[item.ProductID]
+		status := models.ComparisonContinuing
+		if !found {
+			status = models.ComparisonNew
+		}
+		comparison.Items = append(comparison.Items, models.ProductComparison{
+			ProductID:     item.ProductID,
+			Name:          item.Name,
+			Category:      item.Category,
+			Status:        status,
+			Rank:          item.Rank,
+			PreviousRank:  before.Rank,
+			PopularDeltas: popularDeltas(item.Quantity, item.Revenue, item.Orders, before.Quantity, before.Revenue, before.Orders),
+		})
+	}
+	for i, before := range previous.Items {
+		if limit > 0 && i >= limit {
+			break
+		}
+		if currentItems[before.ProductID] {
+			continue
+		}
+		comparison.Items = append(comparison.Items, models.ProductComparison{
+			ProductID:     before.ProductID,
+			Name:          before.Name,
+			Category:      before.Category,
+			Status:        models.ComparisonDropped,
+			PreviousRank:  before.Rank,
+			PopularDeltas: popularDeltas(0, 0, 0, before.Quantity, before.Revenue, before.Orders),
+		})
+	}
+
+	previousCategories := make(map[string]models.CategorySales)
+	for _, category := range previous.Categories {
+		previousCategories[category.Category] = category
+	}
+	currentCategories := make(map[string]bool)
+	for _, category := range report.Categories {
+		currentCategories[category.Category] = true
+
+		before, found := previousCategories[category.Category]
+		status := models.ComparisonContinuing
+		if !found {
+			status = models.ComparisonNew
+		}
+		comparison.Categories = append(comparison.Categories, models.CategoryComparison{
+			Category:      category.Category,
+			Status:        status,
+			PopularDeltas: popularDeltas(category.Quantity, category.Revenue, category.Orders, before.Quantity, before.Revenue, before.Orders),
+		})
+	}
+	for _, before := range previous.Categories {
+		if currentCategories[before.Category] {
+			continue
+		}
+		comparison.Categories = append(comparison.Categories, models.CategoryComparison{
+			Category:      before.Category,
+			Status:        models.ComparisonDropped,
+			PopularDeltas: popularDeltas(0, 0, 0, before.Quantity, before.Revenue, before.Orders),
+		})
+	}
+
+	return comparison
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"hot-coffee/models"
+	"testing"
+	"time"
+)
+
+func TestComparisonPeriod(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name             string
+		from, to         time.Time
+		compare          string
+		wantFrom, wantTo time.Time
+	}{
+		{
+			name:     "same period last year",
+			from:     at(2026, 3, 1, 0),
+			to:       at(2026, 4, 1, 0),
+			compare:  models.CompareSamePeriodLastYear,
+			wantFrom: at(2025, 3, 1, 0),
+			wantTo:   at(2025, 4, 1, 0),
+		},
+		{
+			name:     "previous day",
+			from:     at(2026, 1, 5, 0),
+			to:       at(2026, 1, 6, 0),
+			compare:  models.ComparePreviousPeriod,
+			wantFrom: at(2026, 1, 4, 0),
+			wantTo:   at(2026, 1, 5, 0),
+		},
+		{
+			name:     "previous week across the clock change starts on the same weekday",
+			from:     at(2026, 3, 30, 0),
+			to:       at(2026, 4, 6, 0),
+			compare:  models.ComparePreviousPeriod,
+			wantFrom: at(2026, 3, 23, 0),
+			wantTo:   at(2026, 3, 30, 0),
+		},
+		{
+			name:     "hours step back by the duration",
+			from:     at(2026, 1, 5, 10),
+			to:       at(2026, 1, 5, 12),
+			compare:  models.ComparePreviousPeriod,
+			wantFrom: at(2026, 1, 5, 8),
+			wantTo:   at(2026, 1, 5, 10),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := comparisonPeriod(tt.from, tt.to, tt.compare, loc)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("comparisonPeriod = [%v, %v), want [%v, %v)", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"sort"
+	"time"
+)
+
+type ConsumptionService interface {
+	GetConsumptionReport(from, to time.Time) (models.ConsumptionReport, error)
+}
+
+type consumptionService struct {
+	orderRepo     dal.OrderRepository
+	menuRepo      dal.MenuRepository
+	inventoryRepo dal.InventoryRepository
+	movementRepo  dal.MovementRepository
+}
+
+func NewConsumptionService(orderRepo dal.OrderRepository, menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository, movementRepo dal.MovementRepository) ConsumptionService {
+	return &consumptionService{
+		orderRepo:     orderRepo,
+		menuRepo:      menuRepo,
+		inventoryRepo: inventoryRepo,
+		movementRepo:  movementRepo,
+	}
+}
+
+// consumptionTally accumulates the usage of one ingredient.
+type consumptionTally struct {
+	theoretical float64
+	// tracked is the theoretical usage since the ingredient's ledger started
+	tracked     float64
+	actual      float64
+	waste       float64
+	adjustments float64
+	days        map[string]*dayUsage
+	products    map[string]*models.MenuItemConsumption
+}
+
+type dayUsage struct {
+	theoretical float64
+	actual      float64
+}
+
+// GetConsumptionReport works out per ingredient what the closed orders of
+// the date range should have used, by day and by menu item, and compares it
+// with the sale movements booked in the ledger. Days are business days.
+func (s *consumptionService) GetConsumptionReport(from, to time.Time) (models.ConsumptionReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Building consumption report")
+
+	orders, err := s.orderRepo.ReadClosedOrders()
+	if err != nil {
+		logging.Error("Failed to read closed orders", err)
+		return models.ConsumptionReport{}, err
+	}
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return models.ConsumptionReport{}, err
+	}
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return models.ConsumptionReport{}, err
+	}
+	movements, err := s.movementRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read inventory movements", err)
+		return models.ConsumptionReport{}, err
+	}
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.ConsumptionReport{}, err
+	}
+
+	menuItemMap := mapMenuItems(menuItems)
+	inventoryMap := mapInventoryItems(inventoryItems)
+
+	tallies := make(map[string]*consumptionTally)
+	tally := func(ingredientID string) *consumptionTally {
+		if tallies[ingredientID] == nil {
+			tallies[ingredientID] = &consumptionTally{
+				days:     make(map[string]*dayUsage),
+				products: make(map[string]*models.MenuItemConsumption),
+			}
+		}
+		return tallies[ingredientID]
+	}
+	day := func(t *consumptionTally, timestamp string) *dayUsage {
+		createdAt, _ := time.Parse(time.RFC3339, timestamp)
+		date := createdAt.In(loc).Format("2006-01-02")
+		if t.days[date] == nil {
+			t.days[date] = &dayUsage{}
+		}
+		return t.days[date]
+	}
+
+	// Usage can only be compared from an ingredient's first ledger entry on
+	ledgerStart := make(map[string]time.Time)
+	movementTypes := make(map[string]string)
+	for _, movement := range movements {
+		createdAt, err := time.Parse(time.RFC3339, movement.CreatedAt)
+		if err != nil {
+			continue
+		}
+		if start, found := ledgerStart[movement.IngredientID]; !found || createdAt.Before(start) {
+			ledgerStart[movement.IngredientID] = createdAt
+		}
+		movementTypes[movement.ID] = movement.Type
+	}
+	tracked := func(ingredientID string, timestamp string) bool {
+		start, found := ledgerStart[ingredientID]
+		if !found {
+			return false
+		}
+		createdAt, _ := time.Parse(time.RFC3339, timestamp)
+		return !createdAt.Before(start)
+	}
+
+	// Theoretical usage: every product sold times its recipe
+	for _, order := range orders {
+		if !utils.InDateRange(order.CreatedAt, from, to) {
+			continue
+		}
+		for _, orderItem := range order.Items {
+			products, err := expandOrderItem(orderItem, menuItemMap)
+			if err != nil {
+				logging.Warn("Skipping unresolvable order item", "orderID", order.ID, "productID", orderItem.ProductID, "error", err)
+				continue
+			}
+			for _, product := range products {
+				usage, err := recipeUsage(menuItemMap[product.ProductID], float64(product.Quantity), inventoryMap)
+				if err != nil {
+					logging.Warn("Skipping product without a usable recipe", "productID", product.ProductID, "error", err)
+					continue
+				}
+				for _, line := range usage {
+					t := tally(line.IngredientID)
+					t.theoretical += line.Quantity
+					day(t, order.CreatedAt).theoretical += line.Quantity
+					if tracked(line.IngredientID, order.CreatedAt) {
+						t.tracked += line.Quantity
+					}
+
+					if t.products[product.ProductID] == nil {
+						t.products[product.ProductID] = &models.MenuItemConsumption{
+							ProductID: product.ProductID,
+							Name:      menuItemMap[product.ProductID].Name,
+						}
+					}
+					t.products[product.ProductID].Sold += product.Quantity
+					t.products[product.ProductID].Theoretical += line.Quantity
+				}
+			}
+		}
+	}
+
+	// Actual usage: sale movements, less reversals of sales, from the ledger
+	for _, movement := range movements {
+		if !utils.InDateRange(movement.CreatedAt, from, to) {
+			continue
+		}
+
+		movementType := movement.Type
+		if movementType == models.MovementReversal {
+			movementType = movementTypes[movement.ReversesID]
+		}
+		switch movementType {
+		case models.MovementSale:
+			t := tally(movement.IngredientID)
+			t.actual -= movement.Quantity
+			day(t, movement.CreatedAt).actual -= movement.Quantity
+		case models.MovementWaste:
+			tally(movement.IngredientID).waste -= movement.Quantity
+		case models.MovementAdjustment:
+			tally(movement.IngredientID).adjustments += movement.Quantity
+		}
+	}
+
+	report := models.ConsumptionReport{Ingredients: []models.IngredientConsumption{}}
+	if !from.IsZero() {
+		report.From = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		report.To = to.Format(time.RFC3339)
+	}
+
+	for ingredientID, t := range tallies {
+		consumption := models.IngredientConsumption{
+			IngredientID: ingredientID,
+			Name:         inventoryMap[ingredientID].Name,
+			Unit:         inventoryMap[ingredientID].Unit,
+			Theoretical:  roundQuantity(t.theoretical),
+			Waste:        roundQuantity(t.waste),
+			Adjustments:  roundQuantity(t.adjustments),
+			ByDay:        []models.ConsumptionDay{},
+			ByMenuItem:   []models.MenuItemConsumption{},
+		}
+		start, hasLedger := ledgerStart[ingredientID]
+		if hasLedger {
+			actual := roundQuantity(t.actual)
+			variance := roundQuantity(actual - t.tracked)
+			consumption.Actual = &actual
+			consumption.Variance = &variance
+			if t.tracked != 0 {
+				percent := roundPrice(variance / t.tracked * 100)
+				consumption.VariancePercent = &percent
+			}
+		}
+
+		for date, usage := range t.days {
+			consumptionDay := models.ConsumptionDay{Date: date, Theoretical: roundQuantity(usage.theoretical)}
+			if hasLedger && date >= start.In(loc).Format("2006-01-02") {
+				actual := roundQuantity(usage.actual)
+				consumptionDay.Actual = &actual
+			}
+			consumption.ByDay = append(consumption.ByDay, consumptionDay)
+		}
+		sort.Slice(consumption.ByDay, func(i, j int) bool {
+			return consumption.ByDay[i].Date < consumption.ByDay[j].Date
+		})
+
+		for _, product := range t.products {
+			product.Theoretical = roundQuantity(product.Theoretical)
+			consumption.ByMenuItem = append(consumption.ByMenuItem, *product)
+		}
+		sort.Slice(consumption.ByMenuItem, func(i, j int) bool {
+			a, b := consumption.ByMenuItem[i], consumption.ByMenuItem[j]
+			if a.Theoretical != b.Theoretical {
+				return a.Theoretical > b.Theoretical
+			}
+			return a.ProductID < b.ProductID
+		})
+
+		report.Ingredients = append(report.Ingredients, consumption)
+	}
+	sort.Slice(report.Ingredients, func(i, j int) bool {
+		return report.Ingredients[i].IngredientID < report.Ingredients[j].IngredientID
+	})
+
+	logging.Info("Built consumption report", "ingredients", len(report.Ingredients))
+	return report, nil
+}
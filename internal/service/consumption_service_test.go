@@ -0,0 +1,139 @@
+package service
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetConsumptionReport(t *testing.T) {
+	useTempData(t)
+	previousZone := config.TimeZone
+	config.TimeZone = "UTC"
+	t.Cleanup(func() { config.TimeZone = previousZone })
+
+	at := func(day, hour, minute int) string {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+	orderRepo, menuRepo, inventoryRepo, movementRepo := &dal.OrderService{}, &dal.MenuItemService{}, &dal.InventoryItemService{}, &dal.MovementService{}
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{
+		{IngredientID: "milk", Name: "Milk", Quantity: 4150, Unit: "ml"},
+		{IngredientID: "beans", Name: "Beans", Quantity: 928, Unit: "g"},
+		{IngredientID: "sugar", Name: "Sugar", Quantity: 500, Unit: "g"},
+	}); err != nil {
+		t.Fatalf("SaveItem failed: %v", err)
+	}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4, Ingredients: []models.MenuItemIngredient{
+			{IngredientID: "beans", Quantity: 18},
+			{IngredientID: "milk", Quantity: 0.2, Unit: "l"},
+		}},
+		{ID: "espresso", Name: "Espresso", Price: 2.5, Ingredients: []models.MenuItemIngredient{
+			{IngredientID: "beans", Quantity: 18},
+			{IngredientID: "sugar", Quantity: 5},
+		}},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	// The first order was closed before the ledger started
+	if err := orderRepo.SaveItems([]models.Order{
+		{ID: "order1", Status: "closed", CreatedAt: at(4, 10, 0), Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}},
+		{ID: "order2", Status: "closed", CreatedAt: at(5, 10, 0), Items: []models.OrderItem{{ProductID: "latte", Quantity: 2}}},
+		{ID: "order3", Status: "closed", CreatedAt: at(6, 10, 0), Items: []models.OrderItem{{ProductID: "espresso", Quantity: 1}, {ProductID: "latte", Quantity: 1}}},
+		{ID: "order4", Status: "open", CreatedAt: at(6, 11, 0), Items: []models.OrderItem{{ProductID: "latte", Quantity: 5}}},
+		{ID: "order5", Status: "closed", CreatedAt: at(9, 10, 0), Items: []models.OrderItem{{ProductID: "latte", Quantity: 5}}},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+
+	movement := func(ingredientID, movementType string, quantity, balanceAfter float64, createdAt string) models.InventoryMovement {
+		return models.InventoryMovement{IngredientID: ingredientID, Type: movementType, Quantity: quantity, BalanceAfter: balanceAfter, CreatedAt: createdAt}
+	}
+	if _, err := movementRepo.Append(
+		movement("milk", models.MovementOpening, 5000, 5000, at(5, 8, 0)),
+		movement("beans", models.MovementOpening, 1000, 1000, at(5, 8, 0)),
+		movement("milk", models.MovementSale, -400, 4600, at(5, 10, 0)),
+		movement("beans", models.MovementSale, -36, 964, at(5, 10, 0)),
+		movement("milk", models.MovementSale, -250, 4350, at(6, 10, 0)),
+		movement("beans", models.MovementSale, -36, 928, at(6, 10, 0)),
+		movement("milk", models.MovementWaste, -100, 4250, at(6, 12, 0)),
+	); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	// A sale that was reversed does not count
+	stored, err := movementRepo.Append(movement("milk", models.MovementSale, -200, 4050, at(7, 9, 0)))
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	reversal := movement("milk", models.MovementReversal, 200, 4250, at(7, 9, 5))
+	reversal.ReversesID = stored[0].ID
+	if _, err := movementRepo.Append(reversal); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	service := NewConsumptionService(orderRepo, menuRepo, inventoryRepo, movementRepo)
+	report, err := service.GetConsumptionReport(time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetConsumptionReport failed: %v", err)
+	}
+
+	ingredients := make(map[string]models.IngredientConsumption)
+	for _, ingredient := range report.Ingredients {
+		ingredients[ingredient.IngredientID] = ingredient
+	}
+	if len(report.Ingredients) != 3 || report.Ingredients[0].IngredientID != "beans" {
+		t.Fatalf("ingredients = %+v, want beans, milk and sugar", report.Ingredients)
+	}
+
+	number := func(value *float64) interface{} {
+		if value == nil {
+			return nil
+		}
+		return *value
+	}
+	tests := []struct {
+		ingredientID    string
+		wantTheoretical float64
+		wantActual      interface{}
+		wantVariance    interface{}
+		wantPercent     interface{}
+		wantWaste       float64
+	}{
+		{ingredientID: "milk", wantTheoretical: 800, wantActual: 650.0, wantVariance: 50.0, wantPercent: 8.33, wantWaste: 100},
+		{ingredientID: "beans", wantTheoretical: 90, wantActual: 72.0, wantVariance: 0.0, wantPercent: 0.0},
+		{ingredientID: "sugar", wantTheoretical: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ingredientID, func(t *testing.T) {
+			ingredient := ingredients[tt.ingredientID]
+			if ingredient.Theoretical != tt.wantTheoretical || ingredient.Waste != tt.wantWaste {
+				t.Errorf("theoretical = %v, waste = %v, want %v, %v", ingredient.Theoretical, ingredient.Waste, tt.wantTheoretical, tt.wantWaste)
+			}
+			if number(ingredient.Actual) != tt.wantActual || number(ingredient.Variance) != tt.wantVariance || number(ingredient.VariancePercent) != tt.wantPercent {
+				t.Errorf("actual = %v, variance = %v (%v%%), want %v, %v (%v%%)",
+					number(ingredient.Actual), number(ingredient.Variance), number(ingredient.VariancePercent), tt.wantActual, tt.wantVariance, tt.wantPercent)
+			}
+		})
+	}
+
+	var days []string
+	for _, day := range ingredients["milk"].ByDay {
+		days = append(days, day.Date)
+	}
+	if !reflect.DeepEqual(days, []string{"2026-01-04", "2026-01-05", "2026-01-06", "2026-01-07"}) {
+		t.Errorf("milk days = %v, want the 4th to the 7th", days)
+	}
+	if byDay := ingredients["milk"].ByDay; byDay[0].Actual != nil || number(byDay[2].Actual) != 250.0 || byDay[2].Theoretical != 200 || number(byDay[3].Actual) != 0.0 {
+		t.Errorf("milk by day = %+v, want no actual before the ledger, 250 against 200 on the 6th and the reversed sale netted out", byDay)
+	}
+	wantProducts := []models.MenuItemConsumption{
+		{ProductID: "latte", Name: "Latte", Sold: 4, Theoretical: 72},
+		{ProductID: "espresso", Name: "Espresso", Sold: 1, Theoretical: 18},
+	}
+	if !reflect.DeepEqual(ingredients["beans"].ByMenuItem, wantProducts) {
+		t.Errorf("beans by menu item = %+v, want %+v", ingredients["beans"].ByMenuItem, wantProducts)
+	}
+}
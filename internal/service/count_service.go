@@ -0,0 +1,425 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type CountService interface {
+	OpenCount(openedBy string, note string, ingredientIDs []string) (models.StockCount, error)
+	GetCounts() ([]models.StockCount, error)
+	GetCountByID(id string) (models.StockCount, error)
+	SubmitCount(id string, countedBy string, counts []models.CountedQuantity) (models.StockCount, error)
+	GetVarianceReport(id string) (models.VarianceReport, error)
+	ApproveCount(id string, approvedBy string) (models.StockCount, error)
+	CancelCount(id string) (models.StockCount, error)
+}
+
+type countService struct {
+	countRepo     dal.StockCountRepository
+	inventoryRepo dal.InventoryRepository
+	movementRepo  dal.MovementRepository
+}
+
+func NewCountService(countRepo dal.StockCountRepository, inventoryRepo dal.InventoryRepository, movementRepo dal.MovementRepository) CountService {
+	return &countService{
+		countRepo:     countRepo,
+		inventoryRepo: inventoryRepo,
+		movementRepo:  movementRepo,
+	}
+}
+
+// OpenCount starts a count of the given ingredients, or of the whole
+// inventory when none are given, taking their current quantities as the
+// expected ones. An ingredient can only be in one unfinished count.
+func (s *countService) OpenCount(openedBy string, note string, ingredientIDs []string) (models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to open stock count", "openedBy", openedBy, "ingredients", len(ingredientIDs))
+
+	if strings.TrimSpace(openedBy) == "" {
+		return models.StockCount{}, errors.New("opened by cannot be empty")
+	}
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return models.StockCount{}, err
+	}
+	inventoryMap := mapInventoryItems(items)
+
+	if len(ingredientIDs) == 0 {
+		for _, item := range items {
+			ingredientIDs = append(ingredientIDs, item.IngredientID)
+		}
+	}
+
+	// The count is numbered and checked against the unfinished ones under the count lock
+	var count models.StockCount
+	err = s.countRepo.UpdateItems(func(counts []models.StockCount) ([]models.StockCount, error) {
+		inCount := make(map[string]string)
+		var ids []string
+		for _, existing := range counts {
+			ids = append(ids, existing.ID)
+			if existing.Status != models.CountOpen && existing.Status != models.CountSubmitted {
+				continue
+			}
+			for _, line := range existing.Lines {
+				inCount[line.IngredientID] = existing.ID
+			}
+		}
+
+		count = models.StockCount{
+			ID:       utils.NextID("count", ids),
+			Status:   models.CountOpen,
+			OpenedBy: openedBy,
+			OpenedAt: time.Now().Format(time.RFC3339),
+			Note:     note,
+		}
+		seen := make(map[string]bool)
+		for _, id := range ingredientIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			item, found := inventoryMap[id]
+			if !found {
+				logging.Warn("Ingredient not found in inventory", "ingredientID", id)
+				return nil, errors.New("ingredient not found in inventory: " + id)
+			}
+			if countID, busy := inCount[id]; busy {
+				logging.Warn("Ingredient already in an unfinished stock count", "ingredientID", id, "countID", countID)
+				return nil, errors.New("ingredient " + id + " is already being counted in " + countID)
+			}
+			count.Lines = append(count.Lines, models.StockCountLine{
+				IngredientID:     item.IngredientID,
+				Name:             item.Name,
+				Unit:             item.Unit,
+				ExpectedQuantity: item.Quantity,
+				UnitCost:         item.UnitCost,
+			})
+		}
+		if len(count.Lines) == 0 {
+			return nil, errors.New("stock count has no ingredients")
+		}
+		return append(counts, count), nil
+	})
+	if err != nil {
+		logging.Warn("Failed to open stock count", "error", err)
+		return models.StockCount{}, err
+	}
+
+	logging.Info("Successfully opened stock count", "countID", count.ID, "lines", len(count.Lines))
+	return count, nil
+}
+
+func (s *countService) GetCounts() ([]models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching stock counts")
+
+	counts, err := s.countRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read stock counts", err)
+		return nil, err
+	}
+
+	logging.Info("Fetched stock counts", "count", len(counts))
+	return counts, nil
+}
+
+func (s *countService) GetCountByID(id string) (models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching stock count by ID", "countID", id)
+
+	counts, err := s.countRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read stock counts", err)
+		return models.StockCount{}, err
+	}
+
+	for _, count := range counts {
+		if count.ID == id {
+			return count, nil
+		}
+	}
+
+	logging.Warn("Stock count not found", "countID", id)
+	return models.StockCount{}, errors.New("stock count not found")
+}
+
+// SubmitCount records counted quantities. Counts may be submitted in parts
+// and corrected until the session is approved; once every line has been
+// counted the session moves to submitted and is ready for review.
+func (s *countService) SubmitCount(id string, countedBy string, counted []models.CountedQuantity) (models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to submit stock count", "countID", id, "lines", len(counted))
+
+	if strings.TrimSpace(countedBy) == "" {
+		return models.StockCount{}, errors.New("counted by cannot be empty")
+	}
+	if len(counted) == 0 {
+		return models.StockCount{}, errors.New("no counted quantities submitted")
+	}
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return models.StockCount{}, err
+	}
+	inventoryMap := mapInventoryItems(items)
+
+	var count models.StockCount
+	err = s.countRepo.UpdateItems(func(counts []models.StockCount) ([]models.StockCount, error) {
+		index := findCount(counts, id)
+		if index < 0 {
+			logging.Warn("Stock count not found", "countID", id)
+			return nil, errors.New("stock count not found")
+		}
+		if err := submitCountedQuantities(&counts[index], countedBy, counted, inventoryMap); err != nil {
+			return nil, err
+		}
+		count = counts[index]
+		return counts, nil
+	})
+	if err != nil {
+		logging.Warn("Failed to submit stock count", "countID", id, "error", err)
+		return models.StockCount{}, err
+	}
+
+	logging.Info("Successfully submitted stock count", "countID", id, "status", count.Status)
+	return count, nil
+}
+
+// submitCountedQuantities records counted quantities on an unfinished count
+// and moves it to submitted once every line has been counted.
+func submitCountedQuantities(count *models.StockCount, countedBy string, counted []models.CountedQuantity, inventoryMap map[string]models.InventoryItem) error {
+	if count.Status != models.CountOpen && count.Status != models.CountSubmitted {
+		return errors.New("stock count is " + count.Status)
+	}
+
+	for _, entry := range counted {
+		if entry.Quantity < 0 {
+			return errors.New("counted quantity cannot be negative: " + entry.IngredientID)
+		}
+
+		line := findCountLine(count, entry.IngredientID)
+		if line == nil {
+			return errors.New("ingredient is not part of this stock count: " + entry.IngredientID)
+		}
+
+		quantity := entry.Quantity
+		if entry.Unit != "" {
+			var err error
+			item := inventoryMap[entry.IngredientID]
+			quantity, err = utils.ConvertQuantity(entry.Quantity, entry.Unit, line.Unit, item.CustomUnits)
+			if err != nil {
+				logging.Warn("Failed to convert counted unit", "ingredientID", entry.IngredientID, "error", err)
+				return err
+			}
+		}
+		quantity = roundQuantity(quantity)
+
+		line.CountedQuantity = &quantity
+		line.Variance = roundQuantity(quantity - line.ExpectedQuantity)
+		line.VarianceValue = roundPrice(line.Variance * line.UnitCost)
+	}
+
+	count.CountedBy = countedBy
+	count.Status = models.CountSubmitted
+	for _, line := range count.Lines {
+		if line.CountedQuantity == nil {
+			count.Status = models.CountOpen
+			break
+		}
+	}
+	if count.Status == models.CountSubmitted {
+		count.SubmittedAt = time.Now().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// GetVarianceReport lists the counted lines that differ from the expected
+// quantities, largest value first.
+func (s *countService) GetVarianceReport(id string) (models.VarianceReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	count, err := s.GetCountByID(id)
+	if err != nil {
+		return models.VarianceReport{}, err
+	}
+
+	report := models.VarianceReport{
+		CountID: count.ID,
+		Status:  count.Status,
+		Lines:   []models.StockCountLine{},
+	}
+	for _, line := range count.Lines {
+		if line.CountedQuantity == nil || line.Variance == 0 {
+			continue
+		}
+		report.Lines = append(report.Lines, line)
+		if line.VarianceValue < 0 {
+			report.ShortageValue += line.VarianceValue
+		} else {
+			report.SurplusValue += line.VarianceValue
+		}
+	}
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		return math.Abs(report.Lines[i].VarianceValue) > math.Abs(report.Lines[j].VarianceValue)
+	})
+	report.ShortageValue = roundPrice(report.ShortageValue)
+	report.SurplusValue = roundPrice(report.SurplusValue)
+	report.NetValue = roundPrice(report.ShortageValue + report.SurplusValue)
+
+	logging.Info("Built variance report", "countID", id, "lines", len(report.Lines))
+	return report, nil
+}
+
+// ApproveCount posts the variance of every line as an adjustment. The
+// variance is applied to the current quantity, so stock sold while the
+// count was in progress is not lost.
+func (s *countService) ApproveCount(id string, approvedBy string) (models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to approve stock count", "countID", id)
+
+	if strings.TrimSpace(approvedBy) == "" {
+		return models.StockCount{}, errors.New("approved by cannot be empty")
+	}
+
+	// The status is checked and set under the count lock, so a count is
+	// never posted twice; the adjustments are booked within it
+	var count models.StockCount
+	var before, after []models.InventoryItem
+	var adjustments int
+	err := s.countRepo.UpdateItems(func(counts []models.StockCount) ([]models.StockCount, error) {
+		index := findCount(counts, id)
+		if index < 0 {
+			logging.Warn("Stock count not found", "countID", id)
+			return nil, errors.New("stock count not found")
+		}
+		if counts[index].Status != models.CountSubmitted {
+			return nil, errors.New("only a submitted stock count can be approved")
+		}
+
+		var err error
+		before, after, adjustments, err = s.postCount(counts[index])
+		if err != nil {
+			return nil, err
+		}
+
+		counts[index].Status = models.CountApproved
+		counts[index].ApprovedBy = approvedBy
+		counts[index].ApprovedAt = time.Now().Format(time.RFC3339)
+		count = counts[index]
+		return counts, nil
+	})
+	if err != nil {
+		logging.Error("Failed to approve stock count", err, "countID", id)
+		return models.StockCount{}, err
+	}
+
+	checkReorderPoints(before, after)
+
+	logging.Info("Successfully approved stock count", "countID", id, "adjustments", adjustments)
+	return count, nil
+}
+
+// postCount books the variance of every line of a count under the inventory
+// lock and returns the quantities before and after, and the number of
+// adjustments booked.
+func (s *countService) postCount(count models.StockCount) (before, after []models.InventoryItem, adjustments int, err error) {
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		before = append([]models.InventoryItem(nil), items...)
+
+		var movements []models.InventoryMovement
+		for _, line := range count.Lines {
+			if line.Variance == 0 {
+				continue
+			}
+			for i := range items {
+				item := &items[i]
+				if item.IngredientID != line.IngredientID {
+					continue
+				}
+
+				// A shortage is taken out like waste, a surplus is stock outside any lot
+				adjustment := line.Variance
+				if adjustment < 0 {
+					adjustment = -min(-adjustment, item.Quantity)
+					discardLots(item, -adjustment)
+				} else {
+					item.Quantity = roundQuantity(item.Quantity + adjustment)
+				}
+				movements = append(movements, newMovement(item.IngredientID, models.MovementAdjustment, adjustment, item.Quantity, "stock count "+count.ID))
+			}
+		}
+		if err := recordMovements(s.movementRepo, movements...); err != nil {
+			return nil, err
+		}
+
+		after = items
+		adjustments = len(movements)
+		return items, nil
+	})
+	return before, after, adjustments, err
+}
+
+// CancelCount abandons an unfinished count without touching the inventory.
+func (s *countService) CancelCount(id string) (models.StockCount, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to cancel stock count", "countID", id)
+
+	var count models.StockCount
+	err := s.countRepo.UpdateItems(func(counts []models.StockCount) ([]models.StockCount, error) {
+		index := findCount(counts, id)
+		if index < 0 {
+			logging.Warn("Stock count not found", "countID", id)
+			return nil, errors.New("stock count not found")
+		}
+		if counts[index].Status != models.CountOpen && counts[index].Status != models.CountSubmitted {
+			return nil, errors.New("stock count is " + counts[index].Status)
+		}
+
+		counts[index].Status = models.CountCancelled
+		count = counts[index]
+		return counts, nil
+	})
+	if err != nil {
+		logging.Warn("Failed to cancel stock count", "countID", id, "error", err)
+		return models.StockCount{}, err
+	}
+
+	logging.Info("Successfully cancelled stock count", "countID", id)
+	return count, nil
+}
+
+func findCount(counts []models.StockCount, id string) int {
+	for i := range counts {
+		if counts[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func findCountLine(count *models.StockCount, ingredientID string) *models.StockCountLine {
+	for i := range count.Lines {
+		if count.Lines[i].IngredientID == ingredientID {
+			return &count.Lines[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+)
+
+func TestStockCount(t *testing.T) {
+	useTempData(t)
+	inventoryRepo := &dal.InventoryItemService{}
+	movementRepo := &dal.MovementService{}
+	inventory := NewInventoryService(inventoryRepo, movementRepo, &dal.ReceiptService{}, &dal.MenuItemService{})
+	service := NewCountService(&dal.StockCountService{}, inventoryRepo, movementRepo)
+	for _, item := range []models.InventoryItem{
+		{IngredientID: "milk", Name: "Milk", Quantity: 1000, Unit: "ml", UnitCost: 0.002},
+		{IngredientID: "beans", Name: "Beans", Quantity: 1000, Unit: "g", UnitCost: 0.02},
+	} {
+		if err := inventory.AddInventoryItem(item); err != nil {
+			t.Fatalf("AddInventoryItem failed: %v", err)
+		}
+	}
+
+	count, err := service.OpenCount("aida", "", nil)
+	if err != nil {
+		t.Fatalf("OpenCount failed: %v", err)
+	}
+	if _, err := service.OpenCount("aida", "", []string{"milk"}); err == nil {
+		t.Error("OpenCount succeeded for an ingredient already being counted")
+	}
+
+	// A partial count leaves the count open
+	count, err = service.SubmitCount(count.ID, "aida", []models.CountedQuantity{{IngredientID: "milk", Quantity: 0.9, Unit: "l"}})
+	if err != nil {
+		t.Fatalf("SubmitCount failed: %v", err)
+	}
+	if count.Status != models.CountOpen {
+		t.Errorf("status after a partial count = %s, want %s", count.Status, models.CountOpen)
+	}
+	if _, err := service.ApproveCount(count.ID, "boss"); err == nil {
+		t.Error("ApproveCount succeeded on an open count")
+	}
+	count, err = service.SubmitCount(count.ID, "aida", []models.CountedQuantity{{IngredientID: "beans", Quantity: 1010}})
+	if err != nil {
+		t.Fatalf("SubmitCount failed: %v", err)
+	}
+	if count.Status != models.CountSubmitted {
+		t.Errorf("status after a full count = %s, want %s", count.Status, models.CountSubmitted)
+	}
+
+	report, err := service.GetVarianceReport(count.ID)
+	if err != nil {
+		t.Fatalf("GetVarianceReport failed: %v", err)
+	}
+	var variances []float64
+	for _, line := range report.Lines {
+		variances = append(variances, line.Variance)
+	}
+	if !reflect.DeepEqual(variances, []float64{-100, 10}) || report.ShortageValue != -0.2 || report.SurplusValue != 0.2 || report.NetValue != 0 {
+		t.Errorf("report = %v, %v short, %v surplus, %v net, want [-100 10], -0.2, 0.2, 0", variances, report.ShortageValue, report.SurplusValue, report.NetValue)
+	}
+
+	// Stock sold while the count was in progress is kept
+	if _, err := inventory.PatchInventoryItem("milk", map[string]interface{}{"adjust": -50.0}); err != nil {
+		t.Fatalf("PatchInventoryItem failed: %v", err)
+	}
+	if _, err := service.ApproveCount(count.ID, "boss"); err != nil {
+		t.Fatalf("ApproveCount failed: %v", err)
+	}
+	if _, err := service.ApproveCount(count.ID, "boss"); err == nil {
+		t.Error("ApproveCount succeeded twice")
+	}
+
+	items, err := inventory.GetAllInventoryItems()
+	if err != nil {
+		t.Fatalf("GetAllInventoryItems failed: %v", err)
+	}
+	quantities := make(map[string]float64)
+	for _, item := range items {
+		quantities[item.IngredientID] = item.Quantity
+	}
+	if want := map[string]float64{"milk": 850, "beans": 1010}; !reflect.DeepEqual(quantities, want) {
+		t.Errorf("stock = %v, want %v", quantities, want)
+	}
+}
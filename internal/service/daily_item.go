@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+// slowMovingDays is how many business days of sales the slow-moving
+// strategy looks back over.
+const slowMovingDays = 14
+
+// pickDailyItem chooses one of the available menu items by strategy.
+func pickDailyItem(strategy string, available map[string]models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem, data models.AggregationData, today time.Time, now time.Time) (models.MenuItem, error) {
+	var candidates []models.MenuItem
+	for _, menuItem := range available {
+		candidates = append(candidates, menuItem)
+	}
+	if len(candidates) == 0 {
+		return models.MenuItem{}, errors.New("no menu items available")
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	switch strategy {
+	case models.DailyItemRotation:
+		// The next item after the last pick, in product ID order
+		for _, candidate := range candidates {
+			if candidate.ID > data.DailyItem.ID {
+				return candidate, nil
+			}
+		}
+		return candidates[0], nil
+	case models.DailyItemSlowMoving:
+		return slowMovingItem(candidates, menuItemMap, inventoryMap, data, today, now), nil
+	}
+	return candidates[utils.RandomInt(0, len(candidates)-1)], nil
+}
+
+// slowMovingItem picks the item whose slowest-moving ingredient would last
+// the most days at the recent rate of use. Ingredients that were not used
+// recently have no rate to measure cover by, so they are ignored, and items
+// made only of such ingredients are not ranked. Ties go to the item that
+// sold least, then by ID. Bundles are left out, as they only draw on their
+// components' stock. With no item ranked the first candidate is returned.
+func slowMovingItem(candidates []models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem, data models.AggregationData, today time.Time, now time.Time) models.MenuItem {
+	dailyUsage := make(map[string]float64)
+	itemSales := make(map[string]int)
+	for i := 1; i <= slowMovingDays; i++ {
+		daySales := data.Days[today.AddDate(0, 0, -i).Format("2006-01-02")]
+		for productID, productSales := range daySales.Products {
+			itemSales[productID] += productSales.Quantity
+			usage, err := recipeUsage(menuItemMap[productID], float64(productSales.Quantity), inventoryMap)
+			if err != nil {
+				continue
+			}
+			for _, line := range usage {
+				dailyUsage[line.IngredientID] += line.Quantity / slowMovingDays
+			}
+		}
+	}
+
+	best, bestCover := candidates[0], -1.0
+	for _, candidate := range candidates {
+		if candidate.IsBundle() || len(candidate.Ingredients) == 0 {
+			continue
+		}
+
+		cover := -1.0
+		for _, ingredient := range candidate.Ingredients {
+			usage := dailyUsage[ingredient.IngredientID]
+			if usage <= 0 {
+				continue
+			}
+			available := availableQuantity(inventoryMap[ingredient.IngredientID], now)
+			cover = math.Max(cover, available/usage)
+		}
+		if cover < 0 {
+			continue
+		}
+
+		if cover > bestCover || (cover == bestCover && itemSales[candidate.ID] < itemSales[best.ID]) {
+			best, bestCover = candidate, cover
+		}
+	}
+	return best
+}
+
+// soldOut reports whether one of a menu item cannot be made from the stock
+// that has not expired. A bundle is sold out when a fixed component is, or
+// when every choice of a group is.
+func soldOut(menuItem models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem, now time.Time) bool {
+	if !menuItem.IsBundle() {
+		return !canMake(menuItem, 1, inventoryMap, now)
+	}
+
+	for _, component := range menuItem.Components {
+		choices := component.Choices
+		if component.Group == "" {
+			choices = []string{component.ProductID}
+		}
+
+		available := false
+		for _, productID := range choices {
+			if componentItem, found := menuItemMap[productID]; found && canMake(componentItem, float64(component.Quantity), inventoryMap, now) {
+				available = true
+				break
+			}
+		}
+		if !available {
+			return true
+		}
+	}
+	return false
+}
+
+// canMake reports whether there is enough usable stock for the given number
+// of a plain menu item.
+func canMake(menuItem models.MenuItem, quantity float64, inventoryMap map[string]models.InventoryItem, now time.Time) bool {
+	usage, err := recipeUsage(menuItem, quantity, inventoryMap)
+	if err != nil {
+		return false
+	}
+	for _, line := range usage {
+		if availableQuantity(inventoryMap[line.IngredientID], now) < roundQuantity(line.Quantity) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"testing"
+	"time"
+)
+
+func TestPickDailyItem(t *testing.T) {
+	today := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	now := today.Add(9 * time.Hour)
+	menuItems := []models.MenuItem{
+		{ID: "cappuccino", Ingredients: []models.MenuItemIngredient{{IngredientID: "milk", Quantity: 100}}},
+		{ID: "espresso", Ingredients: []models.MenuItemIngredient{{IngredientID: "beans", Quantity: 18}}},
+		{ID: "tea"},
+	}
+	menuItemMap := mapMenuItems(menuItems)
+	inventoryMap := map[string]models.InventoryItem{
+		"beans": {IngredientID: "beans", Quantity: 1000, Unit: "g"},
+		"milk":  {IngredientID: "milk", Quantity: 100, Unit: "ml"},
+	}
+	// Yesterday's sales use 18 g of beans and 100 ml of milk a day over
+	// the slow-moving window: 55 days of beans left, but one of milk
+	sales := models.AggregationData{Days: map[string]models.DaySales{
+		"2026-01-14": {Products: map[string]models.ProductSales{
+			"cappuccino": {SalesCount: models.SalesCount{Quantity: 14}},
+			"espresso":   {SalesCount: models.SalesCount{Quantity: 14}},
+		}},
+	}}
+
+	tests := []struct {
+		name      string
+		strategy  string
+		available []models.MenuItem
+		last      string
+		data      models.AggregationData
+		want      string
+		wantErr   bool
+	}{
+		{name: "rotation takes the next item", strategy: models.DailyItemRotation, available: menuItems, last: "cappuccino", want: "espresso"},
+		{name: "rotation wraps around", strategy: models.DailyItemRotation, available: menuItems, last: "tea", want: "cappuccino"},
+		{name: "rotation skips items not available", strategy: models.DailyItemRotation, available: menuItems[:1], last: "cappuccino", want: "cappuccino"},
+		{name: "slow moving takes the longest cover", strategy: models.DailyItemSlowMoving, available: menuItems, data: sales, want: "espresso"},
+		{name: "slow moving without recent sales", strategy: models.DailyItemSlowMoving, available: menuItems, want: "cappuccino"},
+		{name: "nothing available", strategy: models.DailyItemRotation, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available := make(map[string]models.MenuItem)
+			for _, menuItem := range tt.available {
+				available[menuItem.ID] = menuItem
+			}
+			data := tt.data
+			data.DailyItem.ID = tt.last
+
+			item, err := pickDailyItem(tt.strategy, available, menuItemMap, inventoryMap, data, today, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("pickDailyItem(%s) = %s, want an error", tt.strategy, item.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pickDailyItem(%s) failed: %v", tt.strategy, err)
+			}
+			if item.ID != tt.want {
+				t.Errorf("pickDailyItem(%s) = %s, want %s", tt.strategy, item.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyItem(t *testing.T) {
+	useTempData(t)
+	previousStrategy := config.DailyItemStrategy
+	config.DailyItemStrategy = models.DailyItemRotation
+	t.Cleanup(func() { config.DailyItemStrategy = previousStrategy })
+
+	menuRepo, inventoryRepo := &dal.MenuItemService{}, &dal.InventoryItemService{}
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{{IngredientID: "milk", Name: "Milk", Quantity: 100, Unit: "ml"}}); err != nil {
+		t.Fatalf("SaveItem failed: %v", err)
+	}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4, Ingredients: []models.MenuItemIngredient{{IngredientID: "milk", Quantity: 200}}},
+		{ID: "americano", Name: "Americano", Price: 3},
+		{ID: "tea", Name: "Tea", Price: 2},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	service := NewReportService(menuRepo, &dal.AggregationService{}, &dal.OrderService{}, inventoryRepo)
+
+	first, err := service.GetDailyItem()
+	if err != nil {
+		t.Fatalf("GetDailyItem failed: %v", err)
+	}
+	if first.ID != "americano" || first.Strategy != models.DailyItemRotation {
+		t.Errorf("daily item = %s by %s, want americano by %s", first.ID, first.Strategy, models.DailyItemRotation)
+	}
+	// The choice holds for the rest of the day
+	again, err := service.GetDailyItem()
+	if err != nil {
+		t.Fatalf("GetDailyItem failed: %v", err)
+	}
+	if again.ID != first.ID || again.ChosenAt != first.ChosenAt {
+		t.Errorf("daily item chosen again as %s at %s, want %s at %s", again.ID, again.ChosenAt, first.ID, first.ChosenAt)
+	}
+
+	if _, err := service.SetDailyItem(models.DailyItemRequest{ProductID: "latte"}); err == nil {
+		t.Error("SetDailyItem succeeded for a sold out item")
+	}
+	if _, err := service.SetDailyItem(models.DailyItemRequest{ProductID: "tea", Strategy: models.DailyItemRandom}); err == nil {
+		t.Error("SetDailyItem succeeded with both a product and a strategy")
+	}
+	manual, err := service.SetDailyItem(models.DailyItemRequest{ProductID: "tea"})
+	if err != nil {
+		t.Fatalf("SetDailyItem failed: %v", err)
+	}
+	if manual.ID != "tea" || manual.Strategy != models.DailyItemManual {
+		t.Errorf("daily item = %s by %s, want tea by %s", manual.ID, manual.Strategy, models.DailyItemManual)
+	}
+	if current, err := service.GetDailyItem(); err != nil || current.ID != "tea" {
+		t.Errorf("GetDailyItem() = %s, %v, want the manual choice tea", current.ID, err)
+	}
+}
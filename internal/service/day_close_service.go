@@ -0,0 +1,248 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// dayCloseMu is held while a business day is closed, and by every order
+// change from its day check until it is saved, so that no order changes
+// while a Z report is taken.
+var dayCloseMu sync.Mutex
+
+type DayCloseService interface {
+	CloseDay(request models.DayCloseRequest) (models.DayClose, error)
+	GetDayClose(date string) (models.DayClose, error)
+}
+
+type dayCloseService struct {
+	dayCloseRepo  dal.DayCloseRepository
+	orderRepo     dal.OrderRepository
+	menuRepo      dal.MenuRepository
+	inventoryRepo dal.InventoryRepository
+	movementRepo  dal.MovementRepository
+}
+
+func NewDayCloseService(dayCloseRepo dal.DayCloseRepository, orderRepo dal.OrderRepository, menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository, movementRepo dal.MovementRepository) DayCloseService {
+	return &dayCloseService{
+		dayCloseRepo:  dayCloseRepo,
+		orderRepo:     orderRepo,
+		menuRepo:      menuRepo,
+		inventoryRepo: inventoryRepo,
+		movementRepo:  movementRepo,
+	}
+}
+
+// CloseDay takes the Z report of a business day, today unless a date is
+// given, and stores it. From then on the day is frozen. A day can be closed
+// only once and not before it has started.
+func (s *dayCloseService) CloseDay(request models.DayCloseRequest) (models.DayClose, error) {
+	defer utils.CatchCriticalPoint()
+
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.DayClose{}, err
+	}
+
+	now := time.Now().In(loc)
+	date := request.Date
+	if date == "" {
+		date = now.Format("2006-01-02")
+	}
+	start, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return models.DayClose{}, errors.New("invalid date: " + date)
+	}
+	if start.After(now) {
+		return models.DayClose{}, errors.New("business day has not started")
+	}
+	end := start.AddDate(0, 0, 1)
+
+	logging.Info("Attempting to close business day", "date", date)
+
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+
+	orders, err := s.orderRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read orders", err)
+		return models.DayClose{}, err
+	}
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.DayClose{}, err
+	}
+	menuItemMap := mapMenuItems(menuItems)
+
+	dayClose := models.DayClose{
+		Date:           date,
+		TimeZone:       loc.String(),
+		ClosedAt:       now.Format(time.RFC3339),
+		PaymentMethods: []models.PaymentTotal{},
+		Cancelled:      []models.DayCloseOrder{},
+		Voided:         []models.DayCloseOrder{},
+		OpenOrders:     []models.DayCloseOrder{},
+	}
+
+	var sold []models.Order
+	payments := make(map[string]*models.PaymentTotal)
+	for _, order := range orders {
+		switch {
+		case order.Status == "closed" && utils.InDateRange(order.CreatedAt, start, end):
+			sold = append(sold, order)
+
+			revenue := orderRevenue(order, menuItemMap)
+			dayClose.Totals.Revenue += revenue
+			dayClose.Totals.Orders++
+			for _, item := range order.Items {
+				dayClose.Totals.ItemsSold += item.Quantity
+			}
+
+			method := order.PaymentMethod
+			if method == "" {
+				method = "unspecified"
+			}
+			if payments[method] == nil {
+				payments[method] = &models.PaymentTotal{Method: method}
+			}
+			payments[method].Orders++
+			payments[method].Revenue += revenue
+		case order.Status == "cancelled" && utils.InDateRange(order.CancelledAt, start, end):
+			dayClose.Cancelled = append(dayClose.Cancelled, dayCloseOrder(order, menuItemMap))
+		case order.Status == "voided" && utils.InDateRange(order.VoidedAt, start, end):
+			dayClose.Voided = append(dayClose.Voided, dayCloseOrder(order, menuItemMap))
+		case order.Status == "open" && !utils.InDateRange(order.CreatedAt, end, time.Time{}):
+			dayClose.OpenOrders = append(dayClose.OpenOrders, dayCloseOrder(order, menuItemMap))
+		}
+	}
+	finishSalesTotals(&dayClose.Totals)
+
+	products := popularItems(sold, menuItemMap, start, end, "revenue")
+	dayClose.Products = products.Items
+	dayClose.Categories = products.Categories
+
+	for _, payment := range payments {
+		payment.Revenue = roundPrice(payment.Revenue)
+		payment.Share = share(payment.Revenue, dayClose.Totals.Revenue)
+		dayClose.PaymentMethods = append(dayClose.PaymentMethods, *payment)
+	}
+	sort.Slice(dayClose.PaymentMethods, func(i, j int) bool {
+		a, b := dayClose.PaymentMethods[i], dayClose.PaymentMethods[j]
+		if a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+		return a.Method < b.Method
+	})
+
+	dayClose.Inventory, err = s.closingBalances(end)
+	if err != nil {
+		return models.DayClose{}, err
+	}
+
+	if err := s.dayCloseRepo.Append(dayClose); err != nil {
+		logging.Warn("Failed to store day close", "date", date, "error", err)
+		return models.DayClose{}, err
+	}
+
+	logging.Info("Successfully closed business day", "date", date, "orders", dayClose.Totals.Orders, "revenue", dayClose.Totals.Revenue)
+	return dayClose, nil
+}
+
+// GetDayClose returns the stored Z report of a business day.
+func (s *dayCloseService) GetDayClose(date string) (models.DayClose, error) {
+	defer utils.CatchCriticalPoint()
+
+	closes, err := s.dayCloseRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read day closes", err)
+		return models.DayClose{}, err
+	}
+	for _, dayClose := range closes {
+		if dayClose.Date == date {
+			return dayClose, nil
+		}
+	}
+
+	logging.Warn("Day close not found", "date", date)
+	return models.DayClose{}, errors.New("day close not found")
+}
+
+// closingBalances is the stock of every ingredient at the given moment: the
+// current quantity less the ledger movements booked since. Opening balances
+// are stock that was already there, so they are not taken back out.
+func (s *dayCloseService) closingBalances(at time.Time) ([]models.ClosingBalance, error) {
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return nil, err
+	}
+	movements, err := s.movementRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read inventory movements", err)
+		return nil, err
+	}
+
+	since := make(map[string]float64)
+	for _, movement := range movements {
+		if movement.Type != models.MovementOpening && utils.InDateRange(movement.CreatedAt, at, time.Time{}) {
+			since[movement.IngredientID] += movement.Quantity
+		}
+	}
+
+	balances := make([]models.ClosingBalance, 0, len(items))
+	for _, item := range items {
+		balances = append(balances, models.ClosingBalance{
+			IngredientID: item.IngredientID,
+			Name:         item.Name,
+			Quantity:     roundQuantity(item.Quantity - since[item.IngredientID]),
+			Unit:         item.Unit,
+		})
+	}
+	return balances, nil
+}
+
+// dayCloseOrder lists an order on a Z report.
+func dayCloseOrder(order models.Order, menuItemMap map[string]models.MenuItem) models.DayCloseOrder {
+	return models.DayCloseOrder{
+		OrderID:       order.ID,
+		CustomerName:  order.CustomerName,
+		Status:        order.Status,
+		Total:         roundPrice(orderRevenue(order, menuItemMap)),
+		PaymentMethod: order.PaymentMethod,
+		CreatedAt:     order.CreatedAt,
+		CancelledAt:   order.CancelledAt,
+		VoidedAt:      order.VoidedAt,
+	}
+}
+
+// checkDayOpen fails when the business day a moment falls in has been closed.
+// Callers hold dayCloseMu until their change is saved.
+func checkDayOpen(dayCloseRepo dal.DayCloseRepository, at time.Time) error {
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return err
+	}
+	closes, err := dayCloseRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read day closes", err)
+		return err
+	}
+
+	date := at.In(loc).Format("2006-01-02")
+	for _, dayClose := range closes {
+		if dayClose.Date == date {
+			logging.Warn("Business day is closed", "date", date)
+			return errors.New("business day is closed")
+		}
+	}
+	return nil
+}
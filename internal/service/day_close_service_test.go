@@ -0,0 +1,114 @@
+package service
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCloseDay(t *testing.T) {
+	useTempData(t)
+	previousZone := config.TimeZone
+	config.TimeZone = "UTC"
+	t.Cleanup(func() { config.TimeZone = previousZone })
+
+	at := func(day, hour int) string {
+		return time.Date(2026, 1, day, hour, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+	dayCloseRepo, orderRepo, menuRepo := &dal.DayCloseService{}, &dal.OrderService{}, &dal.MenuItemService{}
+	inventoryRepo, movementRepo := &dal.InventoryItemService{}, &dal.MovementService{}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Latte", Price: 4, Category: "coffee"},
+		{ID: "muffin", Name: "Muffin", Price: 3, Category: "bakery"},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	latte := []models.OrderItem{{ProductID: "latte", Quantity: 2}}
+	if err := orderRepo.SaveItems([]models.Order{
+		{ID: "order1", Status: "closed", PaymentMethod: "card", CreatedAt: at(5, 10), Items: latte},
+		{ID: "order2", Status: "closed", CreatedAt: at(5, 12), Items: []models.OrderItem{{ProductID: "muffin", Quantity: 1}}},
+		{ID: "order3", Status: "cancelled", CreatedAt: at(5, 9), CancelledAt: at(5, 13), Items: latte},
+		{ID: "order4", Status: "voided", CreatedAt: at(4, 9), VoidedAt: at(5, 14), Items: latte},
+		{ID: "order5", Status: "open", CreatedAt: at(4, 18), Items: latte},
+		{ID: "order6", Status: "open", CreatedAt: at(6, 9), Items: latte},
+		{ID: "order7", Status: "closed", CreatedAt: at(6, 10), Items: latte},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	// 100 ml of milk went out after the day ended
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{{IngredientID: "milk", Name: "Milk", Quantity: 700, Unit: "ml"}}); err != nil {
+		t.Fatalf("SaveItem failed: %v", err)
+	}
+	if _, err := movementRepo.Append(
+		models.InventoryMovement{IngredientID: "milk", Type: models.MovementOpening, Quantity: 1000, BalanceAfter: 1000, CreatedAt: at(5, 8)},
+		models.InventoryMovement{IngredientID: "milk", Type: models.MovementSale, Quantity: -200, BalanceAfter: 800, CreatedAt: at(5, 10)},
+		models.InventoryMovement{IngredientID: "milk", Type: models.MovementSale, Quantity: -100, BalanceAfter: 700, CreatedAt: at(6, 10)},
+	); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	service := NewDayCloseService(dayCloseRepo, orderRepo, menuRepo, inventoryRepo, movementRepo)
+
+	dayClose, err := service.CloseDay(models.DayCloseRequest{Date: "2026-01-05"})
+	if err != nil {
+		t.Fatalf("CloseDay failed: %v", err)
+	}
+
+	wantTotals := models.SalesTotals{Revenue: 11, Orders: 2, ItemsSold: 3, AverageTicket: 5.5}
+	if dayClose.Totals != wantTotals {
+		t.Errorf("totals = %+v, want %+v", dayClose.Totals, wantTotals)
+	}
+	wantPayments := []models.PaymentTotal{
+		{Method: "card", Orders: 1, Revenue: 8, Share: 72.73},
+		{Method: "unspecified", Orders: 1, Revenue: 3, Share: 27.27},
+	}
+	if !reflect.DeepEqual(dayClose.PaymentMethods, wantPayments) {
+		t.Errorf("payment methods = %+v, want %+v", dayClose.PaymentMethods, wantPayments)
+	}
+	if len(dayClose.Products) != 2 || dayClose.Products[0].ProductID != "latte" || len(dayClose.Categories) != 2 {
+		t.Errorf("products = %+v, categories = %+v, want latte then muffin in two categories", dayClose.Products, dayClose.Categories)
+	}
+	listed := func(orders []models.DayCloseOrder) []string {
+		var ids []string
+		for _, order := range orders {
+			ids = append(ids, order.OrderID)
+		}
+		return ids
+	}
+	if cancelled, voided, open := listed(dayClose.Cancelled), listed(dayClose.Voided), listed(dayClose.OpenOrders); !reflect.DeepEqual(cancelled, []string{"order3"}) ||
+		!reflect.DeepEqual(voided, []string{"order4"}) || !reflect.DeepEqual(open, []string{"order5"}) {
+		t.Errorf("cancelled %v, voided %v, open %v, want order3, order4 and order5", cancelled, voided, open)
+	}
+	wantInventory := []models.ClosingBalance{{IngredientID: "milk", Name: "Milk", Quantity: 800, Unit: "ml"}}
+	if !reflect.DeepEqual(dayClose.Inventory, wantInventory) {
+		t.Errorf("inventory = %+v, want %+v", dayClose.Inventory, wantInventory)
+	}
+
+	stored, err := service.GetDayClose("2026-01-05")
+	if err != nil {
+		t.Fatalf("GetDayClose failed: %v", err)
+	}
+	if !reflect.DeepEqual(stored, dayClose) {
+		t.Errorf("stored day close = %+v, want %+v", stored, dayClose)
+	}
+
+	// The day is frozen once closed
+	if _, err := service.CloseDay(models.DayCloseRequest{Date: "2026-01-05"}); err == nil {
+		t.Error("CloseDay succeeded for a day already closed")
+	}
+	if err := checkDayOpen(dayCloseRepo, time.Date(2026, 1, 5, 15, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("checkDayOpen succeeded for a closed day")
+	}
+	if err := checkDayOpen(dayCloseRepo, time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("checkDayOpen failed for an open day: %v", err)
+	}
+
+	if _, err := service.CloseDay(models.DayCloseRequest{Date: time.Now().AddDate(0, 0, 2).Format("2006-01-02")}); err == nil {
+		t.Error("CloseDay succeeded for a day that has not started")
+	}
+	if _, err := service.CloseDay(models.DayCloseRequest{Date: "05.01.2026"}); err == nil {
+		t.Error("CloseDay succeeded with an invalid date")
+	}
+}
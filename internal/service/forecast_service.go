@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"strings"
+	"time"
+)
+
+type ForecastService interface {
+	GetForecast(historyDays, window, horizonDays int) (models.ForecastReport, error)
+}
+
+type forecastService struct {
+	orderRepo     dal.OrderRepository
+	menuRepo      dal.MenuRepository
+	inventoryRepo dal.InventoryRepository
+}
+
+func NewForecastService(orderRepo dal.OrderRepository, menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository) ForecastService {
+	return &forecastService{
+		orderRepo:     orderRepo,
+		menuRepo:      menuRepo,
+		inventoryRepo: inventoryRepo,
+	}
+}
+
+// GetForecast builds the daily usage of every ingredient over the last
+// historyDays full days from closed orders and current recipes. The forecast
+// for a day is the moving average of the last window days scaled by the
+// weekday's share of usage, so busy weekends stay busy.
+func (s *forecastService) GetForecast(historyDays, window, horizonDays int) (models.ForecastReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Building inventory forecast", "historyDays", historyDays, "window", window, "horizonDays", horizonDays)
+
+	if historyDays <= 0 || window <= 0 || horizonDays <= 0 {
+		return models.ForecastReport{}, errors.New("history, window and horizon must be greater than zero")
+	}
+	if window > historyDays {
+		return models.ForecastReport{}, errors.New("window cannot be longer than the history")
+	}
+
+	loc, err := utils.BusinessLocation()
+	if err != nil {
+		logging.Error("Failed to load business time zone", err)
+		return models.ForecastReport{}, err
+	}
+	orders, err := s.orderRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read orders", err)
+		return models.ForecastReport{}, err
+	}
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return models.ForecastReport{}, err
+	}
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return models.ForecastReport{}, err
+	}
+	menuItemMap := mapMenuItems(menuItems)
+	inventoryMap := mapInventoryItems(items)
+
+	// History covers full days, today is forecast
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	start := today.AddDate(0, 0, -historyDays)
+
+	daily := make(map[string][]float64)
+	for _, order := range orders {
+		if order.Status != "closed" {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, order.CreatedAt)
+		if err != nil || createdAt.Before(start) || !createdAt.Before(today) {
+			continue
+		}
+		created := createdAt.In(loc)
+		day := int(time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, loc).Sub(start).Hours()/24 + 0.5)
+
+		usage, err := orderUsage(order, menuItemMap, inventoryMap)
+		if err != nil {
+			// Recipes may have changed since; skip what can no longer be resolved
+			logging.Warn("Skipping order in forecast", "orderID", order.ID, "error", err)
+			continue
+		}
+		for _, line := range usage {
+			if daily[line.IngredientID] == nil {
+				daily[line.IngredientID] = make([]float64, historyDays)
+			}
+			daily[line.IngredientID][day] += line.Quantity
+		}
+	}
+
+	report := models.ForecastReport{
+		GeneratedAt:  now.Format(time.RFC3339),
+		HistoryDays:  historyDays,
+		Window:       window,
+		HorizonDays:  horizonDays,
+		Ingredients:  []models.IngredientForecast{},
+		PurchaseList: []models.PurchaseSuggestion{},
+	}
+	for _, item := range items {
+		usage := daily[item.IngredientID]
+		if usage == nil {
+			usage = make([]float64, historyDays)
+		}
+
+		forecast := forecastIngredient(item, usage, start, today, window, horizonDays, now)
+		report.Ingredients = append(report.Ingredients, forecast)
+
+		if suggestion, needed := suggestPurchase(item, forecast); needed {
+			report.PurchaseList = append(report.PurchaseList, suggestion)
+			report.EstimatedTotal += suggestion.EstimatedCost
+		}
+	}
+	report.EstimatedTotal = roundPrice(report.EstimatedTotal)
+
+	logging.Info("Built inventory forecast", "ingredients", len(report.Ingredients), "purchases", len(report.PurchaseList))
+	return report, nil
+}
+
+// forecastIngredient projects one ingredient's usage from its daily history,
+// which starts on the given day.
+func forecastIngredient(item models.InventoryItem, usage []float64, start, today time.Time, window, horizonDays int, now time.Time) models.IngredientForecast {
+	var total, recent float64
+	var weekdayTotals, weekdayDays [7]float64
+	for day, quantity := range usage {
+		weekday := start.AddDate(0, 0, day).Weekday()
+		weekdayTotals[weekday] += quantity
+		weekdayDays[weekday]++
+		total += quantity
+		if day >= len(usage)-window {
+			recent += quantity
+		}
+	}
+	average := total / float64(len(usage))
+	movingAverage := recent / float64(window)
+
+	// A weekday's factor is its average usage against the overall average
+	factors := make(map[string]float64)
+	var factor [7]float64
+	for weekday := range factor {
+		factor[weekday] = 1
+		if average > 0 && weekdayDays[weekday] > 0 {
+			factor[weekday] = weekdayTotals[weekday] / weekdayDays[weekday] / average
+		}
+		factors[strings.ToLower(time.Weekday(weekday).String())] = roundQuantity(factor[weekday])
+	}
+
+	forecast := models.IngredientForecast{
+		IngredientID:      item.IngredientID,
+		Name:              item.Name,
+		Unit:              item.Unit,
+		AvailableQuantity: availableQuantity(item, now),
+		MovingAverage:     roundQuantity(movingAverage),
+		WeekdayFactors:    factors,
+		Forecast:          []models.DailyForecast{},
+	}
+
+	for day := 0; day < horizonDays; day++ {
+		date := today.AddDate(0, 0, day)
+		quantity := movingAverage * factor[date.Weekday()]
+		forecast.Forecast = append(forecast.Forecast, models.DailyForecast{
+			Date:     date.Format("2006-01-02"),
+			Quantity: roundQuantity(quantity),
+		})
+		forecast.HorizonUsage += quantity
+	}
+	forecast.HorizonUsage = roundQuantity(forecast.HorizonUsage)
+
+	// Walk the forecast forward, repeating weeks past the horizon, until the
+	// stock runs out; an ingredient that is not used never runs out
+	if movingAverage > 0 {
+		remaining := forecast.AvailableQuantity
+		for day := 0; day < 366; day++ {
+			date := today.AddDate(0, 0, day)
+			quantity := movingAverage * factor[date.Weekday()]
+			if quantity > 0 && remaining < quantity {
+				days := roundPrice(float64(day) + remaining/quantity)
+				forecast.DaysUntilStockout = &days
+				forecast.StockoutDate = date.Format("2006-01-02")
+				break
+			}
+			remaining -= quantity
+		}
+	}
+
+	return forecast
+}
+
+// suggestPurchase tops an ingredient up to cover the horizon plus its reorder
+// point, ordering at least its reorder quantity.
+func suggestPurchase(item models.InventoryItem, forecast models.IngredientForecast) (models.PurchaseSuggestion, bool) {
+	needed := forecast.HorizonUsage + item.ReorderPoint - forecast.AvailableQuantity
+	if needed <= 0 {
+		return models.PurchaseSuggestion{}, false
+	}
+
+	quantity := math.Max(math.Ceil(needed), item.ReorderQuantity)
+	return models.PurchaseSuggestion{
+		IngredientID:  item.IngredientID,
+		Name:          item.Name,
+		Unit:          item.Unit,
+		Quantity:      quantity,
+		UnitCost:      item.UnitCost,
+		EstimatedCost: roundPrice(quantity * item.UnitCost),
+	}, true
+}
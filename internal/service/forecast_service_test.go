@@ -0,0 +1,89 @@
+package service
+
+import (
+	"hot-coffee/models"
+	"testing"
+	"time"
+)
+
+func TestForecastIngredient(t *testing.T) {
+	// Two weeks from a Monday: one a day on weekdays, three on weekends
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	today := start.AddDate(0, 0, 14)
+	week := []float64{1, 1, 1, 1, 1, 3, 3}
+	weekly := append(append([]float64{}, week...), week...)
+
+	tests := []struct {
+		name          string
+		quantity      float64
+		usage         []float64
+		movingAverage float64
+		factors       map[string]float64
+		horizonUsage  float64
+		stockoutDays  *float64
+		stockoutDate  string
+	}{
+		{
+			name:          "weekly pattern runs out on thursday",
+			quantity:      3.5,
+			usage:         weekly,
+			movingAverage: 1.5714,
+			factors:       map[string]float64{"monday": 0.6364, "friday": 0.6364, "saturday": 1.9091, "sunday": 1.9091},
+			horizonUsage:  11,
+			stockoutDays:  floatPointer(3.5),
+			stockoutDate:  "2026-01-22",
+		},
+		{
+			name:          "weekend usage spans the stock",
+			quantity:      7,
+			usage:         weekly,
+			movingAverage: 1.5714,
+			factors:       map[string]float64{"monday": 0.6364, "saturday": 1.9091},
+			horizonUsage:  11,
+			stockoutDays:  floatPointer(5.67),
+			stockoutDate:  "2026-01-24",
+		},
+		{
+			name:          "unused ingredient never runs out",
+			quantity:      10,
+			usage:         make([]float64, 14),
+			movingAverage: 0,
+			factors:       map[string]float64{"monday": 1, "saturday": 1},
+			horizonUsage:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := models.InventoryItem{IngredientID: "milk", Quantity: tt.quantity}
+			forecast := forecastIngredient(item, tt.usage, start, today, 7, 7, today)
+
+			if forecast.MovingAverage != tt.movingAverage {
+				t.Errorf("moving average = %v, want %v", forecast.MovingAverage, tt.movingAverage)
+			}
+			for weekday, factor := range tt.factors {
+				if forecast.WeekdayFactors[weekday] != factor {
+					t.Errorf("%s factor = %v, want %v", weekday, forecast.WeekdayFactors[weekday], factor)
+				}
+			}
+			if forecast.HorizonUsage != tt.horizonUsage {
+				t.Errorf("horizon usage = %v, want %v", forecast.HorizonUsage, tt.horizonUsage)
+			}
+			switch {
+			case tt.stockoutDays == nil && forecast.DaysUntilStockout != nil:
+				t.Errorf("days until stockout = %v, want none", *forecast.DaysUntilStockout)
+			case tt.stockoutDays != nil && forecast.DaysUntilStockout == nil:
+				t.Errorf("days until stockout = none, want %v", *tt.stockoutDays)
+			case tt.stockoutDays != nil && *forecast.DaysUntilStockout != *tt.stockoutDays:
+				t.Errorf("days until stockout = %v, want %v", *forecast.DaysUntilStockout, *tt.stockoutDays)
+			}
+			if forecast.StockoutDate != tt.stockoutDate {
+				t.Errorf("stockout date = %q, want %q", forecast.StockoutDate, tt.stockoutDate)
+			}
+		})
+	}
+}
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
@@ -1,11 +1,17 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"hot-coffee/internal/dal"
 	"hot-coffee/models"
 	"hot-coffee/utils"
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"hot-coffee/logging" // Import the logging package
 )
@@ -15,16 +21,28 @@ type InventoryService interface {
 	GetAllInventoryItems() ([]models.InventoryItem, error)
 	GetInventoryItemByID(id string) (models.InventoryItem, error)
 	UpdateInventoryItem(id string, item models.InventoryItem) error
+	PatchInventoryItem(id string, patch map[string]interface{}) (models.InventoryItem, error)
 	DeleteInventoryItem(id string) error
+	GetLowStockAlerts() ([]models.StockAlert, error)
+	ReceiveStock(receipt models.GoodsReceipt) (models.GoodsReceipt, error)
+	GetReceipts(from, to time.Time) ([]models.GoodsReceipt, error)
+	GetReceiptByID(id string) (models.GoodsReceipt, error)
+	GetExpiryReport(withinDays int) (models.ExpiryReport, error)
 }
 
 type inventoryService struct {
 	inventoryRepo dal.InventoryRepository
+	movementRepo  dal.MovementRepository
+	receiptRepo   dal.ReceiptRepository
+	menuRepo      dal.MenuRepository
 }
 
-func NewInventoryService(inventoryRepo dal.InventoryRepository) InventoryService {
+func NewInventoryService(inventoryRepo dal.InventoryRepository, movementRepo dal.MovementRepository, receiptRepo dal.ReceiptRepository, menuRepo dal.MenuRepository) InventoryService {
 	return &inventoryService{
 		inventoryRepo: inventoryRepo,
+		movementRepo:  movementRepo,
+		receiptRepo:   receiptRepo,
+		menuRepo:      menuRepo,
 	}
 }
 
@@ -33,35 +51,61 @@ func (s *inventoryService) AddInventoryItem(item models.InventoryItem) error {
 
 	// Log adding inventory item
 	logging.Info("Attempting to add inventory item", "ingredientID", item.IngredientID)
+	item.Allergens = normalizeAllergens(item.Allergens)
 
-	// Read existing items
-	items, err := s.inventoryRepo.ReadItem()
-	if err != nil {
-		logging.Error("Failed to read inventory items", err)
-		return err
+	if err := utils.ValidateUpdatedInventoryItem(item); err != nil {
+		logging.Warn("Invalid create inventory item data", "error", err)
+		return fmt.Errorf("invalid inventory item: %v", err)
 	}
 
-	// Check for duplicate ingredientID
-	for _, existingItem := range items {
-		if existingItem.IngredientID == item.IngredientID {
-			logging.Warn("Inventory item with IngredientID already exists", "ingredientID", item.IngredientID)
-			return errors.New("inventory item with this IngredientID already exists")
-		}
+	// Lots only come from goods receipts
+	item.Lots = nil
+	item.AvailableQuantity = nil
+
+	// Start the cost history with the initial unit cost
+	item.CostHistory = nil
+	if item.UnitCost > 0 {
+		item.CostHistory = []models.CostChange{newCostChange(item.UnitCost, "created")}
 	}
-	if err := utils.ValidateUpdatedInventoryItem(item); err != nil {
-		logging.Warn("Invalid create inventory item data", "error", err)
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
 		return err
 	}
-	// Add the new item to the list
-	items = append(items, item)
 
-	// Save updated items
-	err = s.inventoryRepo.SaveItem(items)
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		// Check for duplicate ingredientID
+		for _, existingItem := range items {
+			if existingItem.IngredientID == item.IngredientID {
+				logging.Warn("Inventory item with IngredientID already exists", "ingredientID", item.IngredientID)
+				return nil, errors.New("inventory item with this IngredientID already exists")
+			}
+		}
+
+		// A re-added ingredient must convert to the recipes still using it
+		inventoryMap := mapInventoryItems(items)
+		inventoryMap[item.IngredientID] = item
+		if err := checkRecipeUnits(menuItems, item.IngredientID, inventoryMap); err != nil {
+			return nil, err
+		}
+
+		if item.Quantity > 0 {
+			if err := recordMovements(s.movementRepo, newMovement(item.IngredientID, models.MovementRestock, item.Quantity, item.Quantity, "initial stock")); err != nil {
+				return nil, err
+			}
+		}
+
+		// Add the new item to the list
+		return append(items, item), nil
+	})
 	if err != nil {
-		logging.Error("Failed to save updated inventory", err)
+		logging.Error("Failed to add inventory item", err, "ingredientID", item.IngredientID)
 		return err
 	}
 
+	checkReorderPoints(nil, []models.InventoryItem{item})
+
 	// Log success
 	logging.Info("Successfully added inventory item", "ingredientID", item.IngredientID)
 	return nil
@@ -79,6 +123,8 @@ func (s *inventoryService) GetAllInventoryItems() ([]models.InventoryItem, error
 		return nil, err
 	}
 
+	withAvailability(items, time.Now())
+
 	logging.Info("Fetched all inventory items", "count", len(items))
 	return items, nil
 }
@@ -96,6 +142,7 @@ func (s *inventoryService) GetInventoryItemByID(id string) (models.InventoryItem
 	}
 
 	// Search for the item by ID
+	withAvailability(items, time.Now())
 	for _, item := range items {
 		if item.IngredientID == id {
 			logging.Info("Found inventory item", "ingredientID", id)
@@ -111,39 +158,214 @@ func (s *inventoryService) UpdateInventoryItem(id string, updatedItem models.Inv
 	defer utils.CatchCriticalPoint()
 
 	logging.Info("Attempting to update inventory item", "ingredientID", id)
+	updatedItem.Allergens = normalizeAllergens(updatedItem.Allergens)
 
 	// Validate the updated inventory item before proceeding
 	if err := utils.ValidateUpdatedInventoryItem(updatedItem); err != nil {
 		logging.Warn("Invalid updated inventory item data", "ingredientID", id, "error", err)
-		return err
+		return fmt.Errorf("invalid inventory item: %v", err)
 	}
 
-	// Fetch all items from the inventory
-	items, err := s.inventoryRepo.ReadItem()
+	menuItems, err := s.menuRepo.ReadItems()
 	if err != nil {
-		logging.Error("Failed to fetch inventory items", err)
+		logging.Error("Failed to fetch menu items", err)
 		return err
 	}
 
-	// Look for the item to update
-	for i, item := range items {
-		if item.IngredientID == id {
+	var before models.InventoryItem
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		// Look for the item to update
+		for i, item := range items {
+			if item.IngredientID != id {
+				continue
+			}
+
+			// The quantity, lots and costs are all kept in the stock unit
+			if updatedItem.Unit != item.Unit {
+				return nil, errors.New("unit cannot be changed")
+			}
+
+			// The cost history is kept by the server, record a new entry when the cost changes
+			updatedItem.CostHistory = item.CostHistory
+			if updatedItem.UnitCost != item.UnitCost {
+				updatedItem.CostHistory = append(updatedItem.CostHistory, newCostChange(updatedItem.UnitCost, "manual update"))
+			}
+			// Lots are kept by the server too, a lower quantity shrinks them
+			updatedItem.Lots = item.Lots
+			updatedItem.AvailableQuantity = nil
+			trimLots(&updatedItem)
+
+			// The recipes using the ingredient must still convert with its custom units
+			inventoryMap := mapInventoryItems(items)
+			inventoryMap[id] = updatedItem
+			if err := checkRecipeUnits(menuItems, id, inventoryMap); err != nil {
+				return nil, err
+			}
+
+			if delta := updatedItem.Quantity - item.Quantity; delta != 0 {
+				if err := recordMovements(s.movementRepo, newMovement(id, models.MovementAdjustment, delta, updatedItem.Quantity, "manual update")); err != nil {
+					return nil, err
+				}
+			}
+
 			// Update the item with the new data
+			before = item
 			items[i] = updatedItem
-			err := s.inventoryRepo.SaveItem(items)
+			return items, nil
+		}
+		return nil, errors.New("inventory item not found")
+	})
+	if err != nil {
+		logging.Warn("Failed to update inventory item", "ingredientID", id, "error", err)
+		return err
+	}
+	checkReorderPoints([]models.InventoryItem{before}, []models.InventoryItem{updatedItem})
+
+	// Log success
+	logging.Info("Successfully updated inventory item", "ingredientID", id)
+	return nil
+}
+
+// PatchInventoryItem changes an item under the inventory lock. An "adjust"
+// member moves the quantity by a relative amount, booked with the given
+// "reason"; every other member is applied as a JSON Merge Patch. The unit
+// cannot be patched: the quantity, lots and costs are all kept in it.
+func (s *inventoryService) PatchInventoryItem(id string, patch map[string]interface{}) (models.InventoryItem, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to patch inventory item", "ingredientID", id)
+
+	// Pull the adjustment out of the patch; what is left is a merge patch
+	var adjust float64
+	adjustValue, hasAdjust := patch["adjust"]
+	if hasAdjust {
+		number, ok := adjustValue.(float64)
+		if !ok || number == 0 {
+			return models.InventoryItem{}, errors.New("adjust must be a non-zero number")
+		}
+		adjust = number
+	}
+	reason := "manual adjustment"
+	if value, ok := patch["reason"]; ok {
+		text, isString := value.(string)
+		if !isString {
+			return models.InventoryItem{}, errors.New("reason must be a string")
+		}
+		if strings.TrimSpace(text) != "" {
+			reason = text
+		}
+	}
+	fields := make(map[string]interface{})
+	for key, value := range patch {
+		if key != "adjust" && key != "reason" {
+			fields[key] = value
+		}
+	}
+	for _, key := range []string{"ingredient_id", "unit", "cost_history", "lots", "available_quantity"} {
+		if _, ok := fields[key]; ok {
+			return models.InventoryItem{}, errors.New(key + " cannot be patched")
+		}
+	}
+	if _, ok := fields["quantity"]; ok && hasAdjust {
+		return models.InventoryItem{}, errors.New("quantity and adjust cannot be patched together")
+	}
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.InventoryItem{}, err
+	}
+
+	var before, patched models.InventoryItem
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		for i, item := range items {
+			if item.IngredientID != id {
+				continue
+			}
+
+			updated, err := mergeInventoryItem(item, fields)
 			if err != nil {
-				logging.Error("Failed to save updated inventory", err)
-				return err
+				return nil, err
+			}
+			updated.Allergens = normalizeAllergens(updated.Allergens)
+			if err := utils.ValidateUpdatedInventoryItem(updated); err != nil {
+				return nil, err
+			}
+			if updated.UnitCost != item.UnitCost {
+				updated.CostHistory = append(updated.CostHistory, newCostChange(updated.UnitCost, "manual update"))
+			}
+			trimLots(&updated)
+
+			// Custom units may be patched, as long as the recipes still convert
+			inventoryMap := mapInventoryItems(items)
+			inventoryMap[id] = updated
+			if err := checkRecipeUnits(menuItems, id, inventoryMap); err != nil {
+				return nil, err
+			}
+
+			if adjust < 0 {
+				if updated.Quantity < roundQuantity(-adjust) {
+					return nil, errors.New("insufficient inventory for ingredient: " + id)
+				}
+				discardLots(&updated, -adjust)
+			} else if adjust > 0 {
+				updated.Quantity = roundQuantity(updated.Quantity + adjust)
+			}
+
+			var movement models.InventoryMovement
+			if hasAdjust {
+				movement = newMovement(id, models.MovementAdjustment, adjust, updated.Quantity, reason)
+			} else if delta := updated.Quantity - item.Quantity; delta != 0 {
+				movement = newMovement(id, models.MovementAdjustment, delta, updated.Quantity, "manual update")
+			}
+			if movement.Type != "" {
+				if err := recordMovements(s.movementRepo, movement); err != nil {
+					return nil, err
+				}
 			}
 
-			// Log success
-			logging.Info("Successfully updated inventory item", "ingredientID", id)
-			return nil
+			before = item
+			patched = updated
+			items[i] = updated
+			return items, nil
 		}
+		return nil, errors.New("inventory item not found")
+	})
+	if err != nil {
+		logging.Warn("Failed to patch inventory item", "ingredientID", id, "error", err)
+		return models.InventoryItem{}, err
+	}
+
+	checkReorderPoints([]models.InventoryItem{before}, []models.InventoryItem{patched})
+
+	logging.Info("Successfully patched inventory item", "ingredientID", id)
+	return patched, nil
+}
+
+// mergeInventoryItem applies a merge patch to an item through its JSON form.
+// Unknown members are rejected rather than silently dropped.
+func mergeInventoryItem(item models.InventoryItem, fields map[string]interface{}) (models.InventoryItem, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return models.InventoryItem{}, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return models.InventoryItem{}, err
 	}
 
-	logging.Warn("Inventory item not found for update", "ingredientID", id)
-	return errors.New("inventory item not found")
+	merged, err := json.Marshal(utils.MergePatch(document, fields))
+	if err != nil {
+		return models.InventoryItem{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+
+	var updated models.InventoryItem
+	if err := decoder.Decode(&updated); err != nil {
+		return models.InventoryItem{}, errors.New("invalid patch: " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return updated, nil
 }
 
 func (s *inventoryService) DeleteInventoryItem(id string) error {
@@ -151,39 +373,357 @@ func (s *inventoryService) DeleteInventoryItem(id string) error {
 
 	logging.Info("Attempting to delete inventory item", "ingredientID", id)
 
-	// Fetch all items
-	items, err := s.inventoryRepo.ReadItem()
+	menuItems, err := s.menuRepo.ReadItems()
 	if err != nil {
-		logging.Error("Failed to fetch inventory items", err)
+		logging.Error("Failed to fetch menu items", err)
 		return err
 	}
 
-	if len(items) == 0 {
-		logging.Warn("Inventory is empty, cannot delete item", "ingredientID", id)
-		return errors.New("inventory is empty")
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		if len(items) == 0 {
+			logging.Warn("Inventory is empty, cannot delete item", "ingredientID", id)
+			return nil, errors.New("inventory is empty")
+		}
+
+		// Create a new list excluding the item to be deleted
+		var updatedItems []models.InventoryItem
+		var removed []models.InventoryMovement
+		for _, item := range items {
+			if strings.EqualFold(item.IngredientID, id) {
+				if item.Quantity != 0 {
+					removed = append(removed, newMovement(item.IngredientID, models.MovementAdjustment, -item.Quantity, 0, "item deleted"))
+				}
+				continue
+			}
+			updatedItems = append(updatedItems, item)
+		}
+
+		if len(updatedItems) == len(items) {
+			logging.Warn("Inventory item not found for deletion", "ingredientID", id)
+			return nil, errors.New("inventory item not found")
+		}
+
+		// Recipes cannot lose an ingredient
+		for _, menuItem := range menuItems {
+			for _, ingredient := range menuItem.Ingredients {
+				if strings.EqualFold(ingredient.IngredientID, id) {
+					logging.Warn("Inventory item is used by a recipe", "ingredientID", id, "itemID", menuItem.ID)
+					return nil, errors.New("inventory item is used by menu item " + menuItem.ID)
+				}
+			}
+		}
+		if err := recordMovements(s.movementRepo, removed...); err != nil {
+			return nil, err
+		}
+		return updatedItems, nil
+	})
+	if err != nil {
+		logging.Error("Failed to delete inventory item", err, "ingredientID", id)
+		return err
 	}
 
-	// Create a new list excluding the item to be deleted
-	var updatedItems []models.InventoryItem
+	logging.Info("Successfully deleted inventory item", "ingredientID", id)
+	return nil
+}
+
+// GetLowStockAlerts lists the ingredients currently at or below their
+// reorder point.
+func (s *inventoryService) GetLowStockAlerts() ([]models.StockAlert, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching low stock alerts")
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return nil, err
+	}
+
+	alerts := []models.StockAlert{}
 	for _, item := range items {
-		if strings.EqualFold(item.IngredientID, id) {
-			continue
+		if isLowStock(item) {
+			alerts = append(alerts, newStockAlert(item))
 		}
-		updatedItems = append(updatedItems, item)
 	}
 
-	if len(updatedItems) == len(items) {
-		logging.Warn("Inventory item not found for deletion", "ingredientID", id)
-		return errors.New("inventory item not found")
+	logging.Info("Fetched low stock alerts", "count", len(alerts))
+	return alerts, nil
+}
+
+// ReceiveStock books a delivery: every line is added to its ingredient's
+// quantity and folded into the weighted average unit cost. All lines are
+// applied together under the inventory lock, or none are.
+func (s *inventoryService) ReceiveStock(receipt models.GoodsReceipt) (models.GoodsReceipt, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to receive stock", "supplier", receipt.Supplier, "lines", len(receipt.Lines))
+
+	if err := utils.ValidateGoodsReceipt(receipt); err != nil {
+		logging.Warn("Invalid goods receipt data", "error", err)
+		return models.GoodsReceipt{}, err
 	}
 
-	// Save the updated list
-	err = s.inventoryRepo.SaveItem(updatedItems)
+	// The receipt is numbered and stored under the receipt lock, and the stock
+	// booked under the inventory lock within it
+	var before, after []models.InventoryItem
+	err := s.receiptRepo.UpdateItems(func(receipts []models.GoodsReceipt) ([]models.GoodsReceipt, error) {
+		var ids []string
+		for _, existing := range receipts {
+			ids = append(ids, existing.ID)
+		}
+		receipt.ID = utils.NextID("receipt", ids)
+		receipt.ReceivedAt = time.Now().Format(time.RFC3339)
+
+		var err error
+		before, after, err = s.bookReceipt(&receipt)
+		if err != nil {
+			return nil, err
+		}
+		return append(receipts, receipt), nil
+	})
 	if err != nil {
-		logging.Error("Failed to save updated inventory after deletion", err)
-		return err
+		logging.Error("Failed to receive stock", err, "supplier", receipt.Supplier)
+		return models.GoodsReceipt{}, err
 	}
+	checkReorderPoints(before, after)
 
-	logging.Info("Successfully deleted inventory item", "ingredientID", id)
+	logging.Info("Successfully received stock", "receiptID", receipt.ID, "supplier", receipt.Supplier)
+	return receipt, nil
+}
+
+// bookReceipt adds the lines of a receipt to stock under the inventory lock,
+// keeping the quantities before and after for the reorder point check.
+func (s *inventoryService) bookReceipt(receipt *models.GoodsReceipt) (before, after []models.InventoryItem, err error) {
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		before = append([]models.InventoryItem(nil), items...)
+
+		for i := range receipt.Lines {
+			line := &receipt.Lines[i]
+			index := -1
+			for j := range items {
+				if items[j].IngredientID == line.IngredientID {
+					index = j
+					break
+				}
+			}
+			if index < 0 {
+				logging.Warn("Ingredient not found in inventory", "ingredientID", line.IngredientID)
+				return nil, errors.New("ingredient not found in inventory: " + line.IngredientID)
+			}
+			item := &items[index]
+
+			// Deliveries may be counted in another unit than the stock, e.g. kg of flour stocked in g
+			line.StockQuantity = line.Quantity
+			if line.Unit != "" {
+				line.StockQuantity, err = utils.ConvertQuantity(line.Quantity, line.Unit, item.Unit, item.CustomUnits)
+				if err != nil {
+					logging.Warn("Failed to convert received unit", "ingredientID", line.IngredientID, "error", err)
+					return nil, err
+				}
+			}
+			line.StockQuantity = roundQuantity(line.StockQuantity)
+			if line.StockQuantity <= 0 {
+				return nil, errors.New("received quantity is too small: " + line.IngredientID)
+			}
+			line.StockUnitCost = roundQuantity(line.UnitCost * line.Quantity / line.StockQuantity)
+
+			// A line without a cost only adds stock
+			if line.UnitCost > 0 {
+				unitCost := weightedUnitCost(item.Quantity, item.UnitCost, line.StockQuantity, line.StockUnitCost)
+				if unitCost != item.UnitCost {
+					item.UnitCost = unitCost
+					item.CostHistory = append(item.CostHistory, newCostChange(unitCost, "receipt "+receipt.ID))
+				}
+			}
+			item.Quantity = roundQuantity(item.Quantity + line.StockQuantity)
+
+			var lotIDs []string
+			for _, lot := range item.Lots {
+				lotIDs = append(lotIDs, lot.LotID)
+			}
+			line.LotID = utils.NextID("lot", lotIDs)
+			item.Lots = append(item.Lots, models.StockLot{
+				LotID:      line.LotID,
+				Quantity:   line.StockQuantity,
+				ReceivedAt: receipt.ReceivedAt,
+				ExpiresAt:  line.ExpiresAt,
+				ReceiptID:  receipt.ID,
+			})
+		}
+
+		// Restock movements carry the balance after the whole receipt
+		inventoryMap := mapInventoryItems(items)
+		reason := "receipt " + receipt.ID + " from " + receipt.Supplier
+		var movements []models.InventoryMovement
+		for _, line := range receipt.Lines {
+			movements = append(movements, newMovement(line.IngredientID, models.MovementRestock, line.StockQuantity, inventoryMap[line.IngredientID].Quantity, reason))
+		}
+		if err := recordMovements(s.movementRepo, movements...); err != nil {
+			return nil, err
+		}
+
+		after = items
+		return items, nil
+	})
+	return before, after, err
+}
+
+// GetReceipts lists the goods receipts received within the date range.
+func (s *inventoryService) GetReceipts(from, to time.Time) ([]models.GoodsReceipt, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching goods receipts")
+
+	receipts, err := s.receiptRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read goods receipts", err)
+		return nil, err
+	}
+
+	filtered := []models.GoodsReceipt{}
+	for _, receipt := range receipts {
+		if utils.InDateRange(receipt.ReceivedAt, from, to) {
+			filtered = append(filtered, receipt)
+		}
+	}
+
+	logging.Info("Fetched goods receipts", "count", len(filtered))
+	return filtered, nil
+}
+
+func (s *inventoryService) GetReceiptByID(id string) (models.GoodsReceipt, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching goods receipt by ID", "receiptID", id)
+
+	receipts, err := s.receiptRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read goods receipts", err)
+		return models.GoodsReceipt{}, err
+	}
+
+	for _, receipt := range receipts {
+		if receipt.ID == id {
+			return receipt, nil
+		}
+	}
+
+	logging.Warn("Goods receipt not found", "receiptID", id)
+	return models.GoodsReceipt{}, errors.New("goods receipt not found")
+}
+
+// GetExpiryReport lists the lots that have expired, and those expiring
+// within the given number of days, soonest first.
+func (s *inventoryService) GetExpiryReport(withinDays int) (models.ExpiryReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Building expiry report", "withinDays", withinDays)
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return models.ExpiryReport{}, err
+	}
+
+	now := time.Now()
+	report := models.ExpiryReport{
+		GeneratedAt: now.Format(time.RFC3339),
+		WithinDays:  withinDays,
+		Expired:     []models.ExpiringLot{},
+		Expiring:    []models.ExpiringLot{},
+	}
+	expiries := make(map[string]time.Time)
+	for _, item := range items {
+		for _, lot := range item.Lots {
+			if lot.ExpiresAt == "" {
+				continue
+			}
+			expiry, err := utils.ParseExpiry(lot.ExpiresAt)
+			if err != nil {
+				logging.Warn("Invalid lot expiry date", "ingredientID", item.IngredientID, "lotID", lot.LotID)
+				continue
+			}
+
+			entry := models.ExpiringLot{
+				IngredientID: item.IngredientID,
+				Name:         item.Name,
+				LotID:        lot.LotID,
+				Quantity:     lot.Quantity,
+				Unit:         item.Unit,
+				ReceivedAt:   lot.ReceivedAt,
+				ExpiresAt:    lot.ExpiresAt,
+				DaysLeft:     int(math.Floor(expiry.Sub(now).Hours() / 24)),
+				Value:        roundPrice(lot.Quantity * item.UnitCost),
+			}
+			expiries[item.IngredientID+"/"+lot.LotID] = expiry
+
+			switch {
+			case !now.Before(expiry):
+				report.Expired = append(report.Expired, entry)
+				report.ExpiredValue += entry.Value
+			case entry.DaysLeft < withinDays:
+				report.Expiring = append(report.Expiring, entry)
+				report.ExpiringValue += entry.Value
+			}
+		}
+	}
+
+	for _, lots := range [][]models.ExpiringLot{report.Expired, report.Expiring} {
+		sort.SliceStable(lots, func(i, j int) bool {
+			return expiries[lots[i].IngredientID+"/"+lots[i].LotID].Before(expiries[lots[j].IngredientID+"/"+lots[j].LotID])
+		})
+	}
+	report.ExpiredValue = roundPrice(report.ExpiredValue)
+	report.ExpiringValue = roundPrice(report.ExpiringValue)
+
+	logging.Info("Built expiry report", "expired", len(report.Expired), "expiring", len(report.Expiring))
+	return report, nil
+}
+
+// weightedUnitCost folds received stock into the weighted average unit cost
+// of the stock on hand. Stock without a known cost takes the new one.
+func weightedUnitCost(quantity, unitCost, received, receivedUnitCost float64) float64 {
+	if quantity <= 0 || unitCost <= 0 {
+		return receivedUnitCost
+	}
+	return roundQuantity((quantity*unitCost + received*receivedUnitCost) / (quantity + received))
+}
+
+// newCostChange stamps a unit cost change with the current time.
+func newCostChange(unitCost float64, source string) models.CostChange {
+	return models.CostChange{
+		UnitCost:  unitCost,
+		ChangedAt: time.Now().Format(time.RFC3339),
+		Source:    source,
+	}
+}
+
+// normalizeAllergens lower-cases allergen codes and drops blanks.
+func normalizeAllergens(allergens []string) []string {
+	var normalized []string
+	for _, allergen := range allergens {
+		allergen = strings.ToLower(strings.TrimSpace(allergen))
+		if allergen != "" {
+			normalized = append(normalized, allergen)
+		}
+	}
+	return mergeAllergens(nil, normalized)
+}
+
+// checkRecipeUnits validates the recipes of the menu items using an
+// ingredient against the inventory.
+func checkRecipeUnits(menuItems []models.MenuItem, ingredientID string, inventoryMap map[string]models.InventoryItem) error {
+	for _, menuItem := range menuItems {
+		for _, ingredient := range menuItem.Ingredients {
+			if ingredient.IngredientID != ingredientID {
+				continue
+			}
+			if err := validateRecipeUnits(menuItem, inventoryMap); err != nil {
+				logging.Warn("Unit change breaks recipe", "ingredientID", ingredientID, "itemID", menuItem.ID, "error", err)
+				return fmt.Errorf("unit change breaks the recipe of menu item %s: %v", menuItem.ID, err)
+			}
+			break
+		}
+	}
 	return nil
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"sync"
+	"testing"
+)
+
+func TestWeightedUnitCost(t *testing.T) {
+	tests := []struct {
+		name             string
+		quantity         float64
+		unitCost         float64
+		received         float64
+		receivedUnitCost float64
+		want             float64
+	}{
+		{name: "equal quantities average the costs", quantity: 10, unitCost: 2, received: 10, receivedUnitCost: 4, want: 3},
+		{name: "weighted by quantity", quantity: 30, unitCost: 1, received: 10, receivedUnitCost: 5, want: 2},
+		{name: "fraction rounded", quantity: 2, unitCost: 1, received: 1, receivedUnitCost: 0, want: 0.6667},
+		{name: "no stock takes the new cost", quantity: 0, unitCost: 2, received: 5, receivedUnitCost: 3, want: 3},
+		{name: "unknown cost takes the new cost", quantity: 10, unitCost: 0, received: 5, receivedUnitCost: 3, want: 3},
+		{name: "negative stock takes the new cost", quantity: -2, unitCost: 2, received: 5, receivedUnitCost: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weightedUnitCost(tt.quantity, tt.unitCost, tt.received, tt.receivedUnitCost)
+			if got != tt.want {
+				t.Errorf("weightedUnitCost(%v, %v, %v, %v) = %v, want %v", tt.quantity, tt.unitCost, tt.received, tt.receivedUnitCost, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchInventoryItem(t *testing.T) {
+	tests := []struct {
+		name         string
+		patch        map[string]interface{}
+		wantQuantity float64
+		wantName     string
+		wantReason   string
+		wantErr      bool
+	}{
+		{name: "adjust down", patch: map[string]interface{}{"adjust": -250.0, "reason": "spilled"}, wantQuantity: 750, wantName: "Milk", wantReason: "spilled"},
+		{name: "adjust up without a reason", patch: map[string]interface{}{"adjust": 100.0}, wantQuantity: 1100, wantName: "Milk", wantReason: "manual adjustment"},
+		{name: "merge patch leaves the rest", patch: map[string]interface{}{"name": "Whole milk"}, wantQuantity: 1000, wantName: "Whole milk"},
+		{name: "absolute quantity", patch: map[string]interface{}{"quantity": 900.0}, wantQuantity: 900, wantName: "Milk", wantReason: "manual update"},
+		{name: "adjust below zero", patch: map[string]interface{}{"adjust": -1500.0}, wantErr: true},
+		{name: "zero adjust", patch: map[string]interface{}{"adjust": 0.0}, wantErr: true},
+		{name: "quantity and adjust together", patch: map[string]interface{}{"adjust": 10.0, "quantity": 900.0}, wantErr: true},
+		{name: "unit", patch: map[string]interface{}{"unit": "l"}, wantErr: true},
+		{name: "unknown member", patch: map[string]interface{}{"colour": "white"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempData(t)
+			movementRepo := &dal.MovementService{}
+			service := NewInventoryService(&dal.InventoryItemService{}, movementRepo, &dal.ReceiptService{}, &dal.MenuItemService{})
+			if err := service.AddInventoryItem(models.InventoryItem{IngredientID: "milk", Name: "Milk", Quantity: 1000, Unit: "ml"}); err != nil {
+				t.Fatalf("AddInventoryItem failed: %v", err)
+			}
+
+			item, err := service.PatchInventoryItem("milk", tt.patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("PatchInventoryItem(%v) = %+v, want an error", tt.patch, item)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchInventoryItem(%v) failed: %v", tt.patch, err)
+			}
+
+			if item.Quantity != tt.wantQuantity || item.Name != tt.wantName || item.Unit != "ml" {
+				t.Errorf("item = %v %s of %s, want %v ml of %s", item.Quantity, item.Unit, item.Name, tt.wantQuantity, tt.wantName)
+			}
+			movements, err := movementRepo.ReadItems()
+			if err != nil {
+				t.Fatalf("ReadItems failed: %v", err)
+			}
+			var reasons []string
+			for _, movement := range movements {
+				if movement.Type == models.MovementAdjustment {
+					reasons = append(reasons, movement.Reason)
+				}
+			}
+			if (tt.wantReason == "" && len(reasons) != 0) || (tt.wantReason != "" && (len(reasons) != 1 || reasons[0] != tt.wantReason)) {
+				t.Errorf("adjustments booked = %v, want %q", reasons, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPatchInventoryItemConcurrently(t *testing.T) {
+	useTempData(t)
+	service := NewInventoryService(&dal.InventoryItemService{}, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	if err := service.AddInventoryItem(models.InventoryItem{IngredientID: "milk", Name: "Milk", Quantity: 1000, Unit: "ml"}); err != nil {
+		t.Fatalf("AddInventoryItem failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.PatchInventoryItem("milk", map[string]interface{}{"adjust": -10.0}); err != nil {
+				t.Errorf("PatchInventoryItem failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	item, err := service.GetInventoryItemByID("milk")
+	if err != nil {
+		t.Fatalf("GetInventoryItemByID failed: %v", err)
+	}
+	if item.Quantity != 800 {
+		t.Errorf("quantity after 20 adjustments of -10 = %v, want 800", item.Quantity)
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"time"
+)
+
+type LedgerService interface {
+	GetMovements(ingredientID string, from, to time.Time) ([]models.InventoryMovement, error)
+	ReverseMovement(id string, reason string) (models.InventoryMovement, error)
+	CheckDrift() ([]models.LedgerDrift, error)
+	RebuildQuantities() ([]models.LedgerDrift, error)
+}
+
+type ledgerService struct {
+	movementRepo  dal.MovementRepository
+	inventoryRepo dal.InventoryRepository
+}
+
+func NewLedgerService(movementRepo dal.MovementRepository, inventoryRepo dal.InventoryRepository) LedgerService {
+	return &ledgerService{
+		movementRepo:  movementRepo,
+		inventoryRepo: inventoryRepo,
+	}
+}
+
+// GetMovements lists the ledger entries of one ingredient, or of all
+// ingredients when the ID is empty, within the date range.
+func (s *ledgerService) GetMovements(ingredientID string, from, to time.Time) ([]models.InventoryMovement, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching inventory movements", "ingredientID", ingredientID)
+
+	movements, err := s.movementRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read inventory movements", err)
+		return nil, err
+	}
+
+	filtered := []models.InventoryMovement{}
+	for _, movement := range movements {
+		if ingredientID != "" && movement.IngredientID != ingredientID {
+			continue
+		}
+		if !utils.InDateRange(movement.CreatedAt, from, to) {
+			continue
+		}
+		filtered = append(filtered, movement)
+	}
+
+	logging.Info("Fetched inventory movements", "ingredientID", ingredientID, "count", len(filtered))
+	return filtered, nil
+}
+
+// ReverseMovement books the opposite of an earlier movement, undoing its
+// effect on stock while keeping both entries in the ledger. Movements are
+// only appended under the inventory lock, so holding it keeps the check for
+// an earlier reversal and the new one together.
+func (s *ledgerService) ReverseMovement(id string, reason string) (models.InventoryMovement, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to reverse inventory movement", "movementID", id)
+
+	var reversal models.InventoryMovement
+	var before, after models.InventoryItem
+	err := s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		movements, err := s.movementRepo.ReadItems()
+		if err != nil {
+			logging.Error("Failed to read inventory movements", err)
+			return nil, err
+		}
+
+		var original *models.InventoryMovement
+		for i, movement := range movements {
+			if movement.ReversesID == id {
+				logging.Warn("Inventory movement already reversed", "movementID", id)
+				return nil, errors.New("inventory movement already reversed")
+			}
+			if movement.ID == id {
+				original = &movements[i]
+			}
+		}
+		if original == nil {
+			logging.Warn("Inventory movement not found", "movementID", id)
+			return nil, errors.New("inventory movement not found")
+		}
+		if original.Type == models.MovementReversal {
+			return nil, errors.New("a reversal cannot be reversed")
+		}
+
+		for i := range items {
+			if items[i].IngredientID != original.IngredientID {
+				continue
+			}
+			balance := roundQuantity(items[i].Quantity - original.Quantity)
+			if balance < 0 {
+				logging.Warn("Insufficient inventory to reverse movement", "movementID", id)
+				return nil, errors.New("insufficient inventory for ingredient: " + original.IngredientID)
+			}
+			before = items[i]
+			items[i].Quantity = balance
+			trimLots(&items[i])
+			after = items[i]
+
+			reversal = newMovement(original.IngredientID, models.MovementReversal, -original.Quantity, balance, reason)
+			reversal.OrderID = original.OrderID
+			reversal.ReversesID = original.ID
+			stored, err := s.movementRepo.Append(reversal)
+			if err != nil {
+				logging.Error("Failed to record reversal", err, "movementID", id)
+				return nil, err
+			}
+			reversal = stored[len(stored)-1]
+			return items, nil
+		}
+
+		logging.Warn("Inventory item not found for reversal", "ingredientID", original.IngredientID)
+		return nil, errors.New("inventory item not found")
+	})
+	if err != nil {
+		return models.InventoryMovement{}, err
+	}
+	checkReorderPoints([]models.InventoryItem{before}, []models.InventoryItem{after})
+
+	logging.Info("Successfully reversed inventory movement", "movementID", id, "reversalID", reversal.ID)
+	return reversal, nil
+}
+
+// CheckDrift rebuilds every ingredient's quantity from the ledger and
+// compares it with the stored quantity. Ingredients without any movements
+// are reported as untracked.
+func (s *ledgerService) CheckDrift() ([]models.LedgerDrift, error) {
+	defer utils.CatchCriticalPoint()
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return nil, err
+	}
+	movements, err := s.movementRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read inventory movements", err)
+		return nil, err
+	}
+
+	drifts := ledgerDrifts(items, movements)
+
+	logging.Info("Checked inventory ledger drift", "items", len(drifts))
+	return drifts, nil
+}
+
+// RebuildQuantities resets the quantity of every tracked ingredient to what
+// its ledger adds up to. The ledger is read under the inventory lock, so no
+// movement is booked while the quantities are rebuilt.
+func (s *ledgerService) RebuildQuantities() ([]models.LedgerDrift, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Rebuilding inventory quantities from ledger")
+
+	corrected := []models.LedgerDrift{}
+	err := s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		movements, err := s.movementRepo.ReadItems()
+		if err != nil {
+			logging.Error("Failed to read inventory movements", err)
+			return nil, err
+		}
+
+		for i, drift := range ledgerDrifts(items, movements) {
+			if !drift.Tracked || drift.Drift == 0 {
+				continue
+			}
+			items[i].Quantity = drift.LedgerQuantity
+			trimLots(&items[i])
+			corrected = append(corrected, drift)
+		}
+		return items, nil
+	})
+	if err != nil {
+		logging.Error("Failed to rebuild inventory quantities", err)
+		return nil, err
+	}
+
+	logging.Info("Rebuilt inventory quantities from ledger", "corrected", len(corrected))
+	return corrected, nil
+}
+
+// ledgerDrifts compares every item's quantity with what its ledger adds up
+// to, in the order of the items.
+func ledgerDrifts(items []models.InventoryItem, movements []models.InventoryMovement) []models.LedgerDrift {
+	ledgerQuantities := make(map[string]float64)
+	tracked := make(map[string]bool)
+	for _, movement := range movements {
+		ledgerQuantities[movement.IngredientID] += movement.Quantity
+		tracked[movement.IngredientID] = true
+	}
+
+	drifts := []models.LedgerDrift{}
+	for _, item := range items {
+		ledgerQuantity := roundQuantity(ledgerQuantities[item.IngredientID])
+		drift := models.LedgerDrift{
+			IngredientID:   item.IngredientID,
+			Quantity:       item.Quantity,
+			LedgerQuantity: ledgerQuantity,
+			Tracked:        tracked[item.IngredientID],
+		}
+		if drift.Tracked {
+			drift.Drift = roundQuantity(item.Quantity - ledgerQuantity)
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts
+}
+
+// newMovement builds a ledger entry stamped with the current time.
+func newMovement(ingredientID, movementType string, quantity, balanceAfter float64, reason string) models.InventoryMovement {
+	return models.InventoryMovement{
+		IngredientID: ingredientID,
+		Type:         movementType,
+		Quantity:     roundQuantity(quantity),
+		BalanceAfter: balanceAfter,
+		Reason:       reason,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+}
+
+// recordMovements appends the movements of a stock change to the ledger.
+// It is called under the inventory lock, before the change is saved, so a
+// failure leaves the stock as it was instead of leaving a gap in the ledger.
+func recordMovements(movementRepo dal.MovementRepository, movements ...models.InventoryMovement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+	if _, err := movementRepo.Append(movements...); err != nil {
+		logging.Error("Failed to record inventory movements", err, "count", len(movements))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLedgerDrifts(t *testing.T) {
+	items := []models.InventoryItem{
+		{IngredientID: "milk", Quantity: 900},
+		{IngredientID: "beans", Quantity: 500},
+		{IngredientID: "sugar", Quantity: 100},
+	}
+	movements := []models.InventoryMovement{
+		{IngredientID: "milk", Type: models.MovementRestock, Quantity: 1000},
+		{IngredientID: "milk", Type: models.MovementSale, Quantity: -100},
+		{IngredientID: "beans", Type: models.MovementRestock, Quantity: 1000},
+		{IngredientID: "beans", Type: models.MovementSale, Quantity: -18.5},
+	}
+
+	want := []models.LedgerDrift{
+		{IngredientID: "milk", Quantity: 900, LedgerQuantity: 900, Tracked: true},
+		{IngredientID: "beans", Quantity: 500, LedgerQuantity: 981.5, Drift: -481.5, Tracked: true},
+		{IngredientID: "sugar", Quantity: 100},
+	}
+	if got := ledgerDrifts(items, movements); !reflect.DeepEqual(got, want) {
+		t.Errorf("ledgerDrifts = %+v, want %+v", got, want)
+	}
+}
+
+func TestReverseMovement(t *testing.T) {
+	useTempData(t)
+	inventoryRepo := &dal.InventoryItemService{}
+	movementRepo := &dal.MovementService{}
+	inventory := NewInventoryService(inventoryRepo, movementRepo, &dal.ReceiptService{}, &dal.MenuItemService{})
+	ledger := NewLedgerService(movementRepo, inventoryRepo)
+
+	if err := inventory.AddInventoryItem(models.InventoryItem{IngredientID: "milk", Name: "Milk", Quantity: 1000, Unit: "ml"}); err != nil {
+		t.Fatalf("AddInventoryItem failed: %v", err)
+	}
+	if _, err := inventory.PatchInventoryItem("milk", map[string]interface{}{"adjust": -250.0, "reason": "spilled"}); err != nil {
+		t.Fatalf("PatchInventoryItem failed: %v", err)
+	}
+	movements, err := ledger.GetMovements("milk", time.Time{}, time.Time{})
+	if err != nil || len(movements) != 2 {
+		t.Fatalf("GetMovements = %+v, %v, want the restock and the adjustment", movements, err)
+	}
+	adjustment := movements[1]
+
+	reversal, err := ledger.ReverseMovement(adjustment.ID, "found it")
+	if err != nil {
+		t.Fatalf("ReverseMovement failed: %v", err)
+	}
+	if reversal.Quantity != 250 || reversal.BalanceAfter != 1000 || reversal.ReversesID != adjustment.ID {
+		t.Errorf("reversal = %+v, want 250 back to a balance of 1000", reversal)
+	}
+
+	for _, id := range []string{adjustment.ID, reversal.ID, "mov404"} {
+		if _, err := ledger.ReverseMovement(id, ""); err == nil {
+			t.Errorf("ReverseMovement(%s) succeeded, want an error", id)
+		}
+	}
+
+	drifts, err := ledger.CheckDrift()
+	if err != nil {
+		t.Fatalf("CheckDrift failed: %v", err)
+	}
+	want := []models.LedgerDrift{{IngredientID: "milk", Quantity: 1000, LedgerQuantity: 1000, Tracked: true}}
+	if !reflect.DeepEqual(drifts, want) {
+		t.Errorf("drifts = %+v, want %+v", drifts, want)
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"sort"
+	"time"
+)
+
+// defaultPopularityFactor is the share of an equal split of the quantity
+// sold, in percent, that makes an item popular.
+const defaultPopularityFactor = 70
+
+// GetMenuEngineering sorts the menu items into stars, plowhorses, puzzles
+// and dogs by the closed orders of the date range. Bundles are left out:
+// their sales count towards the items they contain. Items with an ingredient
+// of unknown cost have no reliable margin, so they are left unclassified and
+// out of the thresholds and totals. Items whose recipe no longer converts to
+// the stock units are left out. A popularity factor of zero uses the default.
+func (s *reportService) GetMenuEngineering(from, to time.Time, popularityFactor float64) (models.MenuEngineeringReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	if popularityFactor == 0 {
+		popularityFactor = defaultPopularityFactor
+	}
+	if popularityFactor < 0 || popularityFactor > 100 {
+		return models.MenuEngineeringReport{}, errors.New("invalid popularity factor")
+	}
+
+	sales, err := s.popularItems(from, to, "quantity")
+	if err != nil {
+		return models.MenuEngineeringReport{}, err
+	}
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.MenuEngineeringReport{}, err
+	}
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return models.MenuEngineeringReport{}, err
+	}
+
+	return menuEngineering(sales, menuItems, mapInventoryItems(inventoryItems), popularityFactor)
+}
+
+// menuEngineering classifies the menu items by what they sold and earned.
+func menuEngineering(sales models.PopularItemsReport, menuItems []models.MenuItem, inventoryMap map[string]models.InventoryItem, popularityFactor float64) (models.MenuEngineeringReport, error) {
+	menuItemMap := mapMenuItems(menuItems)
+
+	sold := make(map[string]models.PopularItem)
+	for _, item := range sales.Items {
+		sold[item.ProductID] = item
+	}
+
+	report := models.MenuEngineeringReport{
+		From:         sales.From,
+		To:           sales.To,
+		Stars:        []string{},
+		Plowhorses:   []string{},
+		Puzzles:      []string{},
+		Dogs:         []string{},
+		Unclassified: []string{},
+		Items:        []models.MenuEngineeringItem{},
+	}
+
+	// Cost every item and total what the classified items sold
+	var totalMargin, marginSum float64
+	classified := 0
+	for _, menuItem := range menuItems {
+		if menuItem.IsBundle() {
+			continue
+		}
+		itemCost, err := menuItemCost(menuItem, menuItemMap, inventoryMap)
+		if err != nil {
+			logging.Error("Failed to cost menu item, leaving it out of the report", err, "itemID", menuItem.ID)
+			continue
+		}
+		itemSales := sold[menuItem.ID]
+
+		price := menuItem.Price
+		if itemSales.Quantity > 0 {
+			price = itemSales.Revenue / float64(itemSales.Quantity)
+		}
+		margin := price - itemCost.Cost
+
+		if len(itemCost.MissingCosts) == 0 {
+			classified++
+			report.TotalQuantity += itemSales.Quantity
+			report.TotalRevenue += itemSales.Revenue
+			totalMargin += margin * float64(itemSales.Quantity)
+			marginSum += margin
+		}
+
+		report.Items = append(report.Items, models.MenuEngineeringItem{
+			ProductID:    menuItem.ID,
+			Name:         menuItem.Name,
+			Category:     menuCategory(menuItem),
+			Quantity:     itemSales.Quantity,
+			Price:        price,
+			Cost:         itemCost.Cost,
+			Margin:       margin,
+			MissingCosts: itemCost.MissingCosts,
+		})
+	}
+	if len(report.Items) == 0 {
+		logging.Warn("No menu items to classify")
+		return report, nil
+	}
+
+	// Popularity is measured against an equal share of the quantity sold,
+	// profitability against the average margin of a unit sold. With nothing
+	// sold every item weighs the same.
+	var popularityQuantity, marginThreshold float64
+	if classified > 0 {
+		popularityQuantity = popularityFactor / 100 * float64(report.TotalQuantity) / float64(classified)
+		marginThreshold = marginSum / float64(classified)
+		if report.TotalQuantity > 0 {
+			marginThreshold = totalMargin / float64(report.TotalQuantity)
+		}
+		report.Thresholds = models.MenuEngineeringThresholds{
+			PopularityFactor:   popularityFactor,
+			PopularityShare:    roundPrice(popularityFactor / float64(classified)),
+			PopularityQuantity: roundQuantity(popularityQuantity),
+			Margin:             roundPrice(marginThreshold),
+		}
+	}
+
+	for i := range report.Items {
+		item := &report.Items[i]
+		if len(item.MissingCosts) > 0 {
+			item.Price = roundPrice(item.Price)
+			item.Margin = roundPrice(item.Margin)
+			item.Class = models.MenuUnclassified
+			continue
+		}
+
+		item.Popular = report.TotalQuantity > 0 && float64(item.Quantity) >= popularityQuantity
+		item.Profitable = item.Margin >= marginThreshold
+		item.MixShare = share(float64(item.Quantity), float64(report.TotalQuantity))
+		if item.Quantity > 0 {
+			item.TotalMargin = roundPrice(item.Margin * float64(item.Quantity))
+		}
+		item.Price = roundPrice(item.Price)
+		item.Margin = roundPrice(item.Margin)
+
+		switch {
+		case item.Popular && item.Profitable:
+			item.Class = models.MenuStar
+		case item.Popular:
+			item.Class = models.MenuPlowhorse
+		case item.Profitable:
+			item.Class = models.MenuPuzzle
+		default:
+			item.Class = models.MenuDog
+		}
+	}
+	report.TotalRevenue = roundPrice(report.TotalRevenue)
+	report.TotalMargin = roundPrice(totalMargin)
+
+	// Most profitable first, and the lists in the same order
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.TotalMargin != b.TotalMargin {
+			return a.TotalMargin > b.TotalMargin
+		}
+		return a.ProductID < b.ProductID
+	})
+	for _, item := range report.Items {
+		switch item.Class {
+		case models.MenuStar:
+			report.Stars = append(report.Stars, item.ProductID)
+		case models.MenuPlowhorse:
+			report.Plowhorses = append(report.Plowhorses, item.ProductID)
+		case models.MenuPuzzle:
+			report.Puzzles = append(report.Puzzles, item.ProductID)
+		case models.MenuDog:
+			report.Dogs = append(report.Dogs, item.ProductID)
+		default:
+			report.Unclassified = append(report.Unclassified, item.ProductID)
+		}
+	}
+
+	logging.Info("Menu engineering calculated", "items", len(report.Items), "stars", len(report.Stars), "dogs", len(report.Dogs))
+	return report, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+)
+
+func TestMenuEngineering(t *testing.T) {
+	recipe := func(id string, price float64, ingredientID string, quantity float64) models.MenuItem {
+		return models.MenuItem{
+			ID:          id,
+			Name:        id,
+			Price:       price,
+			Ingredients: []models.MenuItemIngredient{{IngredientID: ingredientID, Quantity: quantity}},
+		}
+	}
+	// Every item but the one with syrup costs 1 to make
+	menuItems := []models.MenuItem{
+		recipe("star", 5, "beans", 100),
+		recipe("plowhorse", 3, "beans", 100),
+		recipe("puzzle", 6, "beans", 100),
+		recipe("dog", 2, "beans", 100),
+		recipe("syrup_shot", 4, "syrup", 10),
+	}
+	inventoryMap := map[string]models.InventoryItem{
+		"beans": {IngredientID: "beans", Unit: "g", UnitCost: 0.01},
+		"syrup": {IngredientID: "syrup", Unit: "ml"},
+	}
+	sales := func(quantities map[string]int) models.PopularItemsReport {
+		var report models.PopularItemsReport
+		for _, menuItem := range menuItems {
+			if quantity := quantities[menuItem.ID]; quantity > 0 {
+				report.Items = append(report.Items, models.PopularItem{
+					ProductID: menuItem.ID,
+					Quantity:  quantity,
+					Revenue:   menuItem.Price * float64(quantity),
+				})
+			}
+		}
+		return report
+	}
+
+	tests := []struct {
+		name           string
+		menuItems      []models.MenuItem
+		sales          models.PopularItemsReport
+		wantThresholds models.MenuEngineeringThresholds
+		wantQuantity   int
+		wantMargin     float64
+		wantClasses    map[string]string
+	}{
+		{
+			name:      "items with missing costs stay out of the thresholds",
+			menuItems: menuItems,
+			sales:     sales(map[string]int{"star": 40, "plowhorse": 40, "puzzle": 5, "dog": 5, "syrup_shot": 100}),
+			wantThresholds: models.MenuEngineeringThresholds{
+				PopularityFactor:   70,
+				PopularityShare:    17.5,
+				PopularityQuantity: 15.75,
+				Margin:             3,
+			},
+			wantQuantity: 90,
+			wantMargin:   270,
+			wantClasses: map[string]string{
+				"star":       models.MenuStar,
+				"plowhorse":  models.MenuPlowhorse,
+				"puzzle":     models.MenuPuzzle,
+				"dog":        models.MenuDog,
+				"syrup_shot": models.MenuUnclassified,
+			},
+		},
+		{
+			name:      "nothing sold compares list margins",
+			menuItems: menuItems,
+			sales:     sales(nil),
+			wantThresholds: models.MenuEngineeringThresholds{
+				PopularityFactor: 70,
+				PopularityShare:  17.5,
+				Margin:           3,
+			},
+			wantClasses: map[string]string{
+				"star":       models.MenuPuzzle,
+				"plowhorse":  models.MenuDog,
+				"puzzle":     models.MenuPuzzle,
+				"dog":        models.MenuDog,
+				"syrup_shot": models.MenuUnclassified,
+			},
+		},
+		{
+			name:        "only items with missing costs",
+			menuItems:   menuItems[4:],
+			sales:       sales(map[string]int{"syrup_shot": 10}),
+			wantClasses: map[string]string{"syrup_shot": models.MenuUnclassified},
+		},
+		{
+			name: "items whose recipe no longer converts are left out",
+			menuItems: []models.MenuItem{
+				menuItems[0],
+				{ID: "latte", Name: "latte", Price: 4, Ingredients: []models.MenuItemIngredient{{IngredientID: "beans", Quantity: 1, Unit: "l"}}},
+			},
+			sales: sales(map[string]int{"star": 10}),
+			wantThresholds: models.MenuEngineeringThresholds{
+				PopularityFactor:   70,
+				PopularityShare:    70,
+				PopularityQuantity: 7,
+				Margin:             4,
+			},
+			wantQuantity: 10,
+			wantMargin:   40,
+			wantClasses:  map[string]string{"star": models.MenuStar},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := menuEngineering(tt.sales, tt.menuItems, inventoryMap, defaultPopularityFactor)
+			if err != nil {
+				t.Fatalf("menuEngineering failed: %v", err)
+			}
+
+			if report.Thresholds != tt.wantThresholds {
+				t.Errorf("thresholds = %+v, want %+v", report.Thresholds, tt.wantThresholds)
+			}
+			if report.TotalQuantity != tt.wantQuantity || report.TotalMargin != tt.wantMargin {
+				t.Errorf("totals = %d sold, %v margin, want %d sold, %v margin", report.TotalQuantity, report.TotalMargin, tt.wantQuantity, tt.wantMargin)
+			}
+			classes := make(map[string]string)
+			for _, item := range report.Items {
+				classes[item.ProductID] = item.Class
+			}
+			if !reflect.DeepEqual(classes, tt.wantClasses) {
+				t.Errorf("classes = %v, want %v", classes, tt.wantClasses)
+			}
+
+			listed := make(map[string]string)
+			for class, productIDs := range map[string][]string{
+				models.MenuStar:         report.Stars,
+				models.MenuPlowhorse:    report.Plowhorses,
+				models.MenuPuzzle:       report.Puzzles,
+				models.MenuDog:          report.Dogs,
+				models.MenuUnclassified: report.Unclassified,
+			} {
+				for _, productID := range productIDs {
+					listed[productID] = class
+				}
+			}
+			if !reflect.DeepEqual(listed, tt.wantClasses) {
+				t.Errorf("lists = %v, want %v", listed, tt.wantClasses)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"errors"
+	"hot-coffee/config"
 	"hot-coffee/internal/dal"
 	"hot-coffee/models"
 	"hot-coffee/utils"
+	"strings"
 
 	"hot-coffee/logging" // Import the logging package
 )
@@ -12,19 +14,23 @@ import (
 type MenuService interface {
 	CreateMenuItem(item models.MenuItem) error
 	FetchAllMenuItems() ([]models.MenuItem, error)
+	FetchMenuItemsWithoutAllergens(allergens []string) ([]models.MenuItem, error)
 	FindMenuItemByID(id string) (models.MenuItem, error)
 	UpdateMenuItemByID(id string, item models.MenuItem) error
 	DeleteMenuItemByID(id string) error
 	GetPopularMenuItems() ([]models.MenuItem, error)
+	GetMenuItemCost(id string) (models.MenuItemCost, error)
 }
 
 type menuService struct {
-	menuRepo dal.MenuRepository
+	menuRepo      dal.MenuRepository
+	inventoryRepo dal.InventoryRepository
 }
 
-func NewMenuService(menuRepo dal.MenuRepository) MenuService {
+func NewMenuService(menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository) MenuService {
 	return &menuService{
-		menuRepo: menuRepo,
+		menuRepo:      menuRepo,
+		inventoryRepo: inventoryRepo,
 	}
 }
 
@@ -51,6 +57,17 @@ func (s *menuService) CreateMenuItem(item models.MenuItem) error {
 		logging.Warn("Invalid updated menu item data", "error", err)
 		return err
 	}
+	if err := validateBundleComponents(item, mapMenuItems(items)); err != nil {
+		logging.Warn("Invalid bundle components", "itemID", item.ID, "error", err)
+		return err
+	}
+	if err := s.validateRecipeUnits(item); err != nil {
+		logging.Warn("Invalid recipe", "itemID", item.ID, "error", err)
+		return err
+	}
+	// Allergens and nutrition are derived from the recipe on every read
+	item.Allergens, item.Nutrition = nil, nil
+
 	// Add the new item
 	items = append(items, item)
 
@@ -78,11 +95,74 @@ func (s *menuService) FetchAllMenuItems() ([]models.MenuItem, error) {
 		return nil, err
 	}
 
+	if err := s.applyDietInfo(items); err != nil {
+		return nil, err
+	}
+
 	// Log success
 	logging.Info("Fetched all menu items", "count", len(items))
 	return items, nil
 }
 
+// FetchMenuItemsWithoutAllergens returns the menu items that contain none of
+// the given allergens.
+func (s *menuService) FetchMenuItemsWithoutAllergens(allergens []string) ([]models.MenuItem, error) {
+	defer utils.CatchCriticalPoint()
+
+	items, err := s.FetchAllMenuItems()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []models.MenuItem{}
+	for _, item := range items {
+		excluded := false
+		for _, allergen := range item.Allergens {
+			for _, unwanted := range allergens {
+				if strings.EqualFold(allergen, strings.TrimSpace(unwanted)) {
+					excluded = true
+				}
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, item)
+		}
+	}
+
+	logging.Info("Filtered menu items by allergens", "allergens", allergens, "count", len(filtered))
+	return filtered, nil
+}
+
+// validateRecipeUnits checks the recipe against the current inventory.
+func (s *menuService) validateRecipeUnits(item models.MenuItem) error {
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return err
+	}
+	return validateRecipeUnits(item, mapInventoryItems(inventoryItems))
+}
+
+// applyDietInfo fills in the allergens and nutrition of the menu items from
+// the current recipes and inventory. An item whose recipe no longer converts
+// is listed without diet info rather than failing the whole menu.
+func (s *menuService) applyDietInfo(items []models.MenuItem) error {
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return err
+	}
+
+	menuItemMap := mapMenuItems(items)
+	inventoryMap := mapInventoryItems(inventoryItems)
+	for i := range items {
+		if err := applyDietInfo(&items[i], menuItemMap, inventoryMap); err != nil {
+			logging.Error("Failed to derive diet info, skipping it", err, "itemID", items[i].ID)
+		}
+	}
+	return nil
+}
+
 func (s *menuService) FindMenuItemByID(id string) (models.MenuItem, error) {
 	defer utils.CatchCriticalPoint()
 
@@ -95,6 +175,10 @@ func (s *menuService) FindMenuItemByID(id string) (models.MenuItem, error) {
 		return models.MenuItem{}, err
 	}
 
+	if err := s.applyDietInfo(items); err != nil {
+		return models.MenuItem{}, err
+	}
+
 	// Search for the item by ID
 	for _, item := range items {
 		if item.ID == id {
@@ -125,6 +209,23 @@ func (s *menuService) UpdateMenuItemByID(id string, updatedItem models.MenuItem)
 
 	for i, item := range items {
 		if item.ID == id {
+			if err := validateBundleComponents(updatedItem, mapMenuItems(items)); err != nil {
+				logging.Warn("Invalid bundle components", "itemID", id, "error", err)
+				return err
+			}
+			if updatedItem.IsBundle() {
+				for _, other := range items {
+					if bundleContains(other, id) {
+						logging.Warn("Menu item is part of a bundle", "itemID", id, "bundleID", other.ID)
+						return errors.New("menu item is part of bundle and cannot become a bundle: " + other.ID)
+					}
+				}
+			}
+			if err := s.validateRecipeUnits(updatedItem); err != nil {
+				logging.Warn("Invalid recipe", "itemID", id, "error", err)
+				return err
+			}
+			updatedItem.Allergens, updatedItem.Nutrition = nil, nil
 			items[i] = updatedItem
 			err := s.menuRepo.SaveItems(items)
 			if err != nil {
@@ -154,6 +255,14 @@ func (s *menuService) DeleteMenuItemByID(id string) error {
 		return err
 	}
 
+	// A product that is still sold as part of a bundle cannot be removed
+	for _, item := range items {
+		if item.ID != id && bundleContains(item, id) {
+			logging.Warn("Menu item is part of a bundle", "itemID", id, "bundleID", item.ID)
+			return errors.New("menu item is part of bundle: " + item.ID)
+		}
+	}
+
 	// Create a new list excluding the item to be deleted
 	var updatedItems []models.MenuItem
 	for _, item := range items {
@@ -193,3 +302,39 @@ func (s *menuService) GetPopularMenuItems() ([]models.MenuItem, error) {
 	logging.Info("Fetched popular menu items", "count", len(items))
 	return items, nil
 }
+
+// GetMenuItemCost computes the recipe cost and gross margin of a menu item.
+func (s *menuService) GetMenuItemCost(id string) (models.MenuItemCost, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Calculating menu item cost", "itemID", id)
+
+	items, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch menu items", err)
+		return models.MenuItemCost{}, err
+	}
+
+	menuItemMap := mapMenuItems(items)
+	menuItem, exists := menuItemMap[id]
+	if !exists {
+		logging.Warn("Menu item not found", "itemID", id)
+		return models.MenuItemCost{}, errors.New("menu item not found")
+	}
+
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to fetch inventory items", err)
+		return models.MenuItemCost{}, err
+	}
+
+	itemCost, err := menuItemCost(menuItem, menuItemMap, mapInventoryItems(inventoryItems))
+	if err != nil {
+		logging.Error("Failed to cost menu item", err, "itemID", id)
+		return models.MenuItemCost{}, err
+	}
+	itemCost.BelowThreshold = itemCost.MarginPercent < config.MarginThreshold
+
+	logging.Info("Calculated menu item cost", "itemID", id, "cost", itemCost.Cost, "margin", itemCost.Margin)
+	return itemCost, nil
+}
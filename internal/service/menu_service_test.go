@@ -0,0 +1,125 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+)
+
+func TestApplyDietInfo(t *testing.T) {
+	inventoryMap := map[string]models.InventoryItem{
+		"milk":  {IngredientID: "milk", Unit: "ml", Allergens: []string{"dairy"}, Nutrition: &models.Nutrition{Calories: 0.5, Fat: 0.035, Sugar: 0.05}},
+		"beans": {IngredientID: "beans", Unit: "g", Nutrition: &models.Nutrition{Calories: 0.1}},
+		"flour": {IngredientID: "flour", Unit: "g", Allergens: []string{"gluten"}, Nutrition: &models.Nutrition{Calories: 3.6, Protein: 0.1}},
+	}
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "espresso", Ingredients: []models.MenuItemIngredient{{IngredientID: "beans", Quantity: 18}}},
+		{ID: "latte", Ingredients: []models.MenuItemIngredient{
+			{IngredientID: "beans", Quantity: 18},
+			{IngredientID: "milk", Quantity: 0.2, Unit: "l"},
+		}},
+		{ID: "muffin", Ingredients: []models.MenuItemIngredient{{IngredientID: "flour", Quantity: 50}}},
+		{ID: "breakfast", Components: []models.BundleComponent{
+			{Group: "drink", Choices: []string{"espresso", "latte"}, Quantity: 1},
+			{ProductID: "muffin", Quantity: 2},
+		}},
+		{ID: "bad_latte", Ingredients: []models.MenuItemIngredient{{IngredientID: "milk", Quantity: 200, Unit: "g"}}},
+	})
+
+	tests := []struct {
+		name          string
+		productID     string
+		wantAllergens []string
+		wantNutrition models.Nutrition
+		wantErr       bool
+	}{
+		{
+			name:          "recipe units converted to stock units",
+			productID:     "latte",
+			wantAllergens: []string{"dairy"},
+			wantNutrition: models.Nutrition{Calories: 101.8, Fat: 7, Sugar: 10},
+		},
+		{
+			name:          "bundle lists every allergen and the most calorific choice",
+			productID:     "breakfast",
+			wantAllergens: []string{"dairy", "gluten"},
+			wantNutrition: models.Nutrition{Calories: 461.8, Fat: 7, Sugar: 10, Protein: 10},
+		},
+		{
+			name:      "unit that does not convert",
+			productID: "bad_latte",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menuItem := menuItemMap[tt.productID]
+			err := applyDietInfo(&menuItem, menuItemMap, inventoryMap)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("applyDietInfo(%s) succeeded, want an error", tt.productID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyDietInfo(%s) failed: %v", tt.productID, err)
+			}
+
+			if !reflect.DeepEqual(menuItem.Allergens, tt.wantAllergens) {
+				t.Errorf("allergens = %v, want %v", menuItem.Allergens, tt.wantAllergens)
+			}
+			if menuItem.Nutrition == nil || *menuItem.Nutrition != tt.wantNutrition {
+				t.Errorf("nutrition = %+v, want %+v", menuItem.Nutrition, tt.wantNutrition)
+			}
+		})
+	}
+}
+
+func TestFetchMenuItemsWithoutAllergens(t *testing.T) {
+	useTempData(t)
+	menuRepo := &dal.MenuItemService{}
+	inventoryRepo := &dal.InventoryItemService{}
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{
+		{IngredientID: "milk", Name: "Milk", Unit: "ml", Allergens: []string{"dairy"}},
+		{IngredientID: "flour", Name: "Flour", Unit: "g", Allergens: []string{"gluten"}},
+		{IngredientID: "beans", Name: "Beans", Unit: "g"},
+	}); err != nil {
+		t.Fatalf("saving inventory failed: %v", err)
+	}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "espresso", Ingredients: []models.MenuItemIngredient{{IngredientID: "beans", Quantity: 18}}},
+		{ID: "latte", Ingredients: []models.MenuItemIngredient{{IngredientID: "milk", Quantity: 200}}},
+		{ID: "muffin", Ingredients: []models.MenuItemIngredient{{IngredientID: "flour", Quantity: 50}}},
+	}); err != nil {
+		t.Fatalf("saving menu failed: %v", err)
+	}
+	service := NewMenuService(menuRepo, inventoryRepo)
+
+	tests := []struct {
+		name      string
+		allergens []string
+		want      []string
+	}{
+		{name: "no filter", want: []string{"espresso", "latte", "muffin"}},
+		{name: "one allergen", allergens: []string{"dairy"}, want: []string{"espresso", "muffin"}},
+		{name: "several allergens, any case", allergens: []string{"Dairy", " gluten"}, want: []string{"espresso"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := service.FetchMenuItemsWithoutAllergens(tt.allergens)
+			if err != nil {
+				t.Fatalf("FetchMenuItemsWithoutAllergens failed: %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
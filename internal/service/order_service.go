@@ -12,35 +12,60 @@ import (
 )
 
 type OrderService interface {
-	CreateOrder(order models.Order) error
+	CreateOrder(order models.Order) (models.Order, error)
 	FetchAllOrders() ([]models.Order, error)
 	FindOrderByID(id string) (models.Order, error)
 	UpdateOrderByID(id string, updatedOrder models.Order) error
 	DeleteOrderByID(id string) error
-	CloseOrder(orderID string) error
+	CloseOrder(orderID string, paymentMethod string) error
+	CancelOrder(orderID string) (models.Order, error)
+	VoidOrder(orderID string) (models.Order, error)
 	TotalSalesCount() (map[string]int, error)
 }
 
 type orderService struct {
-	orderRepo dal.OrderRepository
+	orderRepo       dal.OrderRepository
+	menuRepo        dal.MenuRepository
+	inventoryRepo   dal.InventoryRepository
+	pricingRepo     dal.PricingRuleRepository
+	movementRepo    dal.MovementRepository
+	aggregationRepo dal.AggregationRepository
+	dayCloseRepo    dal.DayCloseRepository
 }
 
-func NewOrderService(orderRepo dal.OrderRepository) OrderService {
+func NewOrderService(orderRepo dal.OrderRepository, menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository, pricingRepo dal.PricingRuleRepository, movementRepo dal.MovementRepository, aggregationRepo dal.AggregationRepository, dayCloseRepo dal.DayCloseRepository) OrderService {
 	return &orderService{
-		orderRepo: orderRepo,
+		orderRepo:       orderRepo,
+		menuRepo:        menuRepo,
+		inventoryRepo:   inventoryRepo,
+		pricingRepo:     pricingRepo,
+		movementRepo:    movementRepo,
+		aggregationRepo: aggregationRepo,
+		dayCloseRepo:    dayCloseRepo,
 	}
 }
 
-func (s *orderService) CreateOrder(order models.Order) error {
+func (s *orderService) CreateOrder(order models.Order) (models.Order, error) {
 	defer utils.CatchCriticalPoint()
 
 	logging.Info("Attempting to create order", "customerName", order.CustomerName)
 
+	if err := validatePaymentMethod(order.PaymentMethod); err != nil {
+		logging.Warn("Invalid payment method", "paymentMethod", order.PaymentMethod)
+		return models.Order{}, err
+	}
+
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, time.Now()); err != nil {
+		return models.Order{}, err
+	}
+
 	// Read the current orders
 	orders, err := s.orderRepo.ReadItems()
 	if err != nil {
 		logging.Error("Failed to read orders", err)
-		return err
+		return models.Order{}, err
 	}
 
 	// If there are existing orders, generate the next ID based on the last order ID
@@ -52,7 +77,7 @@ func (s *orderService) CreateOrder(order models.Order) error {
 		_, err := fmt.Sscanf(lastOrder.ID, "order%d", &lastOrderID)
 		if err != nil {
 			logging.Error("Failed to parse last order ID", err)
-			return err
+			return models.Order{}, err
 		}
 
 		// Increment the last order ID
@@ -68,27 +93,55 @@ func (s *orderService) CreateOrder(order models.Order) error {
 	}
 
 	// Set the created_at field to the current time minus 1 hour in Nur-Sultan (Asia/Almaty time zone)
+	loc, err := utils.BusinessLocation() // Load the Asia/Almaty time zone (for Nur-Sultan)
+	if err != nil {
+		logging.Error("Failed to load time zone", err)
+		return models.Order{}, err
+	}
 	if order.CreatedAt == "" {
-		loc, err := time.LoadLocation("Asia/Almaty") // Load the Asia/Almaty time zone (for Nur-Sultan)
-		if err != nil {
-			logging.Error("Failed to load time zone", err)
-			return err
-		}
 		// Get current time in Nur-Sultan time zone and subtract one hour
 		order.CreatedAt = time.Now().In(loc).Add(-time.Hour).Format(time.RFC3339)
 	}
 
+	// Price the order lines with the rules in effect right now
+	if err := s.priceOrder(&order, time.Now().In(loc)); err != nil {
+		logging.Warn("Failed to price order", "error", err)
+		return models.Order{}, err
+	}
+
 	// Append the new order to the existing list
 	orders = append(orders, order)
 
 	// Save the updated orders list back to the file
 	if err := s.orderRepo.SaveItems(orders); err != nil {
 		logging.Error("Failed to save new order", err)
+		return models.Order{}, err
+	}
+
+	logging.Info("Successfully created order", "orderID", order.ID, "total", order.Total)
+	return order, nil
+}
+
+// priceOrder validates the order lines and applies the pricing rules in
+// effect at the given time.
+func (s *orderService) priceOrder(order *models.Order, at time.Time) error {
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return err
+	}
+	menuItemMap := mapMenuItems(menuItems)
+	if err := validateOrderItems(*order, menuItemMap); err != nil {
 		return err
 	}
 
-	logging.Info("Successfully created order", "orderID", order.ID)
-	return nil
+	rules, err := s.pricingRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read pricing rules", err)
+		return err
+	}
+
+	return priceOrder(order, menuItemMap, rules, at)
 }
 
 func (s *orderService) FetchAllOrders() ([]models.Order, error) {
@@ -141,6 +194,16 @@ func (s *orderService) UpdateOrderByID(id string, updatedOrder models.Order) err
 		logging.Warn("Invalid updated order data", "orderID", id, "error", err)
 		return err
 	}
+	if err := validatePaymentMethod(updatedOrder.PaymentMethod); err != nil {
+		logging.Warn("Invalid payment method", "orderID", id, "paymentMethod", updatedOrder.PaymentMethod)
+		return err
+	}
+
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, time.Now()); err != nil {
+		return err
+	}
 
 	// Read current orders from the repository
 	orders, err := s.orderRepo.ReadItems()
@@ -158,6 +221,32 @@ func (s *orderService) UpdateOrderByID(id string, updatedOrder models.Order) err
 				logging.Warn("Order is already closed and cannot be modified", "orderID", id)
 				return errors.New("order is already closed and cannot be modified")
 			}
+			if order.Status != "open" {
+				logging.Warn("Order is no longer open and cannot be modified", "orderID", id, "status", order.Status)
+				return errors.New("order is " + order.Status + " and cannot be modified")
+			}
+			// The status only moves through the close, cancel and void actions
+			if updatedOrder.Status != order.Status {
+				logging.Warn("Order status cannot be changed by an update", "orderID", id, "status", updatedOrder.Status)
+				return errors.New("order status cannot be changed")
+			}
+
+			// Re-price the order since its lines may have changed, with the
+			// rules that were in effect when it was created
+			loc, err := utils.BusinessLocation()
+			if err != nil {
+				logging.Error("Failed to load time zone", err)
+				return err
+			}
+			pricedAt, err := time.Parse(time.RFC3339, order.CreatedAt)
+			if err != nil {
+				logging.Warn("Invalid order timestamp, pricing at the current time", "orderID", id, "createdAt", order.CreatedAt)
+				pricedAt = time.Now()
+			}
+			if err := s.priceOrder(&updatedOrder, pricedAt.In(loc)); err != nil {
+				logging.Warn("Failed to price order", "orderID", id, "error", err)
+				return err
+			}
 
 			// Apply the updates if status is valid
 			orders[i] = updatedOrder
@@ -182,6 +271,12 @@ func (s *orderService) DeleteOrderByID(id string) error {
 
 	logging.Info("Attempting to delete order", "orderID", id)
 
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, time.Now()); err != nil {
+		return err
+	}
+
 	// Read current orders
 	orders, err := s.orderRepo.ReadItems()
 	if err != nil {
@@ -194,6 +289,10 @@ func (s *orderService) DeleteOrderByID(id string) error {
 				logging.Warn("Order is already closed and cannot be deleted", "orderID", id)
 				return errors.New("order is already closed and cannot be deleted")
 			}
+			if order.Status != "open" {
+				logging.Warn("Order is no longer open and cannot be deleted", "orderID", id, "status", order.Status)
+				return errors.New("order is " + order.Status + " and cannot be deleted")
+			}
 		}
 	}
 	// Create a new list excluding the order to be deleted
@@ -217,11 +316,24 @@ func (s *orderService) DeleteOrderByID(id string) error {
 	return nil
 }
 
-func (s *orderService) CloseOrder(orderID string) error {
+// CloseOrder settles an open order, taking its ingredients out of stock.
+// Without a payment method the one already on the order is kept.
+func (s *orderService) CloseOrder(orderID string, paymentMethod string) error {
 	defer utils.CatchCriticalPoint()
 
 	logging.Info("Attempting to close order", "orderID", orderID)
 
+	if err := validatePaymentMethod(paymentMethod); err != nil {
+		logging.Warn("Invalid payment method", "orderID", orderID, "paymentMethod", paymentMethod)
+		return err
+	}
+
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, time.Now()); err != nil {
+		return err
+	}
+
 	// Retrieve current orders
 	orders, err := s.orderRepo.ReadItems()
 	if err != nil {
@@ -248,84 +360,233 @@ func (s *orderService) CloseOrder(orderID string) error {
 		logging.Warn("Order is already closed", "orderID", orderID)
 		return errors.New("order is already closed")
 	}
-
-	// Create instances of MenuItemService and InventoryItemService
-	menuService := dal.MenuItemService{}
-	inventoryService := dal.InventoryItemService{}
+	if orderToUpdate.Status != "open" {
+		logging.Warn("Order is no longer open", "orderID", orderID, "status", orderToUpdate.Status)
+		return errors.New("order is " + orderToUpdate.Status)
+	}
 
 	// Read menu items to verify ordered products
-	menuItems, err := menuService.ReadItems()
+	menuItems, err := s.menuRepo.ReadItems()
 	if err != nil {
 		logging.Error("Failed to read menu items", err)
 		return err
 	}
 
 	// Map the menu items for fast lookup by product ID
-	menuItemMap := make(map[string]models.MenuItem)
-	for _, menuItem := range menuItems {
-		menuItemMap[menuItem.ID] = menuItem
-	}
+	menuItemMap := mapMenuItems(menuItems)
 
-	// Read the inventory to update quantities based on the order
-	inventoryItems, err := inventoryService.ReadItem()
-	if err != nil {
-		logging.Error("Failed to read inventory items", err)
-		return err
-	}
+	// Deduct the ingredients under the inventory lock so concurrent changes are not lost
+	var before, after []models.InventoryItem
+	var usage []ingredientUsage
+	err = s.inventoryRepo.UpdateItems(func(inventoryItems []models.InventoryItem) ([]models.InventoryItem, error) {
+		// Keep the quantities before deduction to spot items crossing their reorder point
+		before = append([]models.InventoryItem(nil), inventoryItems...)
 
-	// Map inventory items for fast lookup by ingredient ID
-	inventoryMap := make(map[string]*models.InventoryItem)
-	for i := range inventoryItems {
-		inventoryMap[inventoryItems[i].IngredientID] = &inventoryItems[i]
-	}
-
-	// Check each order item against the menu and update inventory
-	for _, orderItem := range orderToUpdate.Items {
-		menuItem, exists := menuItemMap[orderItem.ProductID]
-		if !exists {
-			logging.Warn("Product not found in menu", "productID", orderItem.ProductID)
-			return errors.New("product not found in menu: " + orderItem.ProductID)
+		usage, err = orderUsage(*orderToUpdate, menuItemMap, mapInventoryItems(inventoryItems))
+		if err != nil {
+			return nil, err
+		}
+		if err := deductStock(inventoryItems, usage, time.Now()); err != nil {
+			return nil, err
 		}
 
-		// Deduct inventory for the ingredients of the ordered menu item
-		for _, ingredient := range menuItem.Ingredients {
-			inventoryItem, found := inventoryMap[ingredient.IngredientID]
-			if !found {
-				logging.Warn("Ingredient not found in inventory", "ingredientID", ingredient.IngredientID)
-				return errors.New("ingredient not found in inventory: " + ingredient.IngredientID)
-			}
-
-			requiredQty := ingredient.Quantity * float64(orderItem.Quantity)
-			if inventoryItem.Quantity < requiredQty {
-				logging.Warn("Insufficient inventory for ingredient", "ingredientID", ingredient.IngredientID)
-				return errors.New("insufficient inventory for ingredient: " + ingredient.IngredientID)
-			}
-
-			// Deduct the required quantity
-			inventoryItem.Quantity -= requiredQty
+		// Record a sale movement per ingredient
+		movements := usageMovements(usage, inventoryItems, models.MovementSale, "")
+		for i := range movements {
+			movements[i].OrderID = orderID
+		}
+		if err := recordMovements(s.movementRepo, movements...); err != nil {
+			return nil, err
 		}
-	}
 
-	// Save the updated inventory
-	if err := inventoryService.SaveItem(inventoryItems); err != nil {
-		logging.Error("Failed to save updated inventory", err)
+		after = inventoryItems
+		return inventoryItems, nil
+	})
+	if err != nil {
+		logging.Error("Failed to update inventory for order", err, "orderID", orderID)
 		return err
 	}
 
+	checkReorderPoints(before, after)
+
 	// Update the order status to closed
 	orderToUpdate.Status = "closed"
 	orderToUpdate.CreatedAt = time.Now().Format(time.RFC3339)
+	if paymentMethod != "" {
+		orderToUpdate.PaymentMethod = paymentMethod
+	}
 
 	// Save the updated order list
 	if err := s.orderRepo.SaveItems(orders); err != nil {
 		logging.Error("Failed to save updated orders after closing", err)
 		return err
 	}
+	recordSale(s.aggregationRepo, *orderToUpdate, menuItemMap)
 
 	logging.Info("Successfully closed order", "orderID", orderID)
 	return nil
 }
 
+// CancelOrder cancels an open order. Nothing was taken from stock, so
+// nothing is put back; the order is kept for the day's Z report.
+func (s *orderService) CancelOrder(orderID string) (models.Order, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to cancel order", "orderID", orderID)
+
+	now := time.Now()
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, now); err != nil {
+		return models.Order{}, err
+	}
+
+	orders, err := s.orderRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read orders", err)
+		return models.Order{}, err
+	}
+
+	for i := range orders {
+		if orders[i].ID != orderID {
+			continue
+		}
+		if orders[i].Status != "open" {
+			logging.Warn("Only an open order can be cancelled", "orderID", orderID, "status", orders[i].Status)
+			return models.Order{}, errors.New("order is " + orders[i].Status)
+		}
+
+		orders[i].Status = "cancelled"
+		orders[i].CancelledAt = now.Format(time.RFC3339)
+		if err := s.orderRepo.SaveItems(orders); err != nil {
+			logging.Error("Failed to save orders after cancelling", err)
+			return models.Order{}, err
+		}
+
+		logging.Info("Successfully cancelled order", "orderID", orderID)
+		return orders[i], nil
+	}
+
+	logging.Warn("Order not found for cancelling", "orderID", orderID)
+	return models.Order{}, errors.New("order not found")
+}
+
+// VoidOrder takes back a closed order: its sale movements are reversed,
+// putting the ingredients back in stock, and it drops out of the sales
+// totals. An order without sale movements is voided without touching the
+// stock. An order sold on a closed business day cannot be voided.
+func (s *orderService) VoidOrder(orderID string) (models.Order, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to void order", "orderID", orderID)
+
+	now := time.Now()
+	dayCloseMu.Lock()
+	defer dayCloseMu.Unlock()
+	if err := checkDayOpen(s.dayCloseRepo, now); err != nil {
+		return models.Order{}, err
+	}
+
+	orders, err := s.orderRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read orders", err)
+		return models.Order{}, err
+	}
+
+	var order *models.Order
+	for i := range orders {
+		if orders[i].ID == orderID {
+			order = &orders[i]
+			break
+		}
+	}
+	if order == nil {
+		logging.Warn("Order not found for voiding", "orderID", orderID)
+		return models.Order{}, errors.New("order not found")
+	}
+	if order.Status != "closed" {
+		logging.Warn("Only a closed order can be voided", "orderID", orderID, "status", order.Status)
+		return models.Order{}, errors.New("order is " + order.Status)
+	}
+	if soldAt, err := time.Parse(time.RFC3339, order.CreatedAt); err == nil {
+		if err := checkDayOpen(s.dayCloseRepo, soldAt); err != nil {
+			return models.Order{}, err
+		}
+	}
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return models.Order{}, err
+	}
+
+	// Find the sale movements of the order and put the ingredients back under
+	// the inventory lock, so the order's sales cannot be reversed twice
+	var reversals []models.InventoryMovement
+	err = s.inventoryRepo.UpdateItems(func(inventoryItems []models.InventoryItem) ([]models.InventoryItem, error) {
+		ledger, err := s.movementRepo.ReadItems()
+		if err != nil {
+			logging.Error("Failed to read inventory movements", err)
+			return nil, err
+		}
+		reversed := make(map[string]bool)
+		for _, movement := range ledger {
+			if movement.ReversesID != "" {
+				reversed[movement.ReversesID] = true
+			}
+		}
+		var sales []models.InventoryMovement
+		for _, movement := range ledger {
+			if movement.OrderID == orderID && movement.Type == models.MovementSale && !reversed[movement.ID] {
+				sales = append(sales, movement)
+			}
+		}
+		reversals = nil
+		if len(sales) == 0 {
+			// Nothing was taken from stock, e.g. an order closed before the ledger
+			logging.Warn("No sale movements to reverse, voiding without returning stock", "orderID", orderID)
+			return inventoryItems, nil
+		}
+
+		index := make(map[string]int)
+		for i, item := range inventoryItems {
+			index[item.IngredientID] = i
+		}
+		for _, sale := range sales {
+			i, found := index[sale.IngredientID]
+			if !found {
+				logging.Warn("Ingredient of voided order no longer in inventory", "orderID", orderID, "ingredientID", sale.IngredientID)
+				continue
+			}
+			inventoryItems[i].Quantity = roundQuantity(inventoryItems[i].Quantity - sale.Quantity)
+
+			reversal := newMovement(sale.IngredientID, models.MovementReversal, -sale.Quantity, inventoryItems[i].Quantity, "order voided")
+			reversal.OrderID = orderID
+			reversal.ReversesID = sale.ID
+			reversals = append(reversals, reversal)
+		}
+		if err := recordMovements(s.movementRepo, reversals...); err != nil {
+			return nil, err
+		}
+		return inventoryItems, nil
+	})
+	if err != nil {
+		logging.Error("Failed to return stock for voided order", err, "orderID", orderID)
+		return models.Order{}, err
+	}
+
+	order.Status = "voided"
+	order.VoidedAt = now.Format(time.RFC3339)
+	if err := s.orderRepo.SaveItems(orders); err != nil {
+		logging.Error("Failed to save orders after voiding", err)
+		return models.Order{}, err
+	}
+	removeSale(s.aggregationRepo, *order, mapMenuItems(menuItems))
+
+	logging.Info("Successfully voided order", "orderID", orderID, "reversals", len(reversals))
+	return *order, nil
+}
+
 func (s *orderService) TotalSalesCount() (map[string]int, error) {
 	orders, err := s.orderRepo.ReadClosedOrders()
 	if err != nil {
@@ -333,17 +594,44 @@ func (s *orderService) TotalSalesCount() (map[string]int, error) {
 		return nil, err
 	}
 
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return nil, err
+	}
+	menuItemMap := mapMenuItems(menuItems)
+
 	// Create a map to hold the sales count for each menu item
 	salesCount := make(map[string]int)
 
 	// Loop through orders and sum the quantities for each menu item
 	for _, order := range orders {
 		for _, item := range order.Items {
-			// Add the quantity of each item to the sales count
-			salesCount[item.ProductID] += item.Quantity
+			// Bundles count towards the products they contain
+			products, err := expandOrderItem(item, menuItemMap)
+			if err != nil {
+				logging.Warn("Skipping unresolvable order item", "productID", item.ProductID, "error", err)
+				continue
+			}
+			for _, product := range products {
+				salesCount[product.ProductID] += product.Quantity
+			}
 		}
 	}
 
 	logging.Info("Total sales count calculated", "salesCount", salesCount)
 	return salesCount, nil
 }
+
+// validatePaymentMethod accepts one of the known payment methods, or none.
+func validatePaymentMethod(paymentMethod string) error {
+	if paymentMethod == "" {
+		return nil
+	}
+	for _, method := range models.PaymentMethods {
+		if method == paymentMethod {
+			return nil
+		}
+	}
+	return errors.New("invalid payment method: " + paymentMethod)
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PricingService interface {
+	CreateRule(rule models.PricingRule) (models.PricingRule, error)
+	FetchAllRules() ([]models.PricingRule, error)
+	FindRuleByID(id string) (models.PricingRule, error)
+	UpdateRuleByID(id string, rule models.PricingRule) error
+	DeleteRuleByID(id string) error
+}
+
+type pricingService struct {
+	pricingRepo dal.PricingRuleRepository
+}
+
+func NewPricingService(pricingRepo dal.PricingRuleRepository) PricingService {
+	return &pricingService{
+		pricingRepo: pricingRepo,
+	}
+}
+
+func (s *pricingService) CreateRule(rule models.PricingRule) (models.PricingRule, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to create pricing rule", "ruleID", rule.ID)
+
+	rule.Days = normalizeDays(rule.Days)
+	if err := utils.ValidatePricingRule(rule); err != nil {
+		logging.Warn("Invalid pricing rule data", "error", err)
+		return models.PricingRule{}, err
+	}
+
+	err := s.pricingRepo.UpdateItems(func(rules []models.PricingRule) ([]models.PricingRule, error) {
+		var ids []string
+		for _, existing := range rules {
+			if existing.ID == rule.ID {
+				logging.Warn("Pricing rule with this ID already exists", "ruleID", rule.ID)
+				return nil, errors.New("pricing rule with this ID already exists")
+			}
+			ids = append(ids, existing.ID)
+		}
+		if rule.ID == "" {
+			rule.ID = utils.NextID("rule", ids)
+		}
+		return append(rules, rule), nil
+	})
+	if err != nil {
+		logging.Warn("Failed to save new pricing rule", "error", err)
+		return models.PricingRule{}, err
+	}
+
+	logging.Info("Successfully created pricing rule", "ruleID", rule.ID)
+	return rule, nil
+}
+
+func (s *pricingService) FetchAllRules() ([]models.PricingRule, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching all pricing rules")
+
+	rules, err := s.pricingRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch pricing rules", err)
+		return nil, err
+	}
+
+	logging.Info("Fetched all pricing rules", "count", len(rules))
+	return rules, nil
+}
+
+func (s *pricingService) FindRuleByID(id string) (models.PricingRule, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching pricing rule by ID", "ruleID", id)
+
+	rules, err := s.pricingRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch pricing rules", err)
+		return models.PricingRule{}, err
+	}
+
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+
+	logging.Warn("Pricing rule not found", "ruleID", id)
+	return models.PricingRule{}, errors.New("pricing rule not found")
+}
+
+func (s *pricingService) UpdateRuleByID(id string, updatedRule models.PricingRule) error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to update pricing rule", "ruleID", id)
+
+	updatedRule.Days = normalizeDays(updatedRule.Days)
+	if err := utils.ValidatePricingRule(updatedRule); err != nil {
+		logging.Warn("Invalid updated pricing rule data", "ruleID", id, "error", err)
+		return err
+	}
+
+	err := s.pricingRepo.UpdateItems(func(rules []models.PricingRule) ([]models.PricingRule, error) {
+		for i, rule := range rules {
+			if rule.ID == id {
+				updatedRule.ID = id
+				rules[i] = updatedRule
+				return rules, nil
+			}
+		}
+		logging.Warn("Pricing rule not found for update", "ruleID", id)
+		return nil, errors.New("pricing rule not found")
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Successfully updated pricing rule", "ruleID", id)
+	return nil
+}
+
+func (s *pricingService) DeleteRuleByID(id string) error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to delete pricing rule", "ruleID", id)
+
+	err := s.pricingRepo.UpdateItems(func(rules []models.PricingRule) ([]models.PricingRule, error) {
+		var updatedRules []models.PricingRule
+		for _, rule := range rules {
+			if rule.ID != id {
+				updatedRules = append(updatedRules, rule)
+			}
+		}
+		if len(updatedRules) == len(rules) {
+			logging.Warn("Pricing rule not found for deletion", "ruleID", id)
+			return nil, errors.New("pricing rule not found")
+		}
+		return updatedRules, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Successfully deleted pricing rule", "ruleID", id)
+	return nil
+}
+
+// priceOrder sets list prices, discounts and totals on every line of the
+// order. Active rules are evaluated from the highest priority down (ties by
+// rule ID); each matching rule discounts what is left of the line amount, and
+// an exclusive rule stops any further rules on that line.
+func priceOrder(order *models.Order, menuItemMap map[string]models.MenuItem, rules []models.PricingRule, at time.Time) error {
+	var activeRules []models.PricingRule
+	for _, rule := range rules {
+		if rule.Active {
+			activeRules = append(activeRules, rule)
+		}
+	}
+	sort.SliceStable(activeRules, func(i, j int) bool {
+		if activeRules[i].Priority != activeRules[j].Priority {
+			return activeRules[i].Priority > activeRules[j].Priority
+		}
+		return activeRules[i].ID < activeRules[j].ID
+	})
+
+	order.Subtotal, order.Discount, order.Total = 0, 0, 0
+	order.AppliedRules = nil
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		menuItem, exists := menuItemMap[item.ProductID]
+		if !exists {
+			return errors.New("product not found in menu: " + item.ProductID)
+		}
+
+		listAmount := float64(item.Quantity) * menuItem.Price
+		amount := listAmount
+		for _, rule := range activeRules {
+			if !ruleMatches(rule, menuItem, order.CustomerGroup, at) {
+				continue
+			}
+
+			discount := roundPrice(ruleDiscount(rule, item.Quantity, amount))
+			if discount <= 0 {
+				continue
+			}
+			amount -= discount
+			order.AppliedRules = append(order.AppliedRules, models.AppliedRule{
+				RuleID:    rule.ID,
+				Name:      rule.Name,
+				ProductID: item.ProductID,
+				Discount:  discount,
+			})
+
+			if rule.Exclusive {
+				break
+			}
+		}
+
+		item.UnitPrice = menuItem.Price
+		item.Discount = roundPrice(listAmount - amount)
+		order.Subtotal += listAmount
+		order.Discount += item.Discount
+	}
+
+	order.Subtotal = roundPrice(order.Subtotal)
+	order.Discount = roundPrice(order.Discount)
+	order.Total = roundPrice(order.Subtotal - order.Discount)
+	return nil
+}
+
+// ruleMatches checks the conditions of a rule against an order line.
+func ruleMatches(rule models.PricingRule, menuItem models.MenuItem, customerGroup string, at time.Time) bool {
+	if len(rule.ProductIDs) > 0 {
+		found := false
+		for _, productID := range rule.ProductIDs {
+			if productID == menuItem.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Category != "" && !strings.EqualFold(rule.Category, menuItem.Category) {
+		return false
+	}
+	if rule.CustomerGroup != "" && !strings.EqualFold(rule.CustomerGroup, customerGroup) {
+		return false
+	}
+
+	if len(rule.Days) > 0 {
+		today := strings.ToLower(at.Weekday().String())
+		found := false
+		for _, day := range rule.Days {
+			if strings.EqualFold(day, today) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if rule.StartTime != "" && rule.EndTime != "" {
+		start, errStart := time.Parse("15:04", rule.StartTime)
+		end, errEnd := time.Parse("15:04", rule.EndTime)
+		if errStart != nil || errEnd != nil {
+			return false
+		}
+		minute := at.Hour()*60 + at.Minute()
+		startMinute := start.Hour()*60 + start.Minute()
+		endMinute := end.Hour()*60 + end.Minute()
+
+		// Windows such as 22:00-02:00 wrap around midnight
+		if startMinute <= endMinute {
+			return minute >= startMinute && minute < endMinute
+		}
+		return minute >= startMinute || minute < endMinute
+	}
+
+	return true
+}
+
+// ruleDiscount returns how much a rule takes off the remaining line amount.
+func ruleDiscount(rule models.PricingRule, quantity int, amount float64) float64 {
+	if quantity <= 0 || amount <= 0 {
+		return 0
+	}
+
+	switch rule.Type {
+	case models.RuleTypePercentOff:
+		return amount * rule.PercentOff / 100
+	case models.RuleTypeFixedPrice:
+		return math.Max(0, amount-rule.FixedPrice*float64(quantity))
+	case models.RuleTypeBuyNGetOne:
+		free := quantity / (rule.BuyQuantity + 1)
+		return float64(free) * amount / float64(quantity)
+	}
+	return 0
+}
+
+// roundPrice rounds an amount to whole cents.
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// normalizeDays lower-cases the weekdays of a pricing rule, which are
+// matched against lower-case weekday names.
+func normalizeDays(days []string) []string {
+	var normalized []string
+	for _, day := range days {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(day)))
+	}
+	return normalized
+}
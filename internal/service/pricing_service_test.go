@@ -0,0 +1,193 @@
+package service
+
+import (
+	"hot-coffee/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useTempData points every data file at an empty directory for the length of
+// the test.
+func useTempData(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[*string]string{
+		&config.OrdersFile:      "order.json",
+		&config.MenuFile:        "menu_item.json",
+		&config.InventoryFile:   "inventory.json",
+		&config.AggregationFile: "aggregation.json",
+		&config.PricingFile:     "pricing_rules.json",
+		&config.MovementsFile:   "inventory_movements.json",
+		&config.ReceiptsFile:    "goods_receipts.json",
+		&config.WasteFile:       "waste.json",
+		&config.CountsFile:      "stock_counts.json",
+		&config.SuppliersFile:   "suppliers.json",
+		&config.PurchaseFile:    "purchase_orders.json",
+		&config.DayCloseFile:    "day_closes.json",
+	}
+	for path, name := range files {
+		previous := *path
+		*path = filepath.Join(dir, name)
+		t.Cleanup(func() { *path = previous })
+	}
+}
+
+func TestPriceOrder(t *testing.T) {
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "latte", Price: 4, Category: "Coffee"},
+		{ID: "muffin", Price: 3, Category: "Bakery"},
+	})
+	// A Monday at 08:30
+	morning := time.Date(2026, 1, 5, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		items         []models.OrderItem
+		customerGroup string
+		rules         []models.PricingRule
+		wantDiscounts map[string]float64
+		wantTotal     float64
+		wantApplied   []string
+	}{
+		{
+			name:          "no rules",
+			items:         []models.OrderItem{{ProductID: "latte", Quantity: 2}},
+			wantDiscounts: map[string]float64{"latte": 0},
+			wantTotal:     8,
+		},
+		{
+			name:  "percent off a category",
+			items: []models.OrderItem{{ProductID: "latte", Quantity: 2}, {ProductID: "muffin", Quantity: 1}},
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypePercentOff, Active: true, Category: "coffee", PercentOff: 25},
+			},
+			wantDiscounts: map[string]float64{"latte": 2, "muffin": 0},
+			wantTotal:     9,
+			wantApplied:   []string{"rule1"},
+		},
+		{
+			name:  "rules stack on what is left, highest priority first",
+			items: []models.OrderItem{{ProductID: "latte", Quantity: 1}},
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypePercentOff, Active: true, Priority: 1, PercentOff: 50},
+				{ID: "rule2", Type: models.RuleTypeFixedPrice, Active: true, Priority: 2, FixedPrice: 3},
+			},
+			wantDiscounts: map[string]float64{"latte": 2.5},
+			wantTotal:     1.5,
+			wantApplied:   []string{"rule2", "rule1"},
+		},
+		{
+			name:  "exclusive rule stops the rest",
+			items: []models.OrderItem{{ProductID: "latte", Quantity: 1}},
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypePercentOff, Active: true, Priority: 1, PercentOff: 50},
+				{ID: "rule2", Type: models.RuleTypeFixedPrice, Active: true, Priority: 2, Exclusive: true, FixedPrice: 3},
+			},
+			wantDiscounts: map[string]float64{"latte": 1},
+			wantTotal:     3,
+			wantApplied:   []string{"rule2"},
+		},
+		{
+			name:  "buy two get one",
+			items: []models.OrderItem{{ProductID: "muffin", Quantity: 7}},
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypeBuyNGetOne, Active: true, BuyQuantity: 2},
+			},
+			wantDiscounts: map[string]float64{"muffin": 6},
+			wantTotal:     15,
+			wantApplied:   []string{"rule1"},
+		},
+		{
+			name:          "customer group, weekday and time window",
+			items:         []models.OrderItem{{ProductID: "latte", Quantity: 1}},
+			customerGroup: "Staff",
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypePercentOff, Active: true, CustomerGroup: "staff", PercentOff: 10},
+				{ID: "rule2", Type: models.RuleTypePercentOff, Active: true, CustomerGroup: "students", PercentOff: 10},
+				{ID: "rule3", Type: models.RuleTypePercentOff, Active: true, Days: []string{"monday"}, StartTime: "07:00", EndTime: "09:00", PercentOff: 50},
+				{ID: "rule4", Type: models.RuleTypePercentOff, Active: true, Days: []string{"tuesday"}, PercentOff: 50},
+				{ID: "rule5", Type: models.RuleTypePercentOff, Active: true, StartTime: "22:00", EndTime: "08:00", PercentOff: 50},
+			},
+			wantDiscounts: map[string]float64{"latte": 2.2},
+			wantTotal:     1.8,
+			wantApplied:   []string{"rule1", "rule3"},
+		},
+		{
+			name:  "inactive rule",
+			items: []models.OrderItem{{ProductID: "latte", Quantity: 1}},
+			rules: []models.PricingRule{
+				{ID: "rule1", Type: models.RuleTypePercentOff, PercentOff: 50},
+			},
+			wantDiscounts: map[string]float64{"latte": 0},
+			wantTotal:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{Items: tt.items, CustomerGroup: tt.customerGroup}
+			if err := priceOrder(&order, menuItemMap, tt.rules, morning); err != nil {
+				t.Fatalf("priceOrder failed: %v", err)
+			}
+
+			for _, item := range order.Items {
+				if item.UnitPrice != menuItemMap[item.ProductID].Price {
+					t.Errorf("%s unit price = %v, want %v", item.ProductID, item.UnitPrice, menuItemMap[item.ProductID].Price)
+				}
+				if item.Discount != tt.wantDiscounts[item.ProductID] {
+					t.Errorf("%s discount = %v, want %v", item.ProductID, item.Discount, tt.wantDiscounts[item.ProductID])
+				}
+			}
+			if order.Total != tt.wantTotal || order.Total != roundPrice(order.Subtotal-order.Discount) {
+				t.Errorf("totals = %v - %v = %v, want a total of %v", order.Subtotal, order.Discount, order.Total, tt.wantTotal)
+			}
+			var applied []string
+			for _, rule := range order.AppliedRules {
+				applied = append(applied, rule.RuleID)
+			}
+			if len(applied) != len(tt.wantApplied) {
+				t.Fatalf("applied rules = %v, want %v", applied, tt.wantApplied)
+			}
+			for i := range applied {
+				if applied[i] != tt.wantApplied[i] {
+					t.Errorf("applied rules = %v, want %v", applied, tt.wantApplied)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateRuleConcurrently(t *testing.T) {
+	useTempData(t)
+	service := NewPricingService(&dal.PricingRuleService{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rule := models.PricingRule{Name: "Happy hour", Type: models.RuleTypePercentOff, Active: true, PercentOff: 10}
+			if _, err := service.CreateRule(rule); err != nil {
+				t.Errorf("CreateRule failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	rules, err := service.FetchAllRules()
+	if err != nil {
+		t.Fatalf("FetchAllRules failed: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, rule := range rules {
+		ids[rule.ID] = true
+	}
+	if len(rules) != 20 || len(ids) != 20 {
+		t.Errorf("got %d rules with %d distinct IDs, want 20 of each", len(rules), len(ids))
+	}
+}
@@ -0,0 +1,595 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"time"
+)
+
+type ProcurementService interface {
+	CreateSupplier(supplier models.Supplier) (models.Supplier, error)
+	FetchAllSuppliers() ([]models.Supplier, error)
+	FindSupplierByID(id string) (models.Supplier, error)
+	UpdateSupplierByID(id string, supplier models.Supplier) error
+	DeleteSupplierByID(id string) error
+
+	CreatePurchaseOrder(order models.PurchaseOrder) (models.PurchaseOrder, error)
+	FetchPurchaseOrders(status string) ([]models.PurchaseOrder, error)
+	FindPurchaseOrderByID(id string) (models.PurchaseOrder, error)
+	UpdatePurchaseOrder(id string, order models.PurchaseOrder) (models.PurchaseOrder, error)
+	DeletePurchaseOrder(id string) error
+	SendPurchaseOrder(id string) (models.PurchaseOrder, error)
+	CancelPurchaseOrder(id string) (models.PurchaseOrder, error)
+	ReceivePurchaseOrder(id string, receipt models.PurchaseOrderReceipt) (models.PurchaseOrder, error)
+}
+
+type procurementService struct {
+	supplierRepo  dal.SupplierRepository
+	purchaseRepo  dal.PurchaseOrderRepository
+	inventoryRepo dal.InventoryRepository
+	inventory     InventoryService
+}
+
+func NewProcurementService(supplierRepo dal.SupplierRepository, purchaseRepo dal.PurchaseOrderRepository, inventoryRepo dal.InventoryRepository, inventory InventoryService) ProcurementService {
+	return &procurementService{
+		supplierRepo:  supplierRepo,
+		purchaseRepo:  purchaseRepo,
+		inventoryRepo: inventoryRepo,
+		inventory:     inventory,
+	}
+}
+
+func (s *procurementService) CreateSupplier(supplier models.Supplier) (models.Supplier, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to create supplier", "supplierID", supplier.ID)
+
+	if err := s.validateSupplier(&supplier); err != nil {
+		logging.Warn("Invalid supplier data", "error", err)
+		return models.Supplier{}, err
+	}
+
+	err := s.supplierRepo.UpdateItems(func(suppliers []models.Supplier) ([]models.Supplier, error) {
+		var ids []string
+		for _, existing := range suppliers {
+			if existing.ID == supplier.ID {
+				logging.Warn("Supplier with this ID already exists", "supplierID", supplier.ID)
+				return nil, errors.New("supplier with this ID already exists")
+			}
+			ids = append(ids, existing.ID)
+		}
+		if supplier.ID == "" {
+			supplier.ID = utils.NextID("supplier", ids)
+		}
+		return append(suppliers, supplier), nil
+	})
+	if err != nil {
+		logging.Warn("Failed to save new supplier", "error", err)
+		return models.Supplier{}, err
+	}
+
+	logging.Info("Successfully created supplier", "supplierID", supplier.ID)
+	return supplier, nil
+}
+
+func (s *procurementService) FetchAllSuppliers() ([]models.Supplier, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching all suppliers")
+
+	suppliers, err := s.supplierRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch suppliers", err)
+		return nil, err
+	}
+
+	logging.Info("Fetched all suppliers", "count", len(suppliers))
+	return suppliers, nil
+}
+
+func (s *procurementService) FindSupplierByID(id string) (models.Supplier, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching supplier by ID", "supplierID", id)
+
+	suppliers, err := s.supplierRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch suppliers", err)
+		return models.Supplier{}, err
+	}
+
+	for _, supplier := range suppliers {
+		if supplier.ID == id {
+			return supplier, nil
+		}
+	}
+
+	logging.Warn("Supplier not found", "supplierID", id)
+	return models.Supplier{}, errors.New("supplier not found")
+}
+
+func (s *procurementService) UpdateSupplierByID(id string, updatedSupplier models.Supplier) error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to update supplier", "supplierID", id)
+
+	if err := s.validateSupplier(&updatedSupplier); err != nil {
+		logging.Warn("Invalid updated supplier data", "supplierID", id, "error", err)
+		return err
+	}
+
+	err := s.supplierRepo.UpdateItems(func(suppliers []models.Supplier) ([]models.Supplier, error) {
+		for i, supplier := range suppliers {
+			if supplier.ID == id {
+				updatedSupplier.ID = id
+				suppliers[i] = updatedSupplier
+				return suppliers, nil
+			}
+		}
+		logging.Warn("Supplier not found for update", "supplierID", id)
+		return nil, errors.New("supplier not found")
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Successfully updated supplier", "supplierID", id)
+	return nil
+}
+
+// DeleteSupplierByID removes a supplier that has no purchase orders in
+// progress. The check and the removal run under the purchase order lock, so
+// no order can be created for the supplier in between.
+func (s *procurementService) DeleteSupplierByID(id string) error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to delete supplier", "supplierID", id)
+
+	err := s.purchaseRepo.UpdateItems(func(orders []models.PurchaseOrder) ([]models.PurchaseOrder, error) {
+		for _, order := range orders {
+			if order.SupplierID == id && order.Status != models.POReceived && order.Status != models.POCancelled {
+				logging.Warn("Supplier has purchase orders in progress", "supplierID", id, "poID", order.ID)
+				return nil, errors.New("supplier has purchase orders in progress: " + order.ID)
+			}
+		}
+
+		err := s.supplierRepo.UpdateItems(func(suppliers []models.Supplier) ([]models.Supplier, error) {
+			var updatedSuppliers []models.Supplier
+			for _, supplier := range suppliers {
+				if supplier.ID != id {
+					updatedSuppliers = append(updatedSuppliers, supplier)
+				}
+			}
+			if len(updatedSuppliers) == len(suppliers) {
+				logging.Warn("Supplier not found for deletion", "supplierID", id)
+				return nil, errors.New("supplier not found")
+			}
+			return updatedSuppliers, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return orders, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Successfully deleted supplier", "supplierID", id)
+	return nil
+}
+
+// validateSupplier checks the supplier and that every catalogue item is an
+// ingredient whose pack unit converts to the stock unit.
+func (s *procurementService) validateSupplier(supplier *models.Supplier) error {
+	if err := utils.ValidateSupplier(*supplier); err != nil {
+		return err
+	}
+
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return err
+	}
+	inventoryMap := mapInventoryItems(items)
+
+	for i := range supplier.Catalogue {
+		catalogueItem := &supplier.Catalogue[i]
+		item, found := inventoryMap[catalogueItem.IngredientID]
+		if !found {
+			return errors.New("ingredient not found in inventory: " + catalogueItem.IngredientID)
+		}
+		if catalogueItem.PackUnit == "" {
+			catalogueItem.PackUnit = item.Unit
+		}
+		if _, err := utils.ConvertQuantity(catalogueItem.PackSize, catalogueItem.PackUnit, item.Unit, item.CustomUnits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *procurementService) CreatePurchaseOrder(order models.PurchaseOrder) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to create purchase order", "supplierID", order.SupplierID)
+
+	if err := s.priceLines(&order); err != nil {
+		logging.Warn("Invalid purchase order data", "error", err)
+		return models.PurchaseOrder{}, err
+	}
+
+	order.Status = models.PODraft
+	order.CreatedAt = time.Now().Format(time.RFC3339)
+	order.SentAt, order.ExpectedAt, order.ReceivedAt = "", "", ""
+	order.ReceiptIDs = nil
+
+	// The supplier is checked again under the purchase order lock, which a
+	// supplier is deleted under
+	err := s.purchaseRepo.UpdateItems(func(orders []models.PurchaseOrder) ([]models.PurchaseOrder, error) {
+		if _, err := s.FindSupplierByID(order.SupplierID); err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, existing := range orders {
+			ids = append(ids, existing.ID)
+		}
+		order.ID = utils.NextID("po", ids)
+		return append(orders, order), nil
+	})
+	if err != nil {
+		logging.Warn("Failed to save new purchase order", "error", err)
+		return models.PurchaseOrder{}, err
+	}
+
+	logging.Info("Successfully created purchase order", "poID", order.ID)
+	return order, nil
+}
+
+// FetchPurchaseOrders lists the purchase orders, optionally only those with
+// the given status.
+func (s *procurementService) FetchPurchaseOrders(status string) ([]models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching purchase orders", "status", status)
+
+	orders, err := s.purchaseRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch purchase orders", err)
+		return nil, err
+	}
+
+	filtered := []models.PurchaseOrder{}
+	for _, order := range orders {
+		if status == "" || order.Status == status {
+			filtered = append(filtered, order)
+		}
+	}
+
+	logging.Info("Fetched purchase orders", "count", len(filtered))
+	return filtered, nil
+}
+
+func (s *procurementService) FindPurchaseOrderByID(id string) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching purchase order by ID", "poID", id)
+
+	orders, err := s.purchaseRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to fetch purchase orders", err)
+		return models.PurchaseOrder{}, err
+	}
+
+	for _, order := range orders {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+
+	logging.Warn("Purchase order not found", "poID", id)
+	return models.PurchaseOrder{}, errors.New("purchase order not found")
+}
+
+// UpdatePurchaseOrder replaces the supplier, lines and note of a draft.
+func (s *procurementService) UpdatePurchaseOrder(id string, updatedOrder models.PurchaseOrder) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to update purchase order", "poID", id)
+
+	if err := s.priceLines(&updatedOrder); err != nil {
+		logging.Warn("Invalid updated purchase order data", "poID", id, "error", err)
+		return models.PurchaseOrder{}, err
+	}
+
+	return s.changeStatus(id, func(order *models.PurchaseOrder) error {
+		if order.Status != models.PODraft {
+			logging.Warn("Only a draft purchase order can be changed", "poID", id, "status", order.Status)
+			return errors.New("only a draft purchase order can be changed")
+		}
+
+		order.SupplierID = updatedOrder.SupplierID
+		order.Lines = updatedOrder.Lines
+		order.Total = updatedOrder.Total
+		order.Note = updatedOrder.Note
+		return nil
+	})
+}
+
+// DeletePurchaseOrder removes a draft. Sent orders are cancelled instead so
+// that the supplier's copy can still be traced.
+func (s *procurementService) DeletePurchaseOrder(id string) error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to delete purchase order", "poID", id)
+
+	err := s.purchaseRepo.UpdateItems(func(orders []models.PurchaseOrder) ([]models.PurchaseOrder, error) {
+		var updatedOrders []models.PurchaseOrder
+		for _, order := range orders {
+			if order.ID == id {
+				if order.Status != models.PODraft {
+					logging.Warn("Only a draft purchase order can be deleted", "poID", id, "status", order.Status)
+					return nil, errors.New("only a draft purchase order can be deleted")
+				}
+				continue
+			}
+			updatedOrders = append(updatedOrders, order)
+		}
+		if len(updatedOrders) == len(orders) {
+			logging.Warn("Purchase order not found for deletion", "poID", id)
+			return nil, errors.New("purchase order not found")
+		}
+		return updatedOrders, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Successfully deleted purchase order", "poID", id)
+	return nil
+}
+
+// SendPurchaseOrder marks a draft as sent and sets when it is expected, from
+// the supplier's lead time.
+func (s *procurementService) SendPurchaseOrder(id string) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to send purchase order", "poID", id)
+
+	return s.changeStatus(id, func(order *models.PurchaseOrder) error {
+		if order.Status != models.PODraft {
+			return errors.New("only a draft purchase order can be sent")
+		}
+		supplier, err := s.FindSupplierByID(order.SupplierID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		order.Status = models.POSent
+		order.SentAt = now.Format(time.RFC3339)
+		order.ExpectedAt = now.AddDate(0, 0, supplier.LeadTimeDays).Format(time.RFC3339)
+		return nil
+	})
+}
+
+// CancelPurchaseOrder cancels an order that has not been received yet.
+func (s *procurementService) CancelPurchaseOrder(id string) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to cancel purchase order", "poID", id)
+
+	return s.changeStatus(id, func(order *models.PurchaseOrder) error {
+		if order.Status != models.PODraft && order.Status != models.POSent {
+			return errors.New("purchase order is " + order.Status)
+		}
+		order.Status = models.POCancelled
+		return nil
+	})
+}
+
+// ReceivePurchaseOrder books a delivery against a sent purchase order as a
+// goods receipt, at the ordered pack prices. Deliveries can come in parts;
+// the order is received once every line has arrived in full. The order is
+// checked and the stock booked under the purchase order lock, so neither a
+// cancellation nor another delivery can slip in between. A delivery whose
+// invoice was already booked against the order is not booked again, nor is
+// one for an order that receipts missing from it turn out to complete.
+func (s *procurementService) ReceivePurchaseOrder(id string, delivery models.PurchaseOrderReceipt) (models.PurchaseOrder, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to receive purchase order", "poID", id)
+
+	return s.changeStatus(id, func(order *models.PurchaseOrder) error {
+		if order.Status != models.POSent && order.Status != models.POPartiallyReceived {
+			logging.Warn("Purchase order cannot be received", "poID", id, "status", order.Status)
+			return errors.New("only a sent purchase order can be received")
+		}
+
+		// Receipts booked for the order but not saved on it, when saving the
+		// order failed after the stock was booked, count as received
+		receipts, err := s.inventory.GetReceipts(time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		booked := make(map[string]bool)
+		for _, receiptID := range order.ReceiptIDs {
+			booked[receiptID] = true
+		}
+		for _, receipt := range receipts {
+			if receipt.PurchaseOrderID != order.ID {
+				continue
+			}
+			if !booked[receipt.ID] {
+				logging.Warn("Recording receipt missing from purchase order", "poID", id, "receiptID", receipt.ID)
+				applyReceipt(order, receipt)
+			}
+			if delivery.InvoiceRef != "" && receipt.InvoiceRef == delivery.InvoiceRef {
+				logging.Warn("Delivery already received", "poID", id, "invoiceRef", delivery.InvoiceRef, "receiptID", receipt.ID)
+				return nil
+			}
+		}
+		if order.Status == models.POReceived {
+			// Save the recovered order; this delivery has nothing left to fill
+			logging.Warn("Purchase order completed by recovered receipts", "poID", id)
+			return nil
+		}
+
+		supplier, err := s.FindSupplierByID(order.SupplierID)
+		if err != nil {
+			return err
+		}
+
+		// Without lines, everything still outstanding has arrived
+		lines := delivery.Lines
+		if len(lines) == 0 {
+			for _, line := range order.Lines {
+				if outstanding := line.Packs - line.ReceivedPacks; outstanding > 0 {
+					lines = append(lines, models.ReceivedPackLine{IngredientID: line.IngredientID, Packs: outstanding})
+				}
+			}
+		}
+
+		receipt := models.GoodsReceipt{
+			Supplier:        supplier.Name,
+			InvoiceRef:      delivery.InvoiceRef,
+			ReceivedBy:      delivery.ReceivedBy,
+			PurchaseOrderID: order.ID,
+		}
+		received := make(map[string]float64)
+		for _, delivered := range lines {
+			line := findPurchaseOrderLine(order, delivered.IngredientID)
+			if line == nil {
+				return errors.New("ingredient is not on this purchase order: " + delivered.IngredientID)
+			}
+			if delivered.Packs <= 0 {
+				return errors.New("received packs must be greater than zero: " + delivered.IngredientID)
+			}
+			received[line.IngredientID] += delivered.Packs
+			if line.ReceivedPacks+received[line.IngredientID] > line.Packs {
+				return errors.New("more packs received than ordered: " + delivered.IngredientID)
+			}
+
+			receipt.Lines = append(receipt.Lines, models.GoodsReceiptLine{
+				IngredientID: line.IngredientID,
+				Quantity:     delivered.Packs * line.PackSize,
+				Unit:         line.PackUnit,
+				UnitCost:     line.PackPrice / line.PackSize,
+				ExpiresAt:    delivered.ExpiresAt,
+			})
+		}
+
+		stored, err := s.inventory.ReceiveStock(receipt)
+		if err != nil {
+			return err
+		}
+		applyReceipt(order, stored)
+		return nil
+	})
+}
+
+// applyReceipt adds the packs of a goods receipt to the lines of its
+// purchase order and moves the order on.
+func applyReceipt(order *models.PurchaseOrder, receipt models.GoodsReceipt) {
+	for _, received := range receipt.Lines {
+		if line := findPurchaseOrderLine(order, received.IngredientID); line != nil {
+			line.ReceivedPacks = roundQuantity(line.ReceivedPacks + received.Quantity/line.PackSize)
+		}
+	}
+
+	complete := true
+	for _, line := range order.Lines {
+		if line.ReceivedPacks < line.Packs {
+			complete = false
+		}
+	}
+	order.ReceiptIDs = append(order.ReceiptIDs, receipt.ID)
+	order.Status = models.POPartiallyReceived
+	if complete {
+		order.Status = models.POReceived
+		order.ReceivedAt = receipt.ReceivedAt
+	}
+}
+
+func findPurchaseOrderLine(order *models.PurchaseOrder, ingredientID string) *models.PurchaseOrderLine {
+	for i := range order.Lines {
+		if order.Lines[i].IngredientID == ingredientID {
+			return &order.Lines[i]
+		}
+	}
+	return nil
+}
+
+// changeStatus applies a change to one purchase order and saves it, under
+// the purchase order lock.
+func (s *procurementService) changeStatus(id string, change func(order *models.PurchaseOrder) error) (models.PurchaseOrder, error) {
+	var changed models.PurchaseOrder
+	err := s.purchaseRepo.UpdateItems(func(orders []models.PurchaseOrder) ([]models.PurchaseOrder, error) {
+		for i := range orders {
+			if orders[i].ID != id {
+				continue
+			}
+			if err := change(&orders[i]); err != nil {
+				logging.Warn("Failed to change purchase order", "poID", id, "error", err)
+				return nil, err
+			}
+			changed = orders[i]
+			return orders, nil
+		}
+
+		logging.Warn("Purchase order not found", "poID", id)
+		return nil, errors.New("purchase order not found")
+	})
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+
+	logging.Info("Successfully changed purchase order", "poID", id, "status", changed.Status)
+	return changed, nil
+}
+
+// priceLines fills the pack size, unit and price of every line from the
+// supplier's catalogue and totals the order.
+func (s *procurementService) priceLines(order *models.PurchaseOrder) error {
+	supplier, err := s.FindSupplierByID(order.SupplierID)
+	if err != nil {
+		return err
+	}
+	if len(order.Lines) == 0 {
+		return errors.New("purchase order must have at least one line")
+	}
+
+	catalogue := make(map[string]models.CatalogueItem)
+	for _, item := range supplier.Catalogue {
+		catalogue[item.IngredientID] = item
+	}
+
+	seen := make(map[string]bool)
+	order.Total = 0
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		item, found := catalogue[line.IngredientID]
+		if !found {
+			return errors.New("ingredient is not in the supplier's catalogue: " + line.IngredientID)
+		}
+		if seen[line.IngredientID] {
+			return errors.New("duplicate purchase order line: " + line.IngredientID)
+		}
+		seen[line.IngredientID] = true
+		if line.Packs <= 0 {
+			return errors.New("ordered packs must be greater than zero: " + line.IngredientID)
+		}
+
+		line.PackSize = item.PackSize
+		line.PackUnit = item.PackUnit
+		line.PackPrice = item.PackPrice
+		line.ReceivedPacks = 0
+		order.Total += line.Packs * line.PackPrice
+	}
+	order.Total = roundPrice(order.Total)
+	return nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"testing"
+)
+
+func TestReceivePurchaseOrder(t *testing.T) {
+	useTempData(t)
+	inventoryRepo := &dal.InventoryItemService{}
+	inventory := NewInventoryService(inventoryRepo, &dal.MovementService{}, &dal.ReceiptService{}, &dal.MenuItemService{})
+	service := NewProcurementService(&dal.SupplierService{}, &dal.PurchaseOrderService{}, inventoryRepo, inventory)
+	for _, item := range []models.InventoryItem{
+		{IngredientID: "milk", Name: "Milk", Unit: "ml"},
+		{IngredientID: "beans", Name: "Beans", Unit: "g"},
+	} {
+		if err := inventory.AddInventoryItem(item); err != nil {
+			t.Fatalf("AddInventoryItem failed: %v", err)
+		}
+	}
+
+	supplier, err := service.CreateSupplier(models.Supplier{
+		Name:         "Dairy & Beans",
+		LeadTimeDays: 2,
+		Catalogue: []models.CatalogueItem{
+			{IngredientID: "milk", PackSize: 1, PackUnit: "l", PackPrice: 2},
+			{IngredientID: "beans", PackSize: 1000, PackPrice: 20},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSupplier failed: %v", err)
+	}
+	if _, err := service.CreatePurchaseOrder(models.PurchaseOrder{
+		SupplierID: supplier.ID,
+		Lines:      []models.PurchaseOrderLine{{IngredientID: "sugar", Packs: 1}},
+	}); err == nil {
+		t.Error("CreatePurchaseOrder succeeded for an ingredient outside the catalogue")
+	}
+
+	order, err := service.CreatePurchaseOrder(models.PurchaseOrder{
+		SupplierID: supplier.ID,
+		Lines: []models.PurchaseOrderLine{
+			{IngredientID: "milk", Packs: 4},
+			{IngredientID: "beans", Packs: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreatePurchaseOrder failed: %v", err)
+	}
+	if order.Status != models.PODraft || order.Total != 48 {
+		t.Errorf("new order = %s with a total of %v, want %s with a total of 48", order.Status, order.Total, models.PODraft)
+	}
+	if err := service.DeleteSupplierByID(supplier.ID); err == nil {
+		t.Error("DeleteSupplierByID succeeded for a supplier with a draft purchase order")
+	}
+	if _, err := service.ReceivePurchaseOrder(order.ID, models.PurchaseOrderReceipt{}); err == nil {
+		t.Error("ReceivePurchaseOrder succeeded on a draft order")
+	}
+	if _, err := service.SendPurchaseOrder(order.ID); err != nil {
+		t.Fatalf("SendPurchaseOrder failed: %v", err)
+	}
+
+	stock := func() map[string]models.InventoryItem {
+		t.Helper()
+		items, err := inventoryRepo.ReadItem()
+		if err != nil {
+			t.Fatalf("ReadItem failed: %v", err)
+		}
+		return mapInventoryItems(items)
+	}
+
+	// A partial delivery, booked in stock units at the pack price
+	partial := models.PurchaseOrderReceipt{
+		ReceivedBy: "aida",
+		InvoiceRef: "INV-1",
+		Lines:      []models.ReceivedPackLine{{IngredientID: "milk", Packs: 1}},
+	}
+	order, err = service.ReceivePurchaseOrder(order.ID, partial)
+	if err != nil {
+		t.Fatalf("ReceivePurchaseOrder failed: %v", err)
+	}
+	if order.Status != models.POPartiallyReceived || order.Lines[0].ReceivedPacks != 1 {
+		t.Errorf("order after a partial delivery = %s with %v milk packs, want %s with 1", order.Status, order.Lines[0].ReceivedPacks, models.POPartiallyReceived)
+	}
+	if milk := stock()["milk"]; milk.Quantity != 1000 || milk.UnitCost != 0.002 {
+		t.Errorf("milk = %v ml at %v, want 1000 ml at 0.002", milk.Quantity, milk.UnitCost)
+	}
+
+	// The same invoice is not booked twice
+	order, err = service.ReceivePurchaseOrder(order.ID, partial)
+	if err != nil {
+		t.Fatalf("ReceivePurchaseOrder failed: %v", err)
+	}
+	if len(order.ReceiptIDs) != 1 || stock()["milk"].Quantity != 1000 {
+		t.Errorf("a repeated delivery was booked: receipts %v, %v ml of milk", order.ReceiptIDs, stock()["milk"].Quantity)
+	}
+
+	if _, err := service.ReceivePurchaseOrder(order.ID, models.PurchaseOrderReceipt{
+		ReceivedBy: "aida",
+		InvoiceRef: "INV-2",
+		Lines:      []models.ReceivedPackLine{{IngredientID: "beans", Packs: 3}},
+	}); err == nil {
+		t.Error("ReceivePurchaseOrder succeeded for more packs than ordered")
+	}
+
+	// Without lines the rest arrives
+	order, err = service.ReceivePurchaseOrder(order.ID, models.PurchaseOrderReceipt{ReceivedBy: "aida", InvoiceRef: "INV-2"})
+	if err != nil {
+		t.Fatalf("ReceivePurchaseOrder failed: %v", err)
+	}
+	if order.Status != models.POReceived || len(order.ReceiptIDs) != 2 || order.ReceivedAt == "" {
+		t.Errorf("order after the last delivery = %s with receipts %v, want %s with 2", order.Status, order.ReceiptIDs, models.POReceived)
+	}
+	items := stock()
+	if items["milk"].Quantity != 4000 || items["beans"].Quantity != 2000 || items["beans"].UnitCost != 0.02 {
+		t.Errorf("stock = %v ml of milk, %v g of beans at %v, want 4000, 2000 at 0.02", items["milk"].Quantity, items["beans"].Quantity, items["beans"].UnitCost)
+	}
+
+	if _, err := service.CancelPurchaseOrder(order.ID); err == nil {
+		t.Error("CancelPurchaseOrder succeeded on a received order")
+	}
+	if err := service.DeleteSupplierByID(supplier.ID); err != nil {
+		t.Errorf("DeleteSupplierByID failed once its orders were received: %v", err)
+	}
+}
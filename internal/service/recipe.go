@@ -0,0 +1,482 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"sort"
+)
+
+// soldProduct is a product as it actually left the counter. Bundles are
+// resolved into the products they contain, so reports and inventory only
+// ever see real recipes.
+type soldProduct struct {
+	ProductID string
+	Quantity  int
+	Revenue   float64
+}
+
+// mapMenuItems indexes menu items by their product ID.
+func mapMenuItems(items []models.MenuItem) map[string]models.MenuItem {
+	menuItemMap := make(map[string]models.MenuItem)
+	for _, item := range items {
+		menuItemMap[item.ID] = item
+	}
+	return menuItemMap
+}
+
+// expandOrderItem resolves an order line into the products that were sold.
+// A plain menu item comes back as is; a bundle is split into its components
+// and the bundle revenue is shared between them in proportion to their list
+// prices.
+func expandOrderItem(orderItem models.OrderItem, menuItemMap map[string]models.MenuItem) ([]soldProduct, error) {
+	menuItem, exists := menuItemMap[orderItem.ProductID]
+	if !exists {
+		return nil, errors.New("product not found in menu: " + orderItem.ProductID)
+	}
+
+	revenue := lineRevenue(orderItem, menuItem)
+	if !menuItem.IsBundle() {
+		return []soldProduct{{ProductID: menuItem.ID, Quantity: orderItem.Quantity, Revenue: revenue}}, nil
+	}
+
+	var products []soldProduct
+	var totalWeight float64
+	var weights []float64
+	for _, component := range menuItem.Components {
+		productID, err := resolveComponent(menuItem, component, orderItem.Selections)
+		if err != nil {
+			return nil, err
+		}
+		componentItem, exists := menuItemMap[productID]
+		if !exists {
+			return nil, errors.New("product not found in menu: " + productID)
+		}
+
+		quantity := component.Quantity * orderItem.Quantity
+		weight := componentItem.Price * float64(quantity)
+		products = append(products, soldProduct{ProductID: productID, Quantity: quantity})
+		weights = append(weights, weight)
+		totalWeight += weight
+	}
+
+	// Split the bundle revenue; fall back to an even split by quantity when
+	// the components carry no list price.
+	for i := range products {
+		if totalWeight > 0 {
+			products[i].Revenue = revenue * weights[i] / totalWeight
+		} else {
+			products[i].Revenue = revenue / float64(len(products))
+		}
+	}
+
+	return products, nil
+}
+
+// lineRevenue is what the customer paid for an order line. Orders priced by
+// the rules engine carry their own prices; older orders fall back to the
+// current menu price.
+func lineRevenue(orderItem models.OrderItem, menuItem models.MenuItem) float64 {
+	if orderItem.UnitPrice > 0 {
+		return orderItem.UnitPrice*float64(orderItem.Quantity) - orderItem.Discount
+	}
+	return float64(orderItem.Quantity) * menuItem.Price
+}
+
+// resolveComponent returns the product a bundle slot stands for on an order.
+func resolveComponent(bundle models.MenuItem, component models.BundleComponent, selections map[string]string) (string, error) {
+	if component.Group == "" {
+		return component.ProductID, nil
+	}
+
+	selected, ok := selections[component.Group]
+	if !ok {
+		// A group with a single option needs no explicit choice
+		if len(component.Choices) == 1 {
+			return component.Choices[0], nil
+		}
+		return "", fmt.Errorf("missing selection for group %s in bundle %s", component.Group, bundle.ID)
+	}
+
+	for _, choice := range component.Choices {
+		if choice == selected {
+			return selected, nil
+		}
+	}
+	return "", fmt.Errorf("product %s is not a valid choice for group %s in bundle %s", selected, component.Group, bundle.ID)
+}
+
+// validateOrderItems checks that every line of an order is on the menu and
+// that each bundle line selects a valid choice for every group, so an order
+// that could not be resolved later is never taken.
+func validateOrderItems(order models.Order, menuItemMap map[string]models.MenuItem) error {
+	for _, orderItem := range order.Items {
+		menuItem, exists := menuItemMap[orderItem.ProductID]
+		if !exists {
+			return errors.New("invalid order item: product not found in menu: " + orderItem.ProductID)
+		}
+		for _, component := range menuItem.Components {
+			if _, err := resolveComponent(menuItem, component, orderItem.Selections); err != nil {
+				return fmt.Errorf("invalid order item: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBundleComponents checks that every product referenced by a bundle
+// exists on the menu and is a plain item, so bundles never nest.
+func validateBundleComponents(bundle models.MenuItem, menuItemMap map[string]models.MenuItem) error {
+	for _, component := range bundle.Components {
+		productIDs := component.Choices
+		if component.Group == "" {
+			productIDs = []string{component.ProductID}
+		}
+
+		for _, productID := range productIDs {
+			if productID == bundle.ID {
+				return errors.New("bundle cannot contain itself")
+			}
+			componentItem, exists := menuItemMap[productID]
+			if !exists {
+				return errors.New("bundle component not found in menu: " + productID)
+			}
+			if componentItem.IsBundle() {
+				return errors.New("bundle component cannot be another bundle: " + productID)
+			}
+		}
+	}
+	return nil
+}
+
+// bundleContains reports whether the bundle can sell the given product.
+func bundleContains(bundle models.MenuItem, productID string) bool {
+	for _, component := range bundle.Components {
+		if component.ProductID == productID {
+			return true
+		}
+		for _, choice := range component.Choices {
+			if choice == productID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// menuItemCost rolls the unit costs of inventory up through the recipe of a
+// menu item. For a bundle choice group the most expensive option is costed,
+// so the reported margin is the worst case.
+func menuItemCost(menuItem models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem) (models.MenuItemCost, error) {
+	lines, err := recipeCostLines(menuItem, menuItemMap, inventoryMap, 1)
+	if err != nil {
+		return models.MenuItemCost{}, err
+	}
+
+	itemCost := models.MenuItemCost{
+		ProductID:   menuItem.ID,
+		Name:        menuItem.Name,
+		Price:       menuItem.Price,
+		Ingredients: []models.IngredientCost{},
+	}
+
+	// Merge lines for the same ingredient, keeping the recipe order
+	merged := make(map[string]int)
+	for _, line := range lines {
+		if i, exists := merged[line.IngredientID]; exists {
+			itemCost.Ingredients[i].Quantity += line.Quantity
+			itemCost.Ingredients[i].Cost += line.Cost
+			continue
+		}
+		merged[line.IngredientID] = len(itemCost.Ingredients)
+		itemCost.Ingredients = append(itemCost.Ingredients, line)
+		if line.UnitCost <= 0 {
+			itemCost.MissingCosts = append(itemCost.MissingCosts, line.IngredientID)
+		}
+	}
+
+	for i := range itemCost.Ingredients {
+		itemCost.Ingredients[i].Quantity = roundQuantity(itemCost.Ingredients[i].Quantity)
+		itemCost.Ingredients[i].Cost = roundQuantity(itemCost.Ingredients[i].Cost)
+		itemCost.Cost += itemCost.Ingredients[i].Cost
+	}
+
+	itemCost.Cost = roundPrice(itemCost.Cost)
+	itemCost.Margin = roundPrice(menuItem.Price - itemCost.Cost)
+	if menuItem.Price > 0 {
+		itemCost.MarginPercent = roundPrice(itemCost.Margin / menuItem.Price * 100)
+	}
+	return itemCost, nil
+}
+
+// recipeCostLines lists the costed ingredients of a menu item, multiplied by
+// the number of units. An ingredient missing from the inventory is listed
+// in its recipe unit, without a cost.
+func recipeCostLines(menuItem models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem, units float64) ([]models.IngredientCost, error) {
+	var lines []models.IngredientCost
+
+	if !menuItem.IsBundle() {
+		for _, ingredient := range menuItem.Ingredients {
+			inventoryItem, exists := inventoryMap[ingredient.IngredientID]
+			quantity, unit := ingredient.Quantity*units, ingredient.Unit
+			if exists {
+				perUnit, err := recipeQuantity(ingredient, inventoryItem)
+				if err != nil {
+					return nil, err
+				}
+				quantity, unit = perUnit*units, inventoryItem.Unit
+			}
+			lines = append(lines, models.IngredientCost{
+				IngredientID: ingredient.IngredientID,
+				Quantity:     quantity,
+				Unit:         unit,
+				UnitCost:     inventoryItem.UnitCost,
+				Cost:         quantity * inventoryItem.UnitCost,
+			})
+		}
+		return lines, nil
+	}
+
+	for _, component := range menuItem.Components {
+		productIDs := component.Choices
+		if component.Group == "" {
+			productIDs = []string{component.ProductID}
+		}
+
+		var chosen []models.IngredientCost
+		highest := -1.0
+		for _, productID := range productIDs {
+			componentItem, exists := menuItemMap[productID]
+			if !exists {
+				continue
+			}
+			candidate, err := recipeCostLines(componentItem, menuItemMap, inventoryMap, units*float64(component.Quantity))
+			if err != nil {
+				return nil, err
+			}
+			var total float64
+			for _, line := range candidate {
+				total += line.Cost
+			}
+			if total > highest {
+				highest = total
+				chosen = candidate
+			}
+		}
+		lines = append(lines, chosen...)
+	}
+	return lines, nil
+}
+
+// mapInventoryItems indexes inventory items by their ingredient ID.
+func mapInventoryItems(items []models.InventoryItem) map[string]models.InventoryItem {
+	inventoryMap := make(map[string]models.InventoryItem)
+	for _, item := range items {
+		inventoryMap[item.IngredientID] = item
+	}
+	return inventoryMap
+}
+
+// roundQuantity rounds ingredient quantities and costs to four decimals.
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*10000) / 10000
+}
+
+// applyDietInfo derives the allergens and nutrition of a menu item from its
+// recipe. Bundles list every allergen any of their choices may contain, and
+// their nutrition assumes the most calorific choice.
+func applyDietInfo(menuItem *models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem) error {
+	allergens, nutrition, err := recipeDietInfo(*menuItem, menuItemMap, inventoryMap, 1)
+	if err != nil {
+		return err
+	}
+
+	menuItem.Allergens = allergens
+	menuItem.Nutrition = &models.Nutrition{
+		Calories:      roundPrice(nutrition.Calories),
+		Fat:           roundPrice(nutrition.Fat),
+		Carbohydrates: roundPrice(nutrition.Carbohydrates),
+		Sugar:         roundPrice(nutrition.Sugar),
+		Protein:       roundPrice(nutrition.Protein),
+		Salt:          roundPrice(nutrition.Salt),
+	}
+	return nil
+}
+
+func recipeDietInfo(menuItem models.MenuItem, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem, units float64) ([]string, models.Nutrition, error) {
+	var allergens []string
+	var nutrition models.Nutrition
+
+	if !menuItem.IsBundle() {
+		for _, ingredient := range menuItem.Ingredients {
+			inventoryItem := inventoryMap[ingredient.IngredientID]
+			allergens = mergeAllergens(allergens, inventoryItem.Allergens)
+			if inventoryItem.Nutrition != nil {
+				quantity, err := recipeQuantity(ingredient, inventoryItem)
+				if err != nil {
+					return nil, models.Nutrition{}, err
+				}
+				addNutrition(&nutrition, *inventoryItem.Nutrition, quantity*units)
+			}
+		}
+		return allergens, nutrition, nil
+	}
+
+	for _, component := range menuItem.Components {
+		productIDs := component.Choices
+		if component.Group == "" {
+			productIDs = []string{component.ProductID}
+		}
+
+		var chosen models.Nutrition
+		found := false
+		for _, productID := range productIDs {
+			componentItem, exists := menuItemMap[productID]
+			if !exists {
+				continue
+			}
+			candidateAllergens, candidate, err := recipeDietInfo(componentItem, menuItemMap, inventoryMap, units*float64(component.Quantity))
+			if err != nil {
+				return nil, models.Nutrition{}, err
+			}
+			allergens = mergeAllergens(allergens, candidateAllergens)
+			if !found || candidate.Calories > chosen.Calories {
+				chosen = candidate
+				found = true
+			}
+		}
+		addNutrition(&nutrition, chosen, 1)
+	}
+	return allergens, nutrition, nil
+}
+
+// addNutrition adds the given nutrition values, scaled by quantity, to total.
+func addNutrition(total *models.Nutrition, perUnit models.Nutrition, quantity float64) {
+	total.Calories += perUnit.Calories * quantity
+	total.Fat += perUnit.Fat * quantity
+	total.Carbohydrates += perUnit.Carbohydrates * quantity
+	total.Sugar += perUnit.Sugar * quantity
+	total.Protein += perUnit.Protein * quantity
+	total.Salt += perUnit.Salt * quantity
+}
+
+// mergeAllergens returns the sorted union of two allergen lists.
+func mergeAllergens(allergens []string, more []string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, allergen := range append(append([]string{}, allergens...), more...) {
+		if !seen[allergen] {
+			seen[allergen] = true
+			merged = append(merged, allergen)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// ingredientQuantity converts a recipe line into the unit the ingredient is
+// stocked in.
+func ingredientQuantity(ingredient models.MenuItemIngredient, inventoryItem models.InventoryItem) (float64, error) {
+	if ingredient.Unit == "" {
+		return ingredient.Quantity, nil
+	}
+	return utils.ConvertQuantity(ingredient.Quantity, ingredient.Unit, inventoryItem.Unit, inventoryItem.CustomUnits)
+}
+
+// recipeQuantity is ingredientQuantity for calculations over a stored
+// recipe, naming the ingredient whose unit does not convert.
+func recipeQuantity(ingredient models.MenuItemIngredient, inventoryItem models.InventoryItem) (float64, error) {
+	quantity, err := ingredientQuantity(ingredient, inventoryItem)
+	if err != nil {
+		logging.Warn("Failed to convert recipe quantity", "ingredientID", ingredient.IngredientID, "error", err)
+		return 0, fmt.Errorf("invalid unit for ingredient %s: %v", ingredient.IngredientID, err)
+	}
+	return quantity, nil
+}
+
+// validateRecipeUnits checks that every ingredient of a recipe is stocked and
+// that its recipe unit converts to the stock unit.
+func validateRecipeUnits(menuItem models.MenuItem, inventoryMap map[string]models.InventoryItem) error {
+	for _, ingredient := range menuItem.Ingredients {
+		inventoryItem, exists := inventoryMap[ingredient.IngredientID]
+		if !exists {
+			return errors.New("ingredient not found in inventory: " + ingredient.IngredientID)
+		}
+		if _, err := ingredientQuantity(ingredient, inventoryItem); err != nil {
+			return fmt.Errorf("invalid unit for ingredient %s: %v", ingredient.IngredientID, err)
+		}
+	}
+	return nil
+}
+
+// ingredientUsage is an amount of one ingredient in its stock unit.
+type ingredientUsage struct {
+	IngredientID string
+	Quantity     float64
+}
+
+// recipeUsage lists the stock needed to make the given number of a plain
+// menu item.
+func recipeUsage(menuItem models.MenuItem, quantity float64, inventoryMap map[string]models.InventoryItem) ([]ingredientUsage, error) {
+	var usage []ingredientUsage
+	for _, ingredient := range menuItem.Ingredients {
+		inventoryItem, found := inventoryMap[ingredient.IngredientID]
+		if !found {
+			logging.Warn("Ingredient not found in inventory", "ingredientID", ingredient.IngredientID)
+			return nil, errors.New("ingredient not found in inventory: " + ingredient.IngredientID)
+		}
+
+		// Recipes may be written in a different unit than the stock
+		perUnit, err := ingredientQuantity(ingredient, inventoryItem)
+		if err != nil {
+			logging.Warn("Failed to convert ingredient unit", "ingredientID", ingredient.IngredientID, "error", err)
+			return nil, err
+		}
+		usage = append(usage, ingredientUsage{IngredientID: ingredient.IngredientID, Quantity: perUnit * quantity})
+	}
+	return usage, nil
+}
+
+// orderUsage lists the stock an order takes, with bundles expanded and each
+// ingredient summed once, in the order the ingredients first appear.
+func orderUsage(order models.Order, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem) ([]ingredientUsage, error) {
+	var usage []ingredientUsage
+	for _, orderItem := range order.Items {
+		// Bundles are expanded so that their components' recipes are deducted
+		products, err := expandOrderItem(orderItem, menuItemMap)
+		if err != nil {
+			logging.Warn("Failed to resolve order item", "productID", orderItem.ProductID, "error", err)
+			return nil, err
+		}
+
+		for _, product := range products {
+			productUsage, err := recipeUsage(menuItemMap[product.ProductID], float64(product.Quantity), inventoryMap)
+			if err != nil {
+				return nil, err
+			}
+			usage = mergeUsage(usage, productUsage)
+		}
+	}
+	return usage, nil
+}
+
+// mergeUsage adds more usage to a list, summing repeated ingredients.
+func mergeUsage(usage []ingredientUsage, more []ingredientUsage) []ingredientUsage {
+	for _, line := range more {
+		merged := false
+		for i := range usage {
+			if usage[i].IngredientID == line.IngredientID {
+				usage[i].Quantity += line.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			usage = append(usage, line)
+		}
+	}
+	return usage
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"hot-coffee/models"
+	"math"
+	"testing"
+)
+
+func TestExpandOrderItem(t *testing.T) {
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "latte", Price: 3},
+		{ID: "tea", Price: 2},
+		{ID: "muffin", Price: 2},
+		{ID: "water"},
+		{
+			ID:    "breakfast",
+			Price: 4,
+			Components: []models.BundleComponent{
+				{Group: "drink", Choices: []string{"latte", "tea"}, Quantity: 1},
+				{ProductID: "muffin", Quantity: 1},
+			},
+		},
+		{
+			ID:    "water_pack",
+			Price: 3,
+			Components: []models.BundleComponent{
+				{Group: "size", Choices: []string{"water"}, Quantity: 3},
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		item    models.OrderItem
+		want    map[string]soldProduct
+		wantErr bool
+	}{
+		{
+			name: "plain item",
+			item: models.OrderItem{ProductID: "latte", Quantity: 2},
+			want: map[string]soldProduct{"latte": {ProductID: "latte", Quantity: 2, Revenue: 6}},
+		},
+		{
+			name: "bundle revenue split by list price",
+			item: models.OrderItem{ProductID: "breakfast", Quantity: 2, Selections: map[string]string{"drink": "latte"}},
+			want: map[string]soldProduct{
+				"latte":  {ProductID: "latte", Quantity: 2, Revenue: 4.8},
+				"muffin": {ProductID: "muffin", Quantity: 2, Revenue: 3.2},
+			},
+		},
+		{
+			name: "bundle priced by the rules engine",
+			item: models.OrderItem{ProductID: "breakfast", Quantity: 1, Selections: map[string]string{"drink": "tea"}, UnitPrice: 4, Discount: 1},
+			want: map[string]soldProduct{
+				"tea":    {ProductID: "tea", Quantity: 1, Revenue: 1.5},
+				"muffin": {ProductID: "muffin", Quantity: 1, Revenue: 1.5},
+			},
+		},
+		{
+			name: "single choice needs no selection and unpriced components split evenly",
+			item: models.OrderItem{ProductID: "water_pack", Quantity: 1},
+			want: map[string]soldProduct{"water": {ProductID: "water", Quantity: 3, Revenue: 3}},
+		},
+		{
+			name:    "missing selection",
+			item:    models.OrderItem{ProductID: "breakfast", Quantity: 1},
+			wantErr: true,
+		},
+		{
+			name:    "choice outside the group",
+			item:    models.OrderItem{ProductID: "breakfast", Quantity: 1, Selections: map[string]string{"drink": "muffin"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown product",
+			item:    models.OrderItem{ProductID: "bagel", Quantity: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, err := expandOrderItem(tt.item, menuItemMap)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expandOrderItem(%+v) = %+v, want an error", tt.item, products)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandOrderItem(%+v) failed: %v", tt.item, err)
+			}
+
+			if len(products) != len(tt.want) {
+				t.Fatalf("products = %+v, want %+v", products, tt.want)
+			}
+			for _, product := range products {
+				want := tt.want[product.ProductID]
+				if product.ProductID != want.ProductID || product.Quantity != want.Quantity || math.Abs(product.Revenue-want.Revenue) > 1e-9 {
+					t.Errorf("product = %+v, want %+v", product, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBundleComponents(t *testing.T) {
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "latte", Price: 3},
+		{ID: "combo", Components: []models.BundleComponent{{ProductID: "latte", Quantity: 1}}},
+	})
+
+	tests := []struct {
+		name    string
+		bundle  models.MenuItem
+		wantErr bool
+	}{
+		{
+			name:   "plain components",
+			bundle: models.MenuItem{ID: "deal", Components: []models.BundleComponent{{Group: "drink", Choices: []string{"latte"}, Quantity: 1}}},
+		},
+		{
+			name:    "unknown component",
+			bundle:  models.MenuItem{ID: "deal", Components: []models.BundleComponent{{ProductID: "bagel", Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "nested bundle",
+			bundle:  models.MenuItem{ID: "deal", Components: []models.BundleComponent{{Group: "extra", Choices: []string{"latte", "combo"}, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "bundle containing itself",
+			bundle:  models.MenuItem{ID: "deal", Components: []models.BundleComponent{{ProductID: "deal", Quantity: 1}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBundleComponents(tt.bundle, menuItemMap)
+			if tt.wantErr && err == nil {
+				t.Errorf("validateBundleComponents(%s) succeeded, want an error", tt.bundle.ID)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validateBundleComponents(%s) failed: %v", tt.bundle.ID, err)
+			}
+		})
+	}
+}
+
+func TestMenuItemCost(t *testing.T) {
+	inventoryMap := map[string]models.InventoryItem{
+		"beans": {IngredientID: "beans", Unit: "g", UnitCost: 0.02},
+		"milk":  {IngredientID: "milk", Unit: "ml", UnitCost: 0.002},
+		"sugar": {IngredientID: "sugar", Unit: "g"},
+	}
+	menuItemMap := mapMenuItems([]models.MenuItem{
+		{ID: "espresso", Price: 2, Ingredients: []models.MenuItemIngredient{{IngredientID: "beans", Quantity: 18}}},
+		{ID: "latte", Price: 4, Ingredients: []models.MenuItemIngredient{
+			{IngredientID: "beans", Quantity: 18},
+			{IngredientID: "milk", Quantity: 0.2, Unit: "l"},
+		}},
+		{ID: "sweet_espresso", Price: 2.5, Ingredients: []models.MenuItemIngredient{
+			{IngredientID: "beans", Quantity: 18},
+			{IngredientID: "sugar", Quantity: 5},
+		}},
+		{ID: "double_deal", Price: 5, Components: []models.BundleComponent{
+			{Group: "drink", Choices: []string{"espresso", "latte"}, Quantity: 2},
+		}},
+		{ID: "bad_latte", Price: 4, Ingredients: []models.MenuItemIngredient{{IngredientID: "milk", Quantity: 200, Unit: "g"}}},
+	})
+
+	tests := []struct {
+		name        string
+		productID   string
+		wantCost    float64
+		wantMargin  float64
+		wantPercent float64
+		wantMissing []string
+		wantErr     bool
+	}{
+		{name: "recipe units converted to stock units", productID: "latte", wantCost: 0.76, wantMargin: 3.24, wantPercent: 81},
+		{name: "ingredient without a cost", productID: "sweet_espresso", wantCost: 0.36, wantMargin: 2.14, wantPercent: 85.6, wantMissing: []string{"sugar"}},
+		{name: "bundle costs the dearest choice", productID: "double_deal", wantCost: 1.52, wantMargin: 3.48, wantPercent: 69.6},
+		{name: "unit that does not convert", productID: "bad_latte", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itemCost, err := menuItemCost(menuItemMap[tt.productID], menuItemMap, inventoryMap)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("menuItemCost(%s) = %+v, want an error", tt.productID, itemCost)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("menuItemCost(%s) failed: %v", tt.productID, err)
+			}
+
+			if itemCost.Cost != tt.wantCost || itemCost.Margin != tt.wantMargin || itemCost.MarginPercent != tt.wantPercent {
+				t.Errorf("cost = %v, margin = %v (%v%%), want %v, %v (%v%%)",
+					itemCost.Cost, itemCost.Margin, itemCost.MarginPercent, tt.wantCost, tt.wantMargin, tt.wantPercent)
+			}
+			if len(itemCost.MissingCosts) != len(tt.wantMissing) || (len(tt.wantMissing) > 0 && itemCost.MissingCosts[0] != tt.wantMissing[0]) {
+				t.Errorf("missing costs = %v, want %v", itemCost.MissingCosts, tt.wantMissing)
+			}
+		})
+	}
+}
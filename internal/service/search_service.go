@@ -0,0 +1,67 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/search"
+	"hot-coffee/logging"
+	"hot-coffee/utils"
+)
+
+type SearchService interface {
+	BuildIndex() error
+	Search(query string, limit int) map[string][]search.Result
+}
+
+type searchService struct {
+	menuRepo      dal.MenuRepository
+	inventoryRepo dal.InventoryRepository
+	orderRepo     dal.OrderRepository
+}
+
+func NewSearchService(menuRepo dal.MenuRepository, inventoryRepo dal.InventoryRepository, orderRepo dal.OrderRepository) SearchService {
+	return &searchService{
+		menuRepo:      menuRepo,
+		inventoryRepo: inventoryRepo,
+		orderRepo:     orderRepo,
+	}
+}
+
+// BuildIndex loads every entity into the search index. After that the
+// repositories keep the index up to date on each write.
+func (s *searchService) BuildIndex() error {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Building search index")
+
+	menuItems, err := s.menuRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read menu items", err)
+		return err
+	}
+	inventoryItems, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return err
+	}
+	orders, err := s.orderRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read orders", err)
+		return err
+	}
+
+	search.IndexMenuItems(menuItems)
+	search.IndexInventoryItems(inventoryItems)
+	search.IndexOrders(orders)
+
+	logging.Info("Search index built", "menuItems", len(menuItems), "inventoryItems", len(inventoryItems), "orders", len(orders))
+	return nil
+}
+
+func (s *searchService) Search(query string, limit int) map[string][]search.Result {
+	defer utils.CatchCriticalPoint()
+
+	results := search.Search(query, limit)
+
+	logging.Info("Search completed", "query", query, "groups", len(results))
+	return results
+}
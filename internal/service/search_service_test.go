@@ -0,0 +1,92 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/search"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	useTempData(t)
+	menuRepo := &dal.MenuItemService{}
+	inventoryRepo := &dal.InventoryItemService{}
+	orderRepo := &dal.OrderService{}
+	if err := menuRepo.SaveItems([]models.MenuItem{
+		{ID: "latte", Name: "Caffe Latte", Description: "Espresso with steamed milk"},
+		{ID: "muffin", Name: "Blueberry Muffin", Description: "Freshly baked"},
+	}); err != nil {
+		t.Fatalf("saving menu failed: %v", err)
+	}
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{
+		{IngredientID: "milk", Name: "Whole Milk", Unit: "ml"},
+		{IngredientID: "blueberries", Name: "Blueberries", Unit: "g"},
+	}); err != nil {
+		t.Fatalf("saving inventory failed: %v", err)
+	}
+	if err := orderRepo.SaveItems([]models.Order{{ID: "order1", CustomerName: "Milka Stone"}}); err != nil {
+		t.Fatalf("saving orders failed: %v", err)
+	}
+
+	service := NewSearchService(menuRepo, inventoryRepo, orderRepo)
+	if err := service.BuildIndex(); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string][]string
+	}{
+		{
+			name:  "exact word in several types",
+			query: "milk",
+			want: map[string][]string{
+				search.TypeInventory: {"milk"},
+				search.TypeMenu:      {"latte"},
+				search.TypeOrders:    {"order1"},
+			},
+		},
+		{
+			name:  "prefix",
+			query: "blue",
+			want: map[string][]string{
+				search.TypeInventory: {"blueberries"},
+				search.TypeMenu:      {"muffin"},
+			},
+		},
+		{
+			name:  "typo",
+			query: "lattte",
+			want:  map[string][]string{search.TypeMenu: {"latte"}},
+		},
+		{
+			name:  "every word must match",
+			query: "steamed muffin",
+			want:  map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for docType, results := range service.Search(tt.query, 0) {
+				for _, result := range results {
+					got[docType] = append(got[docType], result.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// Writes through the repositories keep the index up to date
+	if err := menuRepo.SaveItems([]models.MenuItem{{ID: "muffin", Name: "Blueberry Muffin"}}); err != nil {
+		t.Fatalf("saving menu failed: %v", err)
+	}
+	if results := service.Search("latte", 0); len(results[search.TypeMenu]) != 0 {
+		t.Errorf("deleted menu item still found: %v", results[search.TypeMenu])
+	}
+}
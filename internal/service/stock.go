@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"sort"
+	"time"
+)
+
+// deductStock takes the usage out of the inventory items in place, drawing
+// on the lots that expire first. Nothing is deducted unless every ingredient
+// has enough stock that has not expired.
+func deductStock(items []models.InventoryItem, usage []ingredientUsage, now time.Time) error {
+	index := make(map[string]int)
+	for i, item := range items {
+		index[item.IngredientID] = i
+	}
+
+	for _, line := range usage {
+		i, found := index[line.IngredientID]
+		if !found {
+			logging.Warn("Ingredient not found in inventory", "ingredientID", line.IngredientID)
+			return errors.New("ingredient not found in inventory: " + line.IngredientID)
+		}
+		if availableQuantity(items[i], now) < roundQuantity(line.Quantity) {
+			logging.Warn("Insufficient inventory for ingredient", "ingredientID", line.IngredientID)
+			return errors.New("insufficient inventory for ingredient: " + line.IngredientID)
+		}
+	}
+
+	for _, line := range usage {
+		consumeLots(&items[index[line.IngredientID]], line.Quantity, now)
+	}
+	return nil
+}
+
+// usageMovements books a ledger movement per used ingredient with the
+// balance left in the given inventory.
+func usageMovements(usage []ingredientUsage, items []models.InventoryItem, movementType string, reason string) []models.InventoryMovement {
+	inventoryMap := mapInventoryItems(items)
+
+	var movements []models.InventoryMovement
+	for _, line := range usage {
+		movements = append(movements, newMovement(line.IngredientID, movementType, -line.Quantity, inventoryMap[line.IngredientID].Quantity, reason))
+	}
+	return movements
+}
+
+// lotExpired reports whether a lot can no longer be used. Lots without a
+// readable expiry never expire.
+func lotExpired(lot models.StockLot, now time.Time) bool {
+	if lot.ExpiresAt == "" {
+		return false
+	}
+	expiry, err := utils.ParseExpiry(lot.ExpiresAt)
+	if err != nil {
+		return false
+	}
+	return !now.Before(expiry)
+}
+
+// availableQuantity is the stock of an ingredient that can still be used:
+// its quantity less the expired lots.
+func availableQuantity(item models.InventoryItem, now time.Time) float64 {
+	available := item.Quantity
+	for _, lot := range item.Lots {
+		if lotExpired(lot, now) {
+			available -= lot.Quantity
+		}
+	}
+	if available < 0 {
+		return 0
+	}
+	return roundQuantity(available)
+}
+
+// withAvailability sets the derived available quantity on inventory items.
+func withAvailability(items []models.InventoryItem, now time.Time) {
+	for i := range items {
+		available := availableQuantity(items[i], now)
+		items[i].AvailableQuantity = &available
+	}
+}
+
+// lotOrder returns the indexes of the lots first-expiring-first-out: by
+// expiry, lots without one last, then by the date they were received.
+func lotOrder(lots []models.StockLot) []int {
+	order := make([]int, len(lots))
+	for i := range order {
+		order[i] = i
+	}
+
+	expiry := func(lot models.StockLot) time.Time {
+		t, err := utils.ParseExpiry(lot.ExpiresAt)
+		if lot.ExpiresAt == "" || err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ea, eb := expiry(lots[order[a]]), expiry(lots[order[b]])
+		if !ea.Equal(eb) {
+			if ea.IsZero() || eb.IsZero() {
+				return eb.IsZero()
+			}
+			return ea.Before(eb)
+		}
+		return lots[order[a]].ReceivedAt < lots[order[b]].ReceivedAt
+	})
+	return order
+}
+
+// consumeLots takes a quantity out of an ingredient, first from its unexpired
+// lots in FEFO order and then from the stock outside any lot. The caller
+// makes sure enough stock is available.
+func consumeLots(item *models.InventoryItem, quantity float64, now time.Time) {
+	takeFromLots(item, quantity, func(lot models.StockLot) bool {
+		return !lotExpired(lot, now)
+	})
+}
+
+// discardLots takes a quantity out of an ingredient that is thrown away.
+// Expired lots go first, as they are the first to expire.
+func discardLots(item *models.InventoryItem, quantity float64) {
+	takeFromLots(item, quantity, func(models.StockLot) bool {
+		return true
+	})
+}
+
+// takeFromLots lowers the quantity of an ingredient and draws the amount from
+// the usable lots in FEFO order; whatever they do not cover comes from stock
+// outside any lot.
+func takeFromLots(item *models.InventoryItem, quantity float64, usable func(models.StockLot) bool) {
+	item.Quantity = roundQuantity(item.Quantity - quantity)
+
+	remaining := quantity
+	for _, i := range lotOrder(item.Lots) {
+		if remaining <= 0 {
+			break
+		}
+		if !usable(item.Lots[i]) {
+			continue
+		}
+		taken := min(remaining, item.Lots[i].Quantity)
+		item.Lots[i].Quantity = roundQuantity(item.Lots[i].Quantity - taken)
+		remaining -= taken
+	}
+	item.Lots = dropEmptyLots(item.Lots)
+	trimLots(item)
+}
+
+// trimLots shrinks the lots of an ingredient, first-expiring first, until
+// they no longer hold more than its quantity. It keeps lots consistent after
+// a manual correction or a reversal lowers the quantity.
+func trimLots(item *models.InventoryItem) {
+	excess := -item.Quantity
+	for _, lot := range item.Lots {
+		excess += lot.Quantity
+	}
+	excess = roundQuantity(excess)
+	if excess <= 0 {
+		return
+	}
+
+	for _, i := range lotOrder(item.Lots) {
+		if excess <= 0 {
+			break
+		}
+		taken := min(excess, item.Lots[i].Quantity)
+		item.Lots[i].Quantity = roundQuantity(item.Lots[i].Quantity - taken)
+		excess -= taken
+	}
+	item.Lots = dropEmptyLots(item.Lots)
+}
+
+func dropEmptyLots(lots []models.StockLot) []models.StockLot {
+	var kept []models.StockLot
+	for _, lot := range lots {
+		if lot.Quantity > 0 {
+			kept = append(kept, lot)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConsumeLots(t *testing.T) {
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	lots := func() []models.StockLot {
+		return []models.StockLot{
+			{LotID: "lot1", Quantity: 5, ReceivedAt: "2026-01-01T09:00:00Z", ExpiresAt: "2026-01-10T00:00:00Z"},
+			{LotID: "lot2", Quantity: 5, ReceivedAt: "2026-01-01T09:00:00Z", ExpiresAt: "2026-01-05T00:00:00Z"},
+			{LotID: "lot3", Quantity: 5, ReceivedAt: "2026-01-02T09:00:00Z"},
+			{LotID: "lot4", Quantity: 5, ReceivedAt: "2026-01-03T09:00:00Z", ExpiresAt: "2026-01-08T00:00:00Z"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		quantity float64
+		want     float64
+		wantLots map[string]float64
+	}{
+		{
+			name:     "first expiring lot goes first",
+			quantity: 3,
+			want:     22,
+			wantLots: map[string]float64{"lot1": 5, "lot2": 5, "lot3": 5, "lot4": 2},
+		},
+		{
+			name:     "lots without expiry go last",
+			quantity: 12,
+			want:     13,
+			wantLots: map[string]float64{"lot2": 5, "lot3": 3},
+		},
+		{
+			name:     "stock outside lots covers the rest",
+			quantity: 18,
+			want:     7,
+			wantLots: map[string]float64{"lot2": 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Five of the stock are outside any lot, lot2 has expired
+			item := models.InventoryItem{IngredientID: "milk", Quantity: 25, Lots: lots()}
+			consumeLots(&item, tt.quantity, now)
+
+			if item.Quantity != tt.want {
+				t.Errorf("quantity = %v, want %v", item.Quantity, tt.want)
+			}
+			got := make(map[string]float64)
+			for _, lot := range item.Lots {
+				got[lot.LotID] = lot.Quantity
+			}
+			if !reflect.DeepEqual(got, tt.wantLots) {
+				t.Errorf("lots = %v, want %v", got, tt.wantLots)
+			}
+		})
+	}
+}
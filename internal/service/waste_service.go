@@ -0,0 +1,287 @@
+package service
+
+import (
+	"errors"
+	"hot-coffee/internal/dal"
+	"hot-coffee/logging"
+	"hot-coffee/models"
+	"hot-coffee/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+type WasteService interface {
+	RecordWaste(entry models.WasteEntry) (models.WasteEntry, error)
+	GetWaste(from, to time.Time) ([]models.WasteEntry, error)
+	GetWasteReport(from, to time.Time) (models.WasteReport, error)
+}
+
+type wasteService struct {
+	wasteRepo     dal.WasteRepository
+	inventoryRepo dal.InventoryRepository
+	menuRepo      dal.MenuRepository
+	movementRepo  dal.MovementRepository
+}
+
+func NewWasteService(wasteRepo dal.WasteRepository, inventoryRepo dal.InventoryRepository, menuRepo dal.MenuRepository, movementRepo dal.MovementRepository) WasteService {
+	return &wasteService{
+		wasteRepo:     wasteRepo,
+		inventoryRepo: inventoryRepo,
+		menuRepo:      menuRepo,
+		movementRepo:  movementRepo,
+	}
+}
+
+// RecordWaste takes wasted stock out of the inventory. A wasted ingredient is
+// taken from its lots, expired ones first; a wasted menu item takes the
+// ingredients of its recipe, as if it had been sold.
+func (s *wasteService) RecordWaste(entry models.WasteEntry) (models.WasteEntry, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Attempting to record waste", "ingredientID", entry.IngredientID, "productID", entry.ProductID, "reason", entry.Reason)
+
+	if err := utils.ValidateWasteEntry(entry); err != nil {
+		logging.Warn("Invalid waste entry data", "error", err)
+		return models.WasteEntry{}, err
+	}
+
+	var menuItemMap map[string]models.MenuItem
+	if entry.ProductID != "" {
+		menuItems, err := s.menuRepo.ReadItems()
+		if err != nil {
+			logging.Error("Failed to read menu items", err)
+			return models.WasteEntry{}, err
+		}
+		menuItemMap = mapMenuItems(menuItems)
+	}
+
+	// The entry is numbered and stored under the waste lock, and the stock
+	// taken out under the inventory lock within it
+	var before, after []models.InventoryItem
+	err := s.wasteRepo.UpdateItems(func(entries []models.WasteEntry) ([]models.WasteEntry, error) {
+		var ids []string
+		for _, existing := range entries {
+			ids = append(ids, existing.ID)
+		}
+		entry.ID = utils.NextID("waste", ids)
+		entry.CreatedAt = time.Now().Format(time.RFC3339)
+
+		var err error
+		before, after, err = s.takeWaste(&entry, menuItemMap)
+		if err != nil {
+			return nil, err
+		}
+		return append(entries, entry), nil
+	})
+	if err != nil {
+		logging.Error("Failed to record waste", err)
+		return models.WasteEntry{}, err
+	}
+	checkReorderPoints(before, after)
+
+	logging.Info("Successfully recorded waste", "wasteID", entry.ID, "cost", entry.Cost)
+	return entry, nil
+}
+
+// takeWaste takes the stock of a waste entry out under the inventory lock,
+// costing its lines, and returns the quantities before and after.
+func (s *wasteService) takeWaste(entry *models.WasteEntry, menuItemMap map[string]models.MenuItem) (before, after []models.InventoryItem, err error) {
+	err = s.inventoryRepo.UpdateItems(func(items []models.InventoryItem) ([]models.InventoryItem, error) {
+		before = append([]models.InventoryItem(nil), items...)
+		inventoryMap := mapInventoryItems(items)
+
+		var usage []ingredientUsage
+		var err error
+		if entry.IngredientID != "" {
+			usage, err = ingredientWaste(*entry, items)
+		} else {
+			usage, err = productWaste(*entry, menuItemMap, inventoryMap)
+			if err == nil {
+				// The ingredients went into a finished product, so they had not expired
+				err = deductStock(items, usage, time.Now())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Lines = nil
+		entry.Cost = 0
+		for _, line := range usage {
+			item := inventoryMap[line.IngredientID]
+			wasteLine := models.WasteLine{
+				IngredientID: line.IngredientID,
+				Quantity:     roundQuantity(line.Quantity),
+				Unit:         item.Unit,
+				Cost:         roundPrice(line.Quantity * item.UnitCost),
+			}
+			entry.Lines = append(entry.Lines, wasteLine)
+			entry.Cost += wasteLine.Cost
+		}
+		entry.Cost = roundPrice(entry.Cost)
+
+		if err := recordMovements(s.movementRepo, usageMovements(usage, items, models.MovementWaste, "waste "+entry.ID+": "+entry.Reason)...); err != nil {
+			return nil, err
+		}
+
+		after = items
+		return items, nil
+	})
+	return before, after, err
+}
+
+// ingredientWaste discards a raw ingredient, converting the wasted quantity
+// to the stock unit. Expired stock can be wasted too.
+func ingredientWaste(entry models.WasteEntry, items []models.InventoryItem) ([]ingredientUsage, error) {
+	for i := range items {
+		item := &items[i]
+		if item.IngredientID != entry.IngredientID {
+			continue
+		}
+
+		quantity := entry.Quantity
+		if entry.Unit != "" {
+			converted, err := utils.ConvertQuantity(entry.Quantity, entry.Unit, item.Unit, item.CustomUnits)
+			if err != nil {
+				logging.Warn("Failed to convert wasted unit", "ingredientID", item.IngredientID, "error", err)
+				return nil, err
+			}
+			quantity = converted
+		}
+		if item.Quantity < roundQuantity(quantity) {
+			logging.Warn("Insufficient inventory for ingredient", "ingredientID", item.IngredientID)
+			return nil, errors.New("insufficient inventory for ingredient: " + item.IngredientID)
+		}
+
+		discardLots(item, quantity)
+		return []ingredientUsage{{IngredientID: item.IngredientID, Quantity: quantity}}, nil
+	}
+
+	logging.Warn("Ingredient not found in inventory", "ingredientID", entry.IngredientID)
+	return nil, errors.New("ingredient not found in inventory: " + entry.IngredientID)
+}
+
+// productWaste lists the ingredients of a wasted menu item. Bundles are
+// expanded like on an order, so they can only be wasted whole.
+func productWaste(entry models.WasteEntry, menuItemMap map[string]models.MenuItem, inventoryMap map[string]models.InventoryItem) ([]ingredientUsage, error) {
+	menuItem, exists := menuItemMap[entry.ProductID]
+	if !exists {
+		return nil, errors.New("product not found in menu: " + entry.ProductID)
+	}
+	if !menuItem.IsBundle() {
+		return recipeUsage(menuItem, entry.Quantity, inventoryMap)
+	}
+
+	if entry.Quantity != math.Trunc(entry.Quantity) {
+		return nil, errors.New("a bundle can only be wasted in whole items")
+	}
+	order := models.Order{Items: []models.OrderItem{{
+		ProductID:  entry.ProductID,
+		Quantity:   int(entry.Quantity),
+		Selections: entry.Selections,
+	}}}
+	return orderUsage(order, menuItemMap, inventoryMap)
+}
+
+// GetWaste lists the waste entries recorded within the date range.
+func (s *wasteService) GetWaste(from, to time.Time) ([]models.WasteEntry, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Fetching waste entries")
+
+	entries, err := s.wasteRepo.ReadItems()
+	if err != nil {
+		logging.Error("Failed to read waste entries", err)
+		return nil, err
+	}
+
+	filtered := []models.WasteEntry{}
+	for _, entry := range entries {
+		if utils.InDateRange(entry.CreatedAt, from, to) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	logging.Info("Fetched waste entries", "count", len(filtered))
+	return filtered, nil
+}
+
+// GetWasteReport totals the waste within the date range by reason and by
+// ingredient, costliest first.
+func (s *wasteService) GetWasteReport(from, to time.Time) (models.WasteReport, error) {
+	defer utils.CatchCriticalPoint()
+
+	logging.Info("Building waste report")
+
+	entries, err := s.GetWaste(from, to)
+	if err != nil {
+		return models.WasteReport{}, err
+	}
+	items, err := s.inventoryRepo.ReadItem()
+	if err != nil {
+		logging.Error("Failed to read inventory items", err)
+		return models.WasteReport{}, err
+	}
+	inventoryMap := mapInventoryItems(items)
+
+	report := models.WasteReport{
+		Entries:      len(entries),
+		ByReason:     []models.WasteReasonTotal{},
+		ByIngredient: []models.WasteIngredientTotal{},
+	}
+	if !from.IsZero() {
+		report.From = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		report.To = to.Format(time.RFC3339)
+	}
+
+	reasons := make(map[string]int)
+	ingredients := make(map[string]int)
+	for _, entry := range entries {
+		report.TotalCost += entry.Cost
+
+		i, found := reasons[entry.Reason]
+		if !found {
+			i = len(report.ByReason)
+			reasons[entry.Reason] = i
+			report.ByReason = append(report.ByReason, models.WasteReasonTotal{Reason: entry.Reason})
+		}
+		report.ByReason[i].Entries++
+		report.ByReason[i].Cost += entry.Cost
+
+		for _, line := range entry.Lines {
+			j, found := ingredients[line.IngredientID]
+			if !found {
+				j = len(report.ByIngredient)
+				ingredients[line.IngredientID] = j
+				report.ByIngredient = append(report.ByIngredient, models.WasteIngredientTotal{
+					IngredientID: line.IngredientID,
+					Name:         inventoryMap[line.IngredientID].Name,
+					Unit:         line.Unit,
+				})
+			}
+			report.ByIngredient[j].Quantity += line.Quantity
+			report.ByIngredient[j].Cost += line.Cost
+		}
+	}
+
+	report.TotalCost = roundPrice(report.TotalCost)
+	for i := range report.ByReason {
+		report.ByReason[i].Cost = roundPrice(report.ByReason[i].Cost)
+	}
+	for i := range report.ByIngredient {
+		report.ByIngredient[i].Quantity = roundQuantity(report.ByIngredient[i].Quantity)
+		report.ByIngredient[i].Cost = roundPrice(report.ByIngredient[i].Cost)
+	}
+	sort.SliceStable(report.ByReason, func(i, j int) bool {
+		return report.ByReason[i].Cost > report.ByReason[j].Cost
+	})
+	sort.SliceStable(report.ByIngredient, func(i, j int) bool {
+		return report.ByIngredient[i].Cost > report.ByIngredient[j].Cost
+	})
+
+	logging.Info("Built waste report", "entries", report.Entries, "totalCost", report.TotalCost)
+	return report, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordWaste(t *testing.T) {
+	useTempData(t)
+	inventoryRepo := &dal.InventoryItemService{}
+	menuRepo := &dal.MenuItemService{}
+	if err := inventoryRepo.SaveItem([]models.InventoryItem{
+		{IngredientID: "milk", Name: "Milk", Quantity: 1000, Unit: "ml", UnitCost: 0.002},
+		{IngredientID: "beans", Name: "Beans", Quantity: 1000, Unit: "g", UnitCost: 0.02},
+	}); err != nil {
+		t.Fatalf("saving inventory failed: %v", err)
+	}
+	if err := menuRepo.SaveItems([]models.MenuItem{{ID: "latte", Ingredients: []models.MenuItemIngredient{
+		{IngredientID: "beans", Quantity: 18},
+		{IngredientID: "milk", Quantity: 200},
+	}}}); err != nil {
+		t.Fatalf("saving menu failed: %v", err)
+	}
+	service := NewWasteService(&dal.WasteService{}, inventoryRepo, menuRepo, &dal.MovementService{})
+
+	tests := []struct {
+		name      string
+		entry     models.WasteEntry
+		wantCost  float64
+		wantLines []models.WasteLine
+		wantErr   bool
+	}{
+		{
+			name:      "ingredient in another unit",
+			entry:     models.WasteEntry{IngredientID: "milk", Quantity: 0.5, Unit: "l", Reason: models.WasteSpilled, Staff: "aida"},
+			wantCost:  1,
+			wantLines: []models.WasteLine{{IngredientID: "milk", Quantity: 500, Unit: "ml", Cost: 1}},
+		},
+		{
+			name:     "menu item takes its recipe",
+			entry:    models.WasteEntry{ProductID: "latte", Quantity: 2, Reason: models.WastePrepError, Staff: "aida"},
+			wantCost: 1.52,
+			wantLines: []models.WasteLine{
+				{IngredientID: "beans", Quantity: 36, Unit: "g", Cost: 0.72},
+				{IngredientID: "milk", Quantity: 400, Unit: "ml", Cost: 0.8},
+			},
+		},
+		{
+			name:    "more than in stock",
+			entry:   models.WasteEntry{IngredientID: "milk", Quantity: 101, Reason: models.WasteSpoiled, Staff: "aida"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown reason",
+			entry:   models.WasteEntry{IngredientID: "milk", Quantity: 1, Reason: "dropped", Staff: "aida"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := service.RecordWaste(tt.entry)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RecordWaste succeeded with %+v, want an error", entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RecordWaste failed: %v", err)
+			}
+			if entry.Cost != tt.wantCost || !reflect.DeepEqual(entry.Lines, tt.wantLines) {
+				t.Errorf("entry = %v %+v, want %v %+v", entry.Cost, entry.Lines, tt.wantCost, tt.wantLines)
+			}
+		})
+	}
+
+	items, err := inventoryRepo.ReadItem()
+	if err != nil {
+		t.Fatalf("reading inventory failed: %v", err)
+	}
+	quantities := make(map[string]float64)
+	for _, item := range items {
+		quantities[item.IngredientID] = item.Quantity
+	}
+	if want := map[string]float64{"milk": 100, "beans": 964}; !reflect.DeepEqual(quantities, want) {
+		t.Errorf("stock = %v, want %v", quantities, want)
+	}
+
+	report, err := service.GetWasteReport(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetWasteReport failed: %v", err)
+	}
+	wantReasons := []models.WasteReasonTotal{
+		{Reason: models.WastePrepError, Entries: 1, Cost: 1.52},
+		{Reason: models.WasteSpilled, Entries: 1, Cost: 1},
+	}
+	wantIngredients := []models.WasteIngredientTotal{
+		{IngredientID: "milk", Name: "Milk", Quantity: 900, Unit: "ml", Cost: 1.8},
+		{IngredientID: "beans", Name: "Beans", Quantity: 36, Unit: "g", Cost: 0.72},
+	}
+	if report.Entries != 2 || report.TotalCost != 2.52 {
+		t.Errorf("report = %d entries costing %v, want 2 costing 2.52", report.Entries, report.TotalCost)
+	}
+	if !reflect.DeepEqual(report.ByReason, wantReasons) {
+		t.Errorf("by reason = %+v, want %+v", report.ByReason, wantReasons)
+	}
+	if !reflect.DeepEqual(report.ByIngredient, wantIngredients) {
+		t.Errorf("by ingredient = %+v, want %+v", report.ByIngredient, wantIngredients)
+	}
+}
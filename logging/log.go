@@ -20,25 +20,25 @@ func InitLogger() error {
 
 func Info(msg string, args ...interface{}) {
 	if logger != nil {
-		logger.Info(msg, args)
+		logger.Info(msg, args...)
 	}
 }
 
 func Error(msg string, err error, args ...interface{}) {
 	if logger != nil {
-		logger.Error(msg, append(args, "error", err.Error()))
+		logger.Error(msg, append(args, "error", err.Error())...)
 	}
 }
 
 func Warn(msg string, args ...interface{}) {
 	if logger != nil {
-		logger.Warn(msg, args)
+		logger.Warn(msg, args...)
 	}
 }
 
 func Fatal(msg string, err error, args ...interface{}) {
 	if logger != nil {
-		logger.Error(msg, append(args, "error", err.Error()))
+		logger.Error(msg, append(args, "error", err.Error())...)
 		os.Exit(1)
 	}
 }
@@ -1,7 +1,48 @@
 package models
 
+// AggregationData holds running sales totals of the closed orders. They are
+// updated as orders close so that reports do not have to rescan every order,
+// and can be rebuilt from the orders at any time.
 type AggregationData struct {
-	TotalSales   float64  `json:"total_sales"`
-	PopularItems MenuItem `json:"popular_items"`
-	DailyItem    MenuItem `json:"daily_item"`
+	TotalSales  float64 `json:"total_sales"`
+	TotalOrders int     `json:"total_orders"`
+	ItemsSold   int     `json:"items_sold"`
+	FirstSale   string  `json:"first_sale,omitempty"`
+	// Days maps a business day (2006-01-02, business time zone) to its sales.
+	Days      map[string]DaySales `json:"days"`
+	DailyItem DailyItem           `json:"daily_item"`
+	RebuiltAt string              `json:"rebuilt_at,omitempty"`
+	UpdatedAt string              `json:"updated_at,omitempty"`
+}
+
+// DaySales totals one business day. Hours are keyed by the hour of the day
+// ("00" to "23") in the business time zone.
+type DaySales struct {
+	Revenue    float64                 `json:"revenue"`
+	Orders     int                     `json:"orders"`
+	ItemsSold  int                     `json:"items_sold"`
+	Hours      map[string]HourSales    `json:"hours"`
+	Products   map[string]ProductSales `json:"products"`
+	Categories map[string]SalesCount   `json:"categories"`
+}
+
+type HourSales struct {
+	Revenue   float64 `json:"revenue"`
+	Orders    int     `json:"orders"`
+	ItemsSold int     `json:"items_sold"`
+}
+
+// ProductSales totals a product, under the menu category it had when sold.
+// Bundles count as the products they contain.
+type ProductSales struct {
+	Category string `json:"category"`
+	SalesCount
+}
+
+// SalesCount is the quantity and revenue of a product or category, and the
+// number of orders it appeared in.
+type SalesCount struct {
+	Quantity int     `json:"quantity"`
+	Revenue  float64 `json:"revenue"`
+	Orders   int     `json:"orders"`
 }
@@ -0,0 +1,17 @@
+package models
+
+// Comparison periods. The previous period is the same length right before
+// the report's range; the same period last year is the range a year earlier.
+const (
+	ComparePreviousPeriod     = "previous_period"
+	CompareSamePeriodLastYear = "same_period_last_year"
+)
+
+// Delta compares a metric with its value in the comparison period. The
+// percentage is null when there is nothing to compare against.
+type Delta struct {
+	Current       float64  `json:"current"`
+	Previous      float64  `json:"previous"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// ConsumptionReport compares the stock closed orders should have used, by
+// their menu items' current recipes, with what the ledger booked.
+type ConsumptionReport struct {
+	From        string                  `json:"from,omitempty"`
+	To          string                  `json:"to,omitempty"`
+	Ingredients []IngredientConsumption `json:"ingredients"`
+}
+
+// IngredientConsumption is the usage of one ingredient in its stock unit.
+// Actual is the net sale movements, and is missing for ingredients that
+// have no ledger. The variance is actual less the theoretical usage of the
+// orders closed since the ingredient's ledger started, so sales from before
+// the ledger do not count against it. Waste and adjustments are the other
+// stock movements of the period, for reference.
+type IngredientConsumption struct {
+	IngredientID    string                `json:"ingredient_id"`
+	Name            string                `json:"name"`
+	Unit            string                `json:"unit"`
+	Theoretical     float64               `json:"theoretical"`
+	Actual          *float64              `json:"actual"`
+	Variance        *float64              `json:"variance"`
+	VariancePercent *float64              `json:"variance_percent"`
+	Waste           float64               `json:"waste"`
+	Adjustments     float64               `json:"adjustments"`
+	ByDay           []ConsumptionDay      `json:"by_day"`
+	ByMenuItem      []MenuItemConsumption `json:"by_menu_item"`
+}
+
+// ConsumptionDay is the usage of an ingredient on one business day. Actual is
+// missing for days before the ingredient's ledger started.
+type ConsumptionDay struct {
+	Date        string   `json:"date"`
+	Theoretical float64  `json:"theoretical"`
+	Actual      *float64 `json:"actual"`
+}
+
+// MenuItemConsumption is how much of an ingredient the sales of one menu
+// item used. Bundles count as the products they contain.
+type MenuItemConsumption struct {
+	ProductID   string  `json:"product_id"`
+	Name        string  `json:"name"`
+	Sold        int     `json:"sold"`
+	Theoretical float64 `json:"theoretical"`
+}
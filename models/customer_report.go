@@ -0,0 +1,35 @@
+package models
+
+// CustomerReport analyses the customers of the closed orders in a date
+// range. Customers are matched by name, ignoring case and extra whitespace.
+// A visit is a business day with at least one order; a repeat customer
+// visited more than once. Rates are percentages.
+type CustomerReport struct {
+	From                 string            `json:"from,omitempty"`
+	To                   string            `json:"to,omitempty"`
+	Weeks                float64           `json:"weeks"`
+	UniqueCustomers      int               `json:"unique_customers"`
+	RepeatCustomers      int               `json:"repeat_customers"`
+	RepeatRate           float64           `json:"repeat_rate"`
+	AverageVisitsPerWeek float64           `json:"average_visits_per_week"`
+	TotalSpend           float64           `json:"total_spend"`
+	TopCustomers         []CustomerSummary `json:"top_customers"`
+}
+
+// CustomerSummary is one customer's activity, under the name they first
+// ordered with. The favourite product is the one ordered most, ties broken
+// by product ID.
+type CustomerSummary struct {
+	Rank             int     `json:"rank"`
+	Customer         string  `json:"customer"`
+	Orders           int     `json:"orders"`
+	Visits           int     `json:"visits"`
+	VisitsPerWeek    float64 `json:"visits_per_week"`
+	Spend            float64 `json:"spend"`
+	AverageTicket    float64 `json:"average_ticket"`
+	FirstVisit       string  `json:"first_visit"`
+	LastVisit        string  `json:"last_visit"`
+	FavouriteProduct string  `json:"favourite_product"`
+	FavouriteName    string  `json:"favourite_name"`
+	FavouriteCount   int     `json:"favourite_count"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Daily item strategies. A manual pick is set through the API and holds for
+// the rest of its business day unless the item sells out.
+const (
+	DailyItemRandom     = "random"
+	DailyItemRotation   = "rotation"
+	DailyItemSlowMoving = "slow_moving"
+	DailyItemManual     = "manual"
+)
+
+// DailyItemStrategies lists the strategies the daily item can be chosen by.
+var DailyItemStrategies = []string{DailyItemRandom, DailyItemRotation, DailyItemSlowMoving}
+
+// DailyItem is the menu item featured on a business day (2006-01-02, business
+// time zone). It is chosen once per day and kept in aggregation.json.
+type DailyItem struct {
+	Date     string `json:"date"`
+	Strategy string `json:"strategy"`
+	ChosenAt string `json:"chosen_at"`
+	MenuItem
+}
+
+// DailyItemRequest picks today's item by hand, or again by a strategy.
+type DailyItemRequest struct {
+	ProductID string `json:"product_id,omitempty"`
+	Strategy  string `json:"strategy,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+// DayClose is the Z report of a business day (2006-01-02, business time
+// zone). It is taken once, when the day is closed, and never changes: orders
+// can no longer be placed, changed or settled on a closed day.
+type DayClose struct {
+	Date           string           `json:"date"`
+	TimeZone       string           `json:"time_zone"`
+	ClosedAt       string           `json:"closed_at"`
+	Totals         SalesTotals      `json:"totals"`
+	Products       []PopularItem    `json:"products"`
+	Categories     []CategorySales  `json:"categories"`
+	PaymentMethods []PaymentTotal   `json:"payment_methods"`
+	Cancelled      []DayCloseOrder  `json:"cancelled_orders"`
+	Voided         []DayCloseOrder  `json:"voided_orders"`
+	OpenOrders     []DayCloseOrder  `json:"open_orders"`
+	Inventory      []ClosingBalance `json:"inventory"`
+}
+
+// PaymentTotal sums the orders settled with one payment method. Orders
+// closed without one are listed as "unspecified".
+type PaymentTotal struct {
+	Method  string  `json:"method"`
+	Orders  int     `json:"orders"`
+	Revenue float64 `json:"revenue"`
+	Share   float64 `json:"share"`
+}
+
+// DayCloseOrder is an order listed on a Z report.
+type DayCloseOrder struct {
+	OrderID       string  `json:"order_id"`
+	CustomerName  string  `json:"customer_name"`
+	Status        string  `json:"status"`
+	Total         float64 `json:"total"`
+	PaymentMethod string  `json:"payment_method,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	CancelledAt   string  `json:"cancelled_at,omitempty"`
+	VoidedAt      string  `json:"voided_at,omitempty"`
+}
+
+// ClosingBalance is the stock of an ingredient at the end of the day.
+type ClosingBalance struct {
+	IngredientID string  `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+}
+
+// DayCloseRequest closes a business day. Without a date it closes today.
+type DayCloseRequest struct {
+	Date string `json:"date,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// ExpiryReport lists the stock lots that have expired or expire within the
+// requested number of days, valued at the ingredient's unit cost.
+type ExpiryReport struct {
+	GeneratedAt   string        `json:"generated_at"`
+	WithinDays    int           `json:"within_days"`
+	Expired       []ExpiringLot `json:"expired"`
+	Expiring      []ExpiringLot `json:"expiring"`
+	ExpiredValue  float64       `json:"expired_value"`
+	ExpiringValue float64       `json:"expiring_value"`
+}
+
+type ExpiringLot struct {
+	IngredientID string  `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	LotID        string  `json:"lot_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	ReceivedAt   string  `json:"received_at"`
+	ExpiresAt    string  `json:"expires_at"`
+	DaysLeft     int     `json:"days_left"`
+	Value        float64 `json:"value"`
+}
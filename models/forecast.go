@@ -0,0 +1,44 @@
+package models
+
+// ForecastReport projects ingredient usage from past sales and lists what to
+// buy to cover the forecast horizon.
+type ForecastReport struct {
+	GeneratedAt    string               `json:"generated_at"`
+	HistoryDays    int                  `json:"history_days"`
+	Window         int                  `json:"window"`
+	HorizonDays    int                  `json:"horizon_days"`
+	Ingredients    []IngredientForecast `json:"ingredients"`
+	PurchaseList   []PurchaseSuggestion `json:"purchase_list"`
+	EstimatedTotal float64              `json:"estimated_total"`
+}
+
+// IngredientForecast is the projected usage of one ingredient. The moving
+// average is scaled by a factor per weekday (1 is an average day).
+type IngredientForecast struct {
+	IngredientID      string             `json:"ingredient_id"`
+	Name              string             `json:"name"`
+	Unit              string             `json:"unit"`
+	AvailableQuantity float64            `json:"available_quantity"`
+	MovingAverage     float64            `json:"moving_average"`
+	WeekdayFactors    map[string]float64 `json:"weekday_factors"`
+	Forecast          []DailyForecast    `json:"forecast"`
+	HorizonUsage      float64            `json:"horizon_usage"`
+	DaysUntilStockout *float64           `json:"days_until_stockout"`
+	StockoutDate      string             `json:"stockout_date,omitempty"`
+}
+
+type DailyForecast struct {
+	Date     string  `json:"date"`
+	Quantity float64 `json:"quantity"`
+}
+
+// PurchaseSuggestion is how much of an ingredient to buy so that the
+// forecast horizon is covered and the reorder point is still kept.
+type PurchaseSuggestion struct {
+	IngredientID  string  `json:"ingredient_id"`
+	Name          string  `json:"name"`
+	Unit          string  `json:"unit"`
+	Quantity      float64 `json:"quantity"`
+	UnitCost      float64 `json:"unit_cost"`
+	EstimatedCost float64 `json:"estimated_cost"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// GoodsReceipt records a delivery of stock from a supplier. Each line adds
+// to one ingredient.
+type GoodsReceipt struct {
+	ID         string `json:"receipt_id"`
+	Supplier   string `json:"supplier"`
+	InvoiceRef string `json:"invoice_ref,omitempty"`
+	ReceivedBy string `json:"received_by"`
+	ReceivedAt string `json:"received_at"`
+	// PurchaseOrderID is set when the delivery was received against a purchase order.
+	PurchaseOrderID string             `json:"purchase_order_id,omitempty"`
+	Lines           []GoodsReceiptLine `json:"lines"`
+}
+
+// GoodsReceiptLine is the quantity of one ingredient received and what it
+// cost per unit. Unit defaults to the ingredient's stock unit; the stock
+// fields hold the line converted to that unit. Every line becomes a stock lot.
+type GoodsReceiptLine struct {
+	IngredientID  string  `json:"ingredient_id"`
+	Quantity      float64 `json:"quantity"`
+	Unit          string  `json:"unit,omitempty"`
+	UnitCost      float64 `json:"unit_cost"`
+	ExpiresAt     string  `json:"expires_at,omitempty"`
+	LotID         string  `json:"lot_id,omitempty"`
+	StockQuantity float64 `json:"stock_quantity"`
+	StockUnitCost float64 `json:"stock_unit_cost"`
+}
@@ -1,8 +1,39 @@
 package models
 
 type InventoryItem struct {
-	IngredientID string  `json:"ingredient_id"`
-	Name         string  `json:"name"`
-	Quantity     float64 `json:"quantity"`
-	Unit         string  `json:"unit"`
+	IngredientID string       `json:"ingredient_id"`
+	Name         string       `json:"name"`
+	Quantity     float64      `json:"quantity"`
+	Unit         string       `json:"unit"`
+	CustomUnits  []CustomUnit `json:"custom_units,omitempty"`
+	// ReorderPoint is the quantity at which the ingredient needs reordering,
+	// ReorderQuantity how much to order then.
+	ReorderPoint    float64      `json:"reorder_point,omitempty"`
+	ReorderQuantity float64      `json:"reorder_quantity,omitempty"`
+	UnitCost        float64      `json:"unit_cost,omitempty"`
+	CostHistory     []CostChange `json:"cost_history,omitempty"`
+	Allergens       []string     `json:"allergens,omitempty"`
+	Nutrition       *Nutrition   `json:"nutrition,omitempty"`
+	// Lots split part of the quantity by delivery; stock outside any lot has
+	// no known expiry.
+	Lots []StockLot `json:"lots,omitempty"`
+	// AvailableQuantity is the quantity less expired lots. It is derived
+	// when the item is read and never stored.
+	AvailableQuantity *float64 `json:"available_quantity,omitempty"`
+}
+
+// StockLot is a batch of an ingredient received together.
+type StockLot struct {
+	LotID      string  `json:"lot_id"`
+	Quantity   float64 `json:"quantity"`
+	ReceivedAt string  `json:"received_at"`
+	ExpiresAt  string  `json:"expires_at,omitempty"`
+	ReceiptID  string  `json:"receipt_id,omitempty"`
+}
+
+// CostChange is one entry in the history of an ingredient's unit cost.
+type CostChange struct {
+	UnitCost  float64 `json:"unit_cost"`
+	ChangedAt string  `json:"changed_at"`
+	Source    string  `json:"source"`
 }
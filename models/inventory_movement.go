@@ -0,0 +1,34 @@
+package models
+
+const (
+	MovementOpening    = "opening"
+	MovementSale       = "sale"
+	MovementRestock    = "restock"
+	MovementAdjustment = "adjustment"
+	MovementWaste      = "waste"
+	MovementReversal   = "reversal"
+)
+
+// InventoryMovement is one entry of the append-only stock ledger. Quantity is
+// the signed change in the ingredient's stock unit.
+type InventoryMovement struct {
+	ID           string  `json:"movement_id"`
+	IngredientID string  `json:"ingredient_id"`
+	Type         string  `json:"type"`
+	Quantity     float64 `json:"quantity"`
+	BalanceAfter float64 `json:"balance_after"`
+	OrderID      string  `json:"order_id,omitempty"`
+	ReversesID   string  `json:"reverses_id,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// LedgerDrift compares the stored quantity of an ingredient with the
+// quantity rebuilt from its ledger.
+type LedgerDrift struct {
+	IngredientID   string  `json:"ingredient_id"`
+	Quantity       float64 `json:"quantity"`
+	LedgerQuantity float64 `json:"ledger_quantity"`
+	Drift          float64 `json:"drift"`
+	Tracked        bool    `json:"tracked"`
+}
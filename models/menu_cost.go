@@ -0,0 +1,28 @@
+package models
+
+// MenuItemCost breaks down what a menu item costs to make and what it earns.
+type MenuItemCost struct {
+	ProductID      string           `json:"product_id"`
+	Name           string           `json:"name"`
+	Price          float64          `json:"price"`
+	Cost           float64          `json:"cost"`
+	Margin         float64          `json:"margin"`
+	MarginPercent  float64          `json:"margin_percent"`
+	BelowThreshold bool             `json:"below_threshold"`
+	MissingCosts   []string         `json:"missing_costs,omitempty"`
+	Ingredients    []IngredientCost `json:"ingredients"`
+}
+
+type IngredientCost struct {
+	IngredientID string  `json:"ingredient_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	UnitCost     float64 `json:"unit_cost"`
+	Cost         float64 `json:"cost"`
+}
+
+type MarginReport struct {
+	Threshold      float64        `json:"threshold"`
+	Items          []MenuItemCost `json:"items"`
+	BelowThreshold []string       `json:"below_threshold"`
+}
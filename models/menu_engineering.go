@@ -0,0 +1,61 @@
+package models
+
+// Menu engineering classes: popular and profitable items are stars, popular
+// ones with a low margin plowhorses, profitable ones that sell little
+// puzzles, and the rest dogs. Items with ingredients of unknown cost have no
+// known margin and are left unclassified.
+const (
+	MenuStar         = "star"
+	MenuPlowhorse    = "plowhorse"
+	MenuPuzzle       = "puzzle"
+	MenuDog          = "dog"
+	MenuUnclassified = "unclassified"
+)
+
+// MenuEngineeringReport classifies the menu items by how well they sold in
+// a date range and what each sale earned. The thresholds and totals cover
+// the classified items only.
+type MenuEngineeringReport struct {
+	From          string                    `json:"from,omitempty"`
+	To            string                    `json:"to,omitempty"`
+	Thresholds    MenuEngineeringThresholds `json:"thresholds"`
+	TotalQuantity int                       `json:"total_quantity"`
+	TotalRevenue  float64                   `json:"total_revenue"`
+	TotalMargin   float64                   `json:"total_margin"`
+	Stars         []string                  `json:"stars"`
+	Plowhorses    []string                  `json:"plowhorses"`
+	Puzzles       []string                  `json:"puzzles"`
+	Dogs          []string                  `json:"dogs"`
+	Unclassified  []string                  `json:"unclassified"`
+	Items         []MenuEngineeringItem     `json:"items"`
+}
+
+// MenuEngineeringThresholds are the lines between high and low. An item is
+// popular when its share of the quantity sold reaches the popularity factor
+// (a percentage) of an equal share, and profitable when its margin per unit
+// reaches the average margin of everything sold.
+type MenuEngineeringThresholds struct {
+	PopularityFactor   float64 `json:"popularity_factor"`
+	PopularityShare    float64 `json:"popularity_share"`
+	PopularityQuantity float64 `json:"popularity_quantity"`
+	Margin             float64 `json:"margin"`
+}
+
+// MenuEngineeringItem is one menu item of the matrix. The price is what a
+// unit sold for on average, after discounts, or the menu price when it did
+// not sell.
+type MenuEngineeringItem struct {
+	ProductID    string   `json:"product_id"`
+	Name         string   `json:"name"`
+	Category     string   `json:"category"`
+	Class        string   `json:"class"`
+	Quantity     int      `json:"quantity"`
+	MixShare     float64  `json:"mix_share"`
+	Price        float64  `json:"price"`
+	Cost         float64  `json:"cost"`
+	Margin       float64  `json:"margin"`
+	TotalMargin  float64  `json:"total_margin"`
+	Popular      bool     `json:"popular"`
+	Profitable   bool     `json:"profitable"`
+	MissingCosts []string `json:"missing_costs,omitempty"`
+}
@@ -4,11 +4,33 @@ type MenuItem struct {
 	ID          string               `json:"product_id"`
 	Name        string               `json:"name"`
 	Description string               `json:"description"`
+	Category    string               `json:"category,omitempty"`
 	Price       float64              `json:"price"`
 	Ingredients []MenuItemIngredient `json:"ingredients"`
+	Components  []BundleComponent    `json:"components,omitempty"`
+	// Allergens and Nutrition are derived from the recipe, never stored.
+	Allergens []string   `json:"allergens,omitempty"`
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
 }
 
 type MenuItemIngredient struct {
 	IngredientID string  `json:"ingredient_id"`
 	Quantity     float64 `json:"quantity"`
+	// Unit defaults to the unit the ingredient is stocked in.
+	Unit string `json:"unit,omitempty"`
+}
+
+// BundleComponent is one slot of a combo deal. A slot either names a fixed
+// product, or a choice group (e.g. "drink") with the products the customer
+// may pick from.
+type BundleComponent struct {
+	ProductID string   `json:"product_id,omitempty"`
+	Group     string   `json:"group,omitempty"`
+	Choices   []string `json:"choices,omitempty"`
+	Quantity  int      `json:"quantity"`
+}
+
+// IsBundle reports whether the menu item is sold as a combo of other items.
+func (m MenuItem) IsBundle() bool {
+	return len(m.Components) > 0
 }
@@ -0,0 +1,12 @@
+package models
+
+// Nutrition holds nutritional values. On an inventory item they are given
+// per unit of the item; on a menu item they are totals for one serving.
+type Nutrition struct {
+	Calories      float64 `json:"calories"`
+	Fat           float64 `json:"fat"`
+	Carbohydrates float64 `json:"carbohydrates"`
+	Sugar         float64 `json:"sugar"`
+	Protein       float64 `json:"protein"`
+	Salt          float64 `json:"salt"`
+}
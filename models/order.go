@@ -1,14 +1,38 @@
 package models
 
+// Payment methods an order can be settled with.
+const (
+	PaymentCash     = "cash"
+	PaymentCard     = "card"
+	PaymentTransfer = "transfer"
+)
+
+// PaymentMethods lists the accepted payment methods.
+var PaymentMethods = []string{PaymentCash, PaymentCard, PaymentTransfer}
+
+// Order starts open and is closed once paid. An open order can be cancelled;
+// a closed one can be voided, which puts its ingredients back in stock.
 type Order struct {
-	ID           string      `json:"order_id"`
-	CustomerName string      `json:"customer_name"`
-	Items        []OrderItem `json:"items"`
-	Status       string      `json:"status"`
-	CreatedAt    string      `json:"created_at"`
+	ID            string        `json:"order_id"`
+	CustomerName  string        `json:"customer_name"`
+	CustomerGroup string        `json:"customer_group,omitempty"`
+	Items         []OrderItem   `json:"items"`
+	Status        string        `json:"status"`
+	CreatedAt     string        `json:"created_at"`
+	Subtotal      float64       `json:"subtotal,omitempty"`
+	Discount      float64       `json:"discount,omitempty"`
+	Total         float64       `json:"total,omitempty"`
+	AppliedRules  []AppliedRule `json:"applied_rules,omitempty"`
+	PaymentMethod string        `json:"payment_method,omitempty"`
+	CancelledAt   string        `json:"cancelled_at,omitempty"`
+	VoidedAt      string        `json:"voided_at,omitempty"`
 }
 
 type OrderItem struct {
 	ProductID string `json:"product_id"`
 	Quantity  int    `json:"quantity"`
+	// Selections maps a bundle choice group to the chosen product ID.
+	Selections map[string]string `json:"selections,omitempty"`
+	UnitPrice  float64           `json:"unit_price,omitempty"`
+	Discount   float64           `json:"discount,omitempty"`
 }
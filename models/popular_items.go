@@ -0,0 +1,81 @@
+package models
+
+// PopularItemsReport ranks the products sold in a date range. Bundles count
+// as the products they contain, with the bundle revenue split between them.
+// Shares are percentages of the period's totals.
+type PopularItemsReport struct {
+	From          string          `json:"from,omitempty"`
+	To            string          `json:"to,omitempty"`
+	By            string          `json:"by"`
+	TotalQuantity int             `json:"total_quantity"`
+	TotalRevenue  float64         `json:"total_revenue"`
+	TotalOrders   int             `json:"total_orders"`
+	Items         []PopularItem   `json:"items"`
+	Categories    []CategorySales `json:"categories"`
+	// Comparison is set when the report is compared with another period.
+	Comparison *PopularItemsComparison `json:"comparison,omitempty"`
+}
+
+// PopularItemsComparison compares the products and categories sold with a
+// previous period. Products listed in the report come first, followed by
+// those that sold only in the comparison period.
+type PopularItemsComparison struct {
+	Compare    string               `json:"compare"`
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Deltas     PopularDeltas        `json:"deltas"`
+	Items      []ProductComparison  `json:"items"`
+	Categories []CategoryComparison `json:"categories"`
+}
+
+type PopularDeltas struct {
+	Quantity Delta `json:"quantity"`
+	Revenue  Delta `json:"revenue"`
+	Orders   Delta `json:"orders"`
+}
+
+// Comparison statuses of a product or category: sold in both periods, only
+// in the report's period, or only in the comparison period.
+const (
+	ComparisonContinuing = "continuing"
+	ComparisonNew        = "new"
+	ComparisonDropped    = "dropped"
+)
+
+type ProductComparison struct {
+	ProductID    string `json:"product_id"`
+	Name         string `json:"name"`
+	Category     string `json:"category"`
+	Status       string `json:"status"`
+	Rank         int    `json:"rank,omitempty"`
+	PreviousRank int    `json:"previous_rank,omitempty"`
+	PopularDeltas
+}
+
+type CategoryComparison struct {
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	PopularDeltas
+}
+type PopularItem struct {
+	Rank          int     `json:"rank"`
+	ProductID     string  `json:"product_id"`
+	Name          string  `json:"name"`
+	Category      string  `json:"category"`
+	Quantity      int     `json:"quantity"`
+	Revenue       float64 `json:"revenue"`
+	Orders        int     `json:"orders"`
+	QuantityShare float64 `json:"quantity_share"`
+	RevenueShare  float64 `json:"revenue_share"`
+}
+
+// CategorySales totals the products of one menu category.
+type CategorySales struct {
+	Category      string  `json:"category"`
+	Products      int     `json:"products"`
+	Quantity      int     `json:"quantity"`
+	Revenue       float64 `json:"revenue"`
+	Orders        int     `json:"orders"`
+	QuantityShare float64 `json:"quantity_share"`
+	RevenueShare  float64 `json:"revenue_share"`
+}
@@ -0,0 +1,36 @@
+package models
+
+const (
+	RuleTypePercentOff = "percent_off"
+	RuleTypeBuyNGetOne = "buy_n_get_one"
+	RuleTypeFixedPrice = "fixed_price"
+)
+
+// PricingRule adjusts order line prices. The conditions (products, category,
+// customer group, time window and days) narrow down which lines a rule
+// applies to; Type decides what it does to them.
+type PricingRule struct {
+	ID            string   `json:"rule_id"`
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Active        bool     `json:"active"`
+	Priority      int      `json:"priority"`
+	Exclusive     bool     `json:"exclusive"`
+	ProductIDs    []string `json:"product_ids,omitempty"`
+	Category      string   `json:"category,omitempty"`
+	CustomerGroup string   `json:"customer_group,omitempty"`
+	StartTime     string   `json:"start_time,omitempty"`
+	EndTime       string   `json:"end_time,omitempty"`
+	Days          []string `json:"days,omitempty"`
+	PercentOff    float64  `json:"percent_off,omitempty"`
+	BuyQuantity   int      `json:"buy_quantity,omitempty"`
+	FixedPrice    float64  `json:"fixed_price,omitempty"`
+}
+
+// AppliedRule records the discount a pricing rule gave on an order line.
+type AppliedRule struct {
+	RuleID    string  `json:"rule_id"`
+	Name      string  `json:"name"`
+	ProductID string  `json:"product_id"`
+	Discount  float64 `json:"discount"`
+}
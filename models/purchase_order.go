@@ -0,0 +1,49 @@
+package models
+
+// Purchase order statuses.
+const (
+	PODraft             = "draft"
+	POSent              = "sent"
+	POPartiallyReceived = "partially_received"
+	POReceived          = "received"
+	POCancelled         = "cancelled"
+)
+
+// PurchaseOrder is an order of ingredients from a supplier. Lines are priced
+// from the supplier's catalogue when the order is drafted.
+type PurchaseOrder struct {
+	ID         string              `json:"po_id"`
+	SupplierID string              `json:"supplier_id"`
+	Status     string              `json:"status"`
+	Lines      []PurchaseOrderLine `json:"lines"`
+	Total      float64             `json:"total"`
+	Note       string              `json:"note,omitempty"`
+	CreatedAt  string              `json:"created_at"`
+	SentAt     string              `json:"sent_at,omitempty"`
+	ExpectedAt string              `json:"expected_at,omitempty"`
+	ReceivedAt string              `json:"received_at,omitempty"`
+	ReceiptIDs []string            `json:"receipt_ids,omitempty"`
+}
+
+type PurchaseOrderLine struct {
+	IngredientID  string  `json:"ingredient_id"`
+	Packs         float64 `json:"packs"`
+	PackSize      float64 `json:"pack_size"`
+	PackUnit      string  `json:"pack_unit"`
+	PackPrice     float64 `json:"pack_price"`
+	ReceivedPacks float64 `json:"received_packs"`
+}
+
+// PurchaseOrderReceipt is a delivery against a purchase order. Without
+// lines, everything still outstanding is received.
+type PurchaseOrderReceipt struct {
+	ReceivedBy string             `json:"received_by"`
+	InvoiceRef string             `json:"invoice_ref,omitempty"`
+	Lines      []ReceivedPackLine `json:"lines,omitempty"`
+}
+
+type ReceivedPackLine struct {
+	IngredientID string  `json:"ingredient_id"`
+	Packs        float64 `json:"packs"`
+	ExpiresAt    string  `json:"expires_at,omitempty"`
+}
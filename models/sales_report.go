@@ -0,0 +1,50 @@
+package models
+
+// SalesReport buckets the closed orders of a date range by hour, day, week
+// or month in the requested time zone. Buckets without sales are included
+// with zeros.
+type SalesReport struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Interval string        `json:"interval"`
+	TimeZone string        `json:"time_zone"`
+	Totals   SalesTotals   `json:"totals"`
+	Buckets  []SalesBucket `json:"buckets"`
+	// Comparison is set when the report is compared with another period.
+	Comparison *SalesComparison `json:"comparison,omitempty"`
+}
+
+// SalesBucket totals one interval. In a compared report the bucket also
+// carries the totals of the bucket in the same position of the other period.
+type SalesBucket struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	SalesTotals
+	Previous *SalesTotals `json:"previous,omitempty"`
+	Deltas   *SalesDeltas `json:"deltas,omitempty"`
+}
+
+// SalesComparison holds the totals of the comparison period and how the
+// report's totals differ from them.
+type SalesComparison struct {
+	Compare string      `json:"compare"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Totals  SalesTotals `json:"totals"`
+	Deltas  SalesDeltas `json:"deltas"`
+}
+
+type SalesDeltas struct {
+	Revenue       Delta `json:"revenue"`
+	Orders        Delta `json:"orders"`
+	ItemsSold     Delta `json:"items_sold"`
+	AverageTicket Delta `json:"average_ticket"`
+}
+
+// SalesTotals sums a set of orders. The average ticket is revenue per order.
+type SalesTotals struct {
+	Revenue       float64 `json:"revenue"`
+	Orders        int     `json:"orders"`
+	ItemsSold     int     `json:"items_sold"`
+	AverageTicket float64 `json:"average_ticket"`
+}
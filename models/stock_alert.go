@@ -0,0 +1,12 @@
+package models
+
+// StockAlert is raised when an ingredient falls to or below its reorder point.
+type StockAlert struct {
+	IngredientID    string  `json:"ingredient_id"`
+	Name            string  `json:"name"`
+	Quantity        float64 `json:"quantity"`
+	Unit            string  `json:"unit"`
+	ReorderPoint    float64 `json:"reorder_point"`
+	ReorderQuantity float64 `json:"reorder_quantity"`
+	RaisedAt        string  `json:"raised_at"`
+}
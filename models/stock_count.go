@@ -0,0 +1,56 @@
+package models
+
+// Stock count session statuses.
+const (
+	CountOpen      = "open"
+	CountSubmitted = "submitted"
+	CountApproved  = "approved"
+	CountCancelled = "cancelled"
+)
+
+// StockCount is a physical count of some or all ingredients. Expected
+// quantities are taken when the session opens; approving it posts the
+// variance between counted and expected as adjustments.
+type StockCount struct {
+	ID          string           `json:"count_id"`
+	Status      string           `json:"status"`
+	OpenedBy    string           `json:"opened_by"`
+	OpenedAt    string           `json:"opened_at"`
+	CountedBy   string           `json:"counted_by,omitempty"`
+	SubmittedAt string           `json:"submitted_at,omitempty"`
+	ApprovedBy  string           `json:"approved_by,omitempty"`
+	ApprovedAt  string           `json:"approved_at,omitempty"`
+	Note        string           `json:"note,omitempty"`
+	Lines       []StockCountLine `json:"lines"`
+}
+
+// StockCountLine is one ingredient of a count, in its stock unit.
+type StockCountLine struct {
+	IngredientID     string   `json:"ingredient_id"`
+	Name             string   `json:"name"`
+	Unit             string   `json:"unit"`
+	ExpectedQuantity float64  `json:"expected_quantity"`
+	CountedQuantity  *float64 `json:"counted_quantity,omitempty"`
+	Variance         float64  `json:"variance"`
+	UnitCost         float64  `json:"unit_cost"`
+	VarianceValue    float64  `json:"variance_value"`
+}
+
+// CountedQuantity is a submitted count of one ingredient, optionally in
+// another unit than the stock unit.
+type CountedQuantity struct {
+	IngredientID string  `json:"ingredient_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit,omitempty"`
+}
+
+// VarianceReport lists the lines of a count that differ from the expected
+// quantities, valued at cost. Shortages are negative.
+type VarianceReport struct {
+	CountID       string           `json:"count_id"`
+	Status        string           `json:"status"`
+	Lines         []StockCountLine `json:"lines"`
+	ShortageValue float64          `json:"shortage_value"`
+	SurplusValue  float64          `json:"surplus_value"`
+	NetValue      float64          `json:"net_value"`
+}
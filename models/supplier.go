@@ -0,0 +1,27 @@
+package models
+
+// Supplier is a vendor ingredients are bought from. LeadTimeDays is how long
+// a sent purchase order takes to arrive.
+type Supplier struct {
+	ID           string          `json:"supplier_id"`
+	Name         string          `json:"name"`
+	Contact      SupplierContact `json:"contact"`
+	LeadTimeDays int             `json:"lead_time_days"`
+	Catalogue    []CatalogueItem `json:"catalogue"`
+}
+
+type SupplierContact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// CatalogueItem is an ingredient a supplier sells, by the pack. PackUnit
+// defaults to the ingredient's stock unit.
+type CatalogueItem struct {
+	IngredientID string  `json:"ingredient_id"`
+	SKU          string  `json:"sku,omitempty"`
+	PackSize     float64 `json:"pack_size"`
+	PackUnit     string  `json:"pack_unit,omitempty"`
+	PackPrice    float64 `json:"pack_price"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// CustomUnit defines an ingredient-specific unit in terms of a standard one,
+// e.g. a "shot" of beans is 18 g.
+type CustomUnit struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
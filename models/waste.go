@@ -0,0 +1,63 @@
+package models
+
+// Waste reason codes.
+const (
+	WasteExpired   = "expired"
+	WasteSpoiled   = "spoiled"
+	WasteDamaged   = "damaged"
+	WasteSpilled   = "spilled"
+	WastePrepError = "prep_error"
+	WasteReturned  = "returned"
+	WasteOther     = "other"
+)
+
+// WasteEntry records stock thrown away, either a raw ingredient or a
+// finished menu item. Lines hold the ingredients it took out of stock and
+// what they cost at the time.
+type WasteEntry struct {
+	ID           string  `json:"waste_id"`
+	IngredientID string  `json:"ingredient_id,omitempty"`
+	ProductID    string  `json:"product_id,omitempty"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit,omitempty"`
+	// Selections picks the choices of a wasted bundle, as on an order item.
+	Selections map[string]string `json:"selections,omitempty"`
+	Reason     string            `json:"reason"`
+	Staff      string            `json:"staff"`
+	Note       string            `json:"note,omitempty"`
+	Lines      []WasteLine       `json:"lines"`
+	Cost       float64           `json:"cost"`
+	CreatedAt  string            `json:"created_at"`
+}
+
+// WasteLine is the quantity of one ingredient wasted, in its stock unit.
+type WasteLine struct {
+	IngredientID string  `json:"ingredient_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	Cost         float64 `json:"cost"`
+}
+
+// WasteReport totals waste over a date range by reason and by ingredient.
+type WasteReport struct {
+	From         string                 `json:"from,omitempty"`
+	To           string                 `json:"to,omitempty"`
+	Entries      int                    `json:"entries"`
+	TotalCost    float64                `json:"total_cost"`
+	ByReason     []WasteReasonTotal     `json:"by_reason"`
+	ByIngredient []WasteIngredientTotal `json:"by_ingredient"`
+}
+
+type WasteReasonTotal struct {
+	Reason  string  `json:"reason"`
+	Entries int     `json:"entries"`
+	Cost    float64 `json:"cost"`
+}
+
+type WasteIngredientTotal struct {
+	IngredientID string  `json:"ingredient_id"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	Cost         float64 `json:"cost"`
+}
@@ -19,9 +19,30 @@ func Start(Port string) {
 	http.HandleFunc("/menu", handler.MenuHandler)
 	http.HandleFunc("/inventory/", handler.InventoryHandler)
 	http.HandleFunc("/inventory", handler.InventoryHandler)
+	http.HandleFunc("/inventory/movements/", handler.LedgerHandler)
+	http.HandleFunc("/inventory/movements", handler.LedgerHandler)
+	http.HandleFunc("/inventory/alerts", handler.InventoryAlertsHandler)
+	http.HandleFunc("/inventory/expiry", handler.InventoryExpiryHandler)
+	http.HandleFunc("/inventory/forecast", handler.InventoryForecastHandler)
+	http.HandleFunc("/inventory/waste", handler.WasteHandler)
+	http.HandleFunc("/inventory/counts/", handler.CountHandler)
+	http.HandleFunc("/inventory/counts", handler.CountHandler)
+	http.HandleFunc("/inventory/receipts/", handler.ReceiptHandler)
+	http.HandleFunc("/inventory/receipts", handler.ReceiptHandler)
 	http.HandleFunc("/order/", handler.OrderHandler)
 	http.HandleFunc("/order", handler.OrderHandler)
 	http.HandleFunc("/reports/", handler.ReportHandler)
+	http.HandleFunc("/pricing-rules/", handler.PricingRuleHandler)
+	http.HandleFunc("/pricing-rules", handler.PricingRuleHandler)
+	http.HandleFunc("/suppliers/", handler.SupplierHandler)
+	http.HandleFunc("/suppliers", handler.SupplierHandler)
+	http.HandleFunc("/purchase-orders/", handler.PurchaseOrderHandler)
+	http.HandleFunc("/purchase-orders", handler.PurchaseOrderHandler)
+	http.HandleFunc("/search", handler.SearchHandler)
+
+	if err := handler.InitSearchIndex(); err != nil {
+		logging.Error("Failed to build search index", err)
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + Port,
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"fmt"
+	"hot-coffee/models"
+	"strings"
+)
+
+const (
+	DimensionMass   = "mass"
+	DimensionVolume = "volume"
+	DimensionCount  = "count"
+)
+
+type unitDefinition struct {
+	dimension string
+	// factor converts one of this unit into the base unit of the dimension
+	// (g, ml or pcs).
+	factor float64
+}
+
+var standardUnits = map[string]unitDefinition{
+	"mg":     {DimensionMass, 0.001},
+	"g":      {DimensionMass, 1},
+	"kg":     {DimensionMass, 1000},
+	"oz":     {DimensionMass, 28.349523125},
+	"lb":     {DimensionMass, 453.59237},
+	"ml":     {DimensionVolume, 1},
+	"cl":     {DimensionVolume, 10},
+	"dl":     {DimensionVolume, 100},
+	"l":      {DimensionVolume, 1000},
+	"tsp":    {DimensionVolume, 4.92892159375},
+	"tbsp":   {DimensionVolume, 14.78676478125},
+	"cup":    {DimensionVolume, 240},
+	"fl_oz":  {DimensionVolume, 29.5735295625},
+	"pcs":    {DimensionCount, 1},
+	"pc":     {DimensionCount, 1},
+	"piece":  {DimensionCount, 1},
+	"pieces": {DimensionCount, 1},
+	"each":   {DimensionCount, 1},
+	"unit":   {DimensionCount, 1},
+	"units":  {DimensionCount, 1},
+	"shot":   {DimensionCount, 1},
+	"shots":  {DimensionCount, 1},
+	"dozen":  {DimensionCount, 12},
+}
+
+// NormalizeUnit puts a unit name into the form used for lookups.
+func NormalizeUnit(unit string) string {
+	return strings.ToLower(strings.TrimSpace(unit))
+}
+
+// IsKnownUnit reports whether the unit is a standard unit or one of the
+// ingredient's custom units.
+func IsKnownUnit(unit string, customUnits []models.CustomUnit) bool {
+	unit = NormalizeUnit(unit)
+	if _, ok := findCustomUnit(unit, customUnits); ok {
+		return true
+	}
+	_, ok := standardUnits[unit]
+	return ok
+}
+
+// ConvertQuantity converts a quantity between units. Custom units of the
+// ingredient take precedence over standard units with the same name.
+func ConvertQuantity(quantity float64, from, to string, customUnits []models.CustomUnit) (float64, error) {
+	from, to = NormalizeUnit(from), NormalizeUnit(to)
+	if from == to {
+		return quantity, nil
+	}
+
+	// Express custom units in their standard unit first
+	if custom, ok := findCustomUnit(from, customUnits); ok {
+		quantity, from = quantity*custom.Quantity, NormalizeUnit(custom.Unit)
+	}
+	divisor := 1.0
+	if custom, ok := findCustomUnit(to, customUnits); ok {
+		divisor, to = custom.Quantity, NormalizeUnit(custom.Unit)
+	}
+
+	fromUnit, ok := standardUnits[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %s", from)
+	}
+	toUnit, ok := standardUnits[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %s", to)
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, fromUnit.dimension, to, toUnit.dimension)
+	}
+
+	return quantity * fromUnit.factor / toUnit.factor / divisor, nil
+}
+
+// ValidateCustomUnits ensures custom units are defined in standard units and
+// do not repeat.
+func ValidateCustomUnits(customUnits []models.CustomUnit) error {
+	seen := make(map[string]bool)
+	for _, custom := range customUnits {
+		name := NormalizeUnit(custom.Name)
+		if name == "" {
+			return fmt.Errorf("custom unit name cannot be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate custom unit: %s", custom.Name)
+		}
+		seen[name] = true
+
+		if custom.Quantity <= 0 {
+			return fmt.Errorf("custom unit %s must have a quantity greater than zero", custom.Name)
+		}
+		if _, ok := standardUnits[NormalizeUnit(custom.Unit)]; !ok {
+			return fmt.Errorf("custom unit %s must be defined in a standard unit, got %s", custom.Name, custom.Unit)
+		}
+	}
+	return nil
+}
+
+func findCustomUnit(unit string, customUnits []models.CustomUnit) (models.CustomUnit, bool) {
+	for _, custom := range customUnits {
+		if NormalizeUnit(custom.Name) == unit {
+			return custom, true
+		}
+	}
+	return models.CustomUnit{}, false
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"hot-coffee/models"
+	"math"
+	"testing"
+)
+
+func TestConvertQuantity(t *testing.T) {
+	customUnits := []models.CustomUnit{
+		{Name: "bag", Quantity: 2, Unit: "kg"},
+		{Name: "scoop", Quantity: 15, Unit: "g"},
+		{Name: "cup", Quantity: 200, Unit: "ml"},
+	}
+
+	tests := []struct {
+		name     string
+		quantity float64
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{name: "same unit", quantity: 3, from: "g", to: "g", want: 3},
+		{name: "case and spaces", quantity: 3, from: " KG", to: "g ", want: 3000},
+		{name: "mass", quantity: 1500, from: "g", to: "kg", want: 1.5},
+		{name: "volume", quantity: 2, from: "l", to: "ml", want: 2000},
+		{name: "imperial", quantity: 1, from: "lb", to: "oz", want: 16},
+		{name: "count", quantity: 2, from: "dozen", to: "pcs", want: 24},
+		{name: "from custom unit", quantity: 3, from: "bag", to: "g", want: 6000},
+		{name: "to custom unit", quantity: 45, from: "g", to: "scoop", want: 3},
+		{name: "between custom units", quantity: 1, from: "bag", to: "scoop", want: 2000.0 / 15},
+		{name: "custom unit shadows standard", quantity: 1, from: "cup", to: "ml", want: 200},
+		{name: "different dimensions", quantity: 1, from: "g", to: "ml", wantErr: true},
+		{name: "unknown from", quantity: 1, from: "handful", to: "g", wantErr: true},
+		{name: "unknown to", quantity: 1, from: "g", to: "handful", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertQuantity(tt.quantity, tt.from, tt.to, customUnits)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ConvertQuantity(%v, %q, %q) = %v, want an error", tt.quantity, tt.from, tt.to, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertQuantity(%v, %q, %q) failed: %v", tt.quantity, tt.from, tt.to, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ConvertQuantity(%v, %q, %q) = %v, want %v", tt.quantity, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,20 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"hot-coffee/config"
 	"hot-coffee/models"
 	"log"
 	"math/rand"
+	"strings"
 	"time"
 )
 
+// Allergens lists the allergen codes ingredients can be flagged with.
+var Allergens = []string{
+	"gluten", "crustaceans", "eggs", "fish", "peanuts", "soy", "dairy",
+	"nuts", "celery", "mustard", "sesame", "sulphites", "lupin", "molluscs",
+}
+
 func CatchCriticalPoint() {
 	if r := recover(); r != nil {
 		log.Printf("Recovered from error")
@@ -68,12 +76,38 @@ func ValidateUpdatedMenuItem(item models.MenuItem) error {
 	if item.Price <= 0 {
 		return errors.New("menu item price must be greater than zero")
 	}
-	if item.Ingredients == nil {
+	if item.Ingredients == nil && !item.IsBundle() {
 		return errors.New("menu item ingredient cannot be empty")
 	}
 	if item.Description == "" {
 		return errors.New("menu item ingredient cannot be empty")
 	}
+	if item.IsBundle() && len(item.Ingredients) > 0 {
+		return errors.New("bundle menu item cannot have its own ingredients")
+	}
+
+	groups := make(map[string]bool)
+	for _, component := range item.Components {
+		if component.Quantity <= 0 {
+			return errors.New("bundle component quantity must be greater than zero")
+		}
+		if component.Group == "" {
+			if component.ProductID == "" {
+				return errors.New("bundle component must have a product ID or a choice group")
+			}
+			continue
+		}
+		if component.ProductID != "" {
+			return fmt.Errorf("bundle component %s cannot have both a product ID and choices", component.Group)
+		}
+		if len(component.Choices) == 0 {
+			return fmt.Errorf("bundle choice group %s must list at least one product", component.Group)
+		}
+		if groups[component.Group] {
+			return fmt.Errorf("duplicate bundle choice group: %s", component.Group)
+		}
+		groups[component.Group] = true
+	}
 	return nil
 }
 
@@ -91,15 +125,281 @@ func ValidateUpdatedInventoryItem(item models.InventoryItem) error {
 	if item.Unit == "" {
 		return errors.New("ingredient unit cannot be empty")
 	}
+	if err := ValidateCustomUnits(item.CustomUnits); err != nil {
+		return err
+	}
+	if !IsKnownUnit(item.Unit, item.CustomUnits) {
+		return fmt.Errorf("unknown ingredient unit: %s", item.Unit)
+	}
+	if item.UnitCost < 0 {
+		return errors.New("ingredient unit cost cannot be negative")
+	}
+	if item.ReorderPoint < 0 || item.ReorderQuantity < 0 {
+		return errors.New("ingredient reorder point and quantity cannot be negative")
+	}
+	for _, allergen := range item.Allergens {
+		if !contains(Allergens, allergen) {
+			return fmt.Errorf("unknown allergen: %s", allergen)
+		}
+	}
+	if item.Nutrition != nil {
+		n := item.Nutrition
+		if n.Calories < 0 || n.Fat < 0 || n.Carbohydrates < 0 || n.Sugar < 0 || n.Protein < 0 || n.Salt < 0 {
+			return errors.New("ingredient nutrition values cannot be negative")
+		}
+	}
 
 	return nil
 }
 
 // RandomInt generates a random integer between min and max (inclusive).
 func RandomInt(min, max int) int {
-	// Initialize the random number generator with the current time as the seed
-	rand.Seed(time.Now().UnixNano())
-
 	// Return a random integer between min and max (inclusive)
 	return rand.Intn(max-min+1) + min
 }
+
+// NextID returns the next sequential ID with the given prefix (e.g. "rule3"
+// after "rule2"), ignoring IDs that do not follow the prefix+number format.
+func NextID(prefix string, existingIDs []string) string {
+	highest := 0
+	for _, id := range existingIDs {
+		var n int
+		if _, err := fmt.Sscanf(id, prefix+"%d", &n); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return fmt.Sprintf("%s%d", prefix, highest+1)
+}
+
+// BusinessLocation returns the time zone the coffee shop operates in.
+func BusinessLocation() (*time.Location, error) {
+	return time.LoadLocation(config.TimeZone)
+}
+
+// ValidatePricingRule ensures that a pricing rule is complete for its type
+func ValidatePricingRule(rule models.PricingRule) error {
+	if rule.Name == "" {
+		return errors.New("pricing rule name cannot be empty")
+	}
+
+	switch rule.Type {
+	case models.RuleTypePercentOff:
+		if rule.PercentOff <= 0 || rule.PercentOff > 100 {
+			return errors.New("percent off must be between 0 and 100")
+		}
+	case models.RuleTypeBuyNGetOne:
+		if rule.BuyQuantity <= 0 {
+			return errors.New("buy quantity must be greater than zero")
+		}
+	case models.RuleTypeFixedPrice:
+		if rule.FixedPrice <= 0 {
+			return errors.New("fixed price must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("invalid pricing rule type: %s", rule.Type)
+	}
+
+	if (rule.StartTime == "") != (rule.EndTime == "") {
+		return errors.New("pricing rule time window needs both start and end time")
+	}
+	for _, clock := range []string{rule.StartTime, rule.EndTime} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return fmt.Errorf("invalid pricing rule time %s, expected HH:MM", clock)
+		}
+	}
+
+	validDays := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	for _, day := range rule.Days {
+		if !contains(validDays, strings.ToLower(day)) {
+			return fmt.Errorf("invalid pricing rule day: %s", day)
+		}
+	}
+
+	return nil
+}
+
+// ParseDateRange parses optional from/to query values given either as
+// RFC3339 timestamps or as YYYY-MM-DD dates in the business time zone. The
+// range is half-open: a plain "to" date includes that whole day. Empty values
+// come back as zero times.
+func ParseDateRange(from, to string) (time.Time, time.Time, error) {
+	loc, err := BusinessLocation()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return ParseDateRangeIn(from, to, loc)
+}
+
+// ParseDateRangeIn is ParseDateRange with plain dates read in the given
+// time zone.
+func ParseDateRangeIn(from, to string, loc *time.Location) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+
+	if from != "" {
+		if start, err = time.Parse(time.RFC3339, from); err != nil {
+			if start, err = time.ParseInLocation("2006-01-02", from, loc); err != nil {
+				return start, end, fmt.Errorf("invalid from date: %s", from)
+			}
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse(time.RFC3339, to); err != nil {
+			if end, err = time.ParseInLocation("2006-01-02", to, loc); err != nil {
+				return start, end, fmt.Errorf("invalid to date: %s", to)
+			}
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, errors.New("from date must be before to date")
+	}
+	return start, end, nil
+}
+
+// InDateRange reports whether an RFC3339 timestamp falls in [from, to). Zero
+// bounds are open.
+func InDateRange(timestamp string, from, to time.Time) bool {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return false
+	}
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+// ValidateGoodsReceipt checks the fields of a goods receipt that do not
+// depend on the inventory.
+func ValidateGoodsReceipt(receipt models.GoodsReceipt) error {
+	if strings.TrimSpace(receipt.Supplier) == "" {
+		return errors.New("supplier cannot be empty")
+	}
+	if strings.TrimSpace(receipt.ReceivedBy) == "" {
+		return errors.New("received by cannot be empty")
+	}
+	if len(receipt.Lines) == 0 {
+		return errors.New("goods receipt must have at least one line")
+	}
+	for _, line := range receipt.Lines {
+		if line.IngredientID == "" {
+			return errors.New("goods receipt line ingredient ID cannot be empty")
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("received quantity must be greater than zero: %s", line.IngredientID)
+		}
+		if line.UnitCost < 0 {
+			return fmt.Errorf("unit cost cannot be negative: %s", line.IngredientID)
+		}
+		if line.ExpiresAt != "" {
+			if _, err := ParseExpiry(line.ExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseExpiry returns the moment stock with the given expiry stops being
+// usable. A YYYY-MM-DD date in the business time zone is usable through the
+// end of that day.
+func ParseExpiry(expiresAt string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, expiresAt); err == nil {
+		return t, nil
+	}
+	loc, err := BusinessLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation("2006-01-02", expiresAt, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry date: %s", expiresAt)
+	}
+	return t.AddDate(0, 0, 1), nil
+}
+
+// WasteReasons lists the reason codes waste can be recorded with.
+var WasteReasons = []string{
+	models.WasteExpired, models.WasteSpoiled, models.WasteDamaged, models.WasteSpilled,
+	models.WastePrepError, models.WasteReturned, models.WasteOther,
+}
+
+// ValidateWasteEntry checks that a waste entry names exactly one ingredient
+// or menu item and has a known reason.
+func ValidateWasteEntry(entry models.WasteEntry) error {
+	if (entry.IngredientID == "") == (entry.ProductID == "") {
+		return errors.New("waste entry needs either an ingredient ID or a product ID")
+	}
+	if entry.Quantity <= 0 {
+		return errors.New("waste quantity must be greater than zero")
+	}
+	if entry.ProductID != "" && entry.Unit != "" {
+		return errors.New("waste of a menu item is counted in items, not units")
+	}
+	if !contains(WasteReasons, entry.Reason) {
+		return fmt.Errorf("invalid waste reason: %s", entry.Reason)
+	}
+	if strings.TrimSpace(entry.Staff) == "" {
+		return errors.New("staff cannot be empty")
+	}
+	return nil
+}
+
+// ValidateSupplier checks a supplier and its catalogue. Ingredients and pack
+// units are checked against the inventory by the service.
+func ValidateSupplier(supplier models.Supplier) error {
+	if strings.TrimSpace(supplier.Name) == "" {
+		return errors.New("supplier name cannot be empty")
+	}
+	if supplier.LeadTimeDays < 0 {
+		return errors.New("lead time cannot be negative")
+	}
+
+	seen := make(map[string]bool)
+	for _, item := range supplier.Catalogue {
+		if item.IngredientID == "" {
+			return errors.New("catalogue ingredient ID cannot be empty")
+		}
+		if seen[item.IngredientID] {
+			return fmt.Errorf("duplicate catalogue ingredient: %s", item.IngredientID)
+		}
+		seen[item.IngredientID] = true
+		if item.PackSize <= 0 {
+			return fmt.Errorf("pack size must be greater than zero: %s", item.IngredientID)
+		}
+		if item.PackPrice < 0 {
+			return fmt.Errorf("pack price cannot be negative: %s", item.IngredientID)
+		}
+	}
+	return nil
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7386) to a decoded JSON object:
+// null removes a member, objects are merged recursively and any other value
+// replaces the member.
+func MergePatch(target map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			targetObject, _ := target[key].(map[string]interface{})
+			target[key] = MergePatch(targetObject, patchObject)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}